@@ -0,0 +1,154 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package amount parses and formats amounts of coin exactly, in a unit such as SOTER or mSOTER. Amounts are never
+// converted to floating point numbers, which can't represent every amount of the base unit (nSoter).
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/soterutil"
+	"strings"
+)
+
+// BaseUnit is the smallest unit of coin, which amounts are counted in
+const BaseUnit = soterutil.AmountNanoSoter
+
+// units are the names that units can be given by. The first name of a unit is the one it's formatted with.
+var units = []struct {
+	unit  soterutil.AmountUnit
+	names []string
+}{
+	{soterutil.AmountMegaSOTER, []string{"MSOTER"}},
+	{soterutil.AmountKiloSOTER, []string{"kSOTER"}},
+	{soterutil.AmountSOTER, []string{"SOTER"}},
+	{soterutil.AmountMilliSOTER, []string{"mSOTER"}},
+	// The micro sign, the greek letter mu, and u for keyboards without either of them
+	{soterutil.AmountMicroSOTER, []string{"µSOTER", "μSOTER", "uSOTER"}},
+	{BaseUnit, []string{"nSoter", "nSOTER"}},
+}
+
+// ErrInvalidAmount means a string isn't an amount of coin
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// UnitNames returns the names of the units that amounts can be given in, in order from the largest unit
+func UnitNames() []string {
+	names := make([]string, 0, len(units))
+	for _, u := range units {
+		names = append(names, u.names[0])
+	}
+
+	return names
+}
+
+// ParseUnit returns the unit with the name
+func ParseUnit(name string) (soterutil.AmountUnit, error) {
+	for _, u := range units {
+		for _, n := range u.names {
+			if name == n {
+				return u.unit, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("unknown unit %s; use one of %s", name, strings.Join(UnitNames(), ", "))
+}
+
+// UnitName returns the name that the unit is formatted with
+func UnitName(unit soterutil.AmountUnit) string {
+	for _, u := range units {
+		if u.unit == unit {
+			return u.names[0]
+		}
+	}
+
+	return unit.String()
+}
+
+// decimals returns the number of decimal places of an amount in the unit, which is how many digits can follow the
+// decimal point before the amount has more precision than the base unit
+func decimals(unit soterutil.AmountUnit) int {
+	return int(unit - BaseUnit)
+}
+
+// Parse returns the amount of the string, such as "1.5", "1.5 SOTER" or "1500mSOTER". An amount without a unit is in
+// the given unit. An amount that's negative, has more precision than the base unit, or is more than the most coin
+// there can be is an error.
+func Parse(s string, unit soterutil.AmountUnit) (soterutil.Amount, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("%w %q: amounts can't be negative", ErrInvalidAmount, s)
+	}
+	num := s
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end >= 0 {
+		var err error
+		num = s[:end]
+		unit, err = ParseUnit(strings.TrimSpace(s[end:]))
+		if err != nil {
+			return 0, fmt.Errorf("%w %q: %s", ErrInvalidAmount, s, err)
+		}
+	}
+
+	parts := strings.Split(num, ".")
+	if len(parts) > 2 || len(parts[0])+len(parts[len(parts)-1]) == 0 {
+		return 0, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+	}
+	whole := parts[0]
+	var frac string
+	if len(parts) == 2 {
+		// Trailing zeros don't add precision
+		frac = strings.TrimRight(parts[1], "0")
+	}
+
+	places := decimals(unit)
+	if len(frac) > places {
+		return 0, fmt.Errorf("%w %q: more precise than 1 %s", ErrInvalidAmount, s, UnitName(BaseUnit))
+	}
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", places-len(frac)), "0")
+
+	// Amounts with more digits than the most coin there can be are too large, which also keeps them from overflowing
+	max := soterutil.Amount(soterutil.MaxNanoSoter)
+	if len(digits) > len(fmt.Sprint(int64(max))) {
+		return 0, fmt.Errorf("%w %q: more than %s", ErrInvalidAmount, s, Format(max, soterutil.AmountSOTER))
+	}
+	var a soterutil.Amount
+	for _, d := range digits {
+		a = a*10 + soterutil.Amount(d-'0')
+	}
+	if a > max {
+		return 0, fmt.Errorf("%w %q: more than %s", ErrInvalidAmount, s, Format(max, soterutil.AmountSOTER))
+	}
+
+	return a, nil
+}
+
+// FormatNumber returns the amount as a decimal number in the unit, without the unit. Trailing zeros after the decimal
+// point are left out.
+func FormatNumber(a soterutil.Amount, unit soterutil.AmountUnit) string {
+	sign := ""
+	n := uint64(a)
+	if a < 0 {
+		sign = "-"
+		n = uint64(-a)
+	}
+
+	places := decimals(unit)
+	digits := fmt.Sprintf("%0*d", places+1, n)
+	whole := digits[:len(digits)-places]
+	frac := strings.TrimRight(digits[len(digits)-places:], "0")
+	if len(frac) == 0 {
+		return sign + whole
+	}
+
+	return sign + whole + "." + frac
+}
+
+// Format returns the amount in the unit, with the unit's name, such as "1.5 SOTER"
+func Format(a soterutil.Amount, unit soterutil.AmountUnit) string {
+	return FormatNumber(a, unit) + " " + UnitName(unit)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package amount
+
+import (
+	"errors"
+	"github.com/soteria-dag/soterd/soterutil"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		unit soterutil.AmountUnit
+		want soterutil.Amount
+	}{
+		{"1", soterutil.AmountSOTER, 1000000000},
+		{"1.5", soterutil.AmountSOTER, 1500000000},
+		{" 0.000000001 ", soterutil.AmountSOTER, 1},
+		{".5", soterutil.AmountSOTER, 500000000},
+		{"5.", soterutil.AmountSOTER, 5000000000},
+		{"0.1000000000", soterutil.AmountSOTER, 100000000},
+		{"1.5 SOTER", soterutil.AmountMilliSOTER, 1500000000},
+		{"1500mSOTER", soterutil.AmountSOTER, 1500000000},
+		{"2 µSOTER", soterutil.AmountSOTER, 2000},
+		{"2 μSOTER", soterutil.AmountSOTER, 2000},
+		{"2uSOTER", soterutil.AmountSOTER, 2000},
+		{"7 nSoter", soterutil.AmountSOTER, 7},
+		{"7", BaseUnit, 7},
+		{"2.1 MSOTER", soterutil.AmountSOTER, soterutil.MaxNanoSoter},
+		{"0", soterutil.AmountSOTER, 0},
+		// Float64 can't represent this amount exactly
+		{"2099999.999999999", soterutil.AmountSOTER, soterutil.MaxNanoSoter - 1},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.s, test.unit)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", test.s, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("wrong amount of %q; got %d, want %d", test.s, got, test.want)
+		}
+	}
+
+	invalid := []struct {
+		s    string
+		unit soterutil.AmountUnit
+	}{
+		{"", soterutil.AmountSOTER},
+		{".", soterutil.AmountSOTER},
+		{"1.2.3", soterutil.AmountSOTER},
+		{"-1", soterutil.AmountSOTER},
+		{"1e5", soterutil.AmountSOTER},
+		{"1 BTC", soterutil.AmountSOTER},
+		{"SOTER", soterutil.AmountSOTER},
+		{"1,000", soterutil.AmountSOTER},
+		// More precise than the base unit
+		{"0.0000000001", soterutil.AmountSOTER},
+		{"1.5 nSoter", soterutil.AmountSOTER},
+		{"1.5", BaseUnit},
+		// More than the most coin there can be
+		{"2100000.000000001", soterutil.AmountSOTER},
+		{"99999999999999999999", soterutil.AmountSOTER},
+	}
+	for _, test := range invalid {
+		_, err := Parse(test.s, test.unit)
+		if !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("wrong error of parsing %q; got %v, want %s", test.s, err, ErrInvalidAmount)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		a    soterutil.Amount
+		unit soterutil.AmountUnit
+		want string
+	}{
+		{0, soterutil.AmountSOTER, "0 SOTER"},
+		{1, soterutil.AmountSOTER, "0.000000001 SOTER"},
+		{1500000000, soterutil.AmountSOTER, "1.5 SOTER"},
+		{1500000000, soterutil.AmountMilliSOTER, "1500 mSOTER"},
+		{2000, soterutil.AmountMicroSOTER, "2 µSOTER"},
+		{7, BaseUnit, "7 nSoter"},
+		{-2500000000, soterutil.AmountSOTER, "-2.5 SOTER"},
+		{soterutil.MaxNanoSoter - 1, soterutil.AmountSOTER, "2099999.999999999 SOTER"},
+		{soterutil.MaxNanoSoter, soterutil.AmountMegaSOTER, "2.1 MSOTER"},
+	}
+	for _, test := range tests {
+		got := Format(test.a, test.unit)
+		if got != test.want {
+			t.Errorf("wrong format of %d in %s; got %q, want %q", test.a, test.unit, got, test.want)
+		}
+
+		// Formatted amounts can be parsed again
+		if test.a >= 0 {
+			a, err := Parse(got, soterutil.AmountSOTER)
+			if err != nil || a != test.a {
+				t.Errorf("failed to parse %q again; got %d, %v", got, a, err)
+			}
+		}
+	}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		unit   string
+		locale string
+		a      soterutil.Amount
+		want   string
+	}{
+		{"", "", 1234567891234567, "1234567.891234567 SOTER"},
+		{"", "en", 1234567891234567, "1,234,567.891234567 SOTER"},
+		{"", "de_DE.UTF-8", 1234567891234567, "1.234.567,891234567 SOTER"},
+		{"", "fr", 1234567000000000, "1\u202f234\u202f567 SOTER"},
+		{"mSOTER", "en", 1234567891234567, "1,234,567,891.234567 mSOTER"},
+		{"nSoter", "en-US", 1234567891234567, "1,234,567,891,234,567 nSoter"},
+		{"uSOTER", "", 1500, "1.5 µSOTER"},
+		{"", "en", 999000000000, "999 SOTER"},
+		{"", "en", -1234500000000, "-1,234.5 SOTER"},
+		{"kSOTER", "none", 1234567000000000, "1234.567 kSOTER"},
+	}
+	for _, test := range tests {
+		s, err := NewStyle(test.unit, test.locale)
+		if err != nil {
+			t.Errorf("failed to create style of unit %q and locale %q: %s", test.unit, test.locale, err)
+			continue
+		}
+		got := s.Format(test.a)
+		if got != test.want {
+			t.Errorf("wrong format of %d with unit %q and locale %q; got %q, want %q",
+				test.a, test.unit, test.locale, got, test.want)
+		}
+	}
+
+	_, err := NewStyle("BTC", "")
+	if err == nil {
+		t.Errorf("created style with an unknown unit")
+	}
+	_, err = NewStyle("", "xx")
+	if err == nil {
+		t.Errorf("created style with an unknown locale")
+	}
+
+	if DefaultStyle.Format(1500000000) != Format(1500000000, soterutil.AmountSOTER) {
+		t.Errorf("default style doesn't format amounts like Format")
+	}
+}
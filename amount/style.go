@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package amount
+
+import (
+	"fmt"
+	"github.com/soteria-dag/soterd/soterutil"
+	"strings"
+)
+
+// Style is how amounts are shown: the unit they're shown in, and the marks that group their thousands and separate
+// their decimals
+type Style struct {
+	Unit      soterutil.AmountUnit
+	Thousands string
+	Decimal   string
+}
+
+// DefaultStyle shows amounts in SOTER, without grouping their thousands
+var DefaultStyle = Style{Unit: soterutil.AmountSOTER, Decimal: "."}
+
+// locales are the number formats that can be chosen, by the language of a locale
+var locales = []struct {
+	name      string
+	thousands string
+	decimal   string
+}{
+	{"none", "", "."},
+	{"en", ",", "."},
+	{"de", ".", ","},
+	// A narrow no-break space, so that an amount isn't split over two lines
+	{"fr", "\u202f", ","},
+}
+
+// LocaleNames returns the names of the number formats that can be chosen
+func LocaleNames() []string {
+	names := make([]string, 0, len(locales))
+	for _, l := range locales {
+		names = append(names, l.name)
+	}
+
+	return names
+}
+
+// NewStyle returns the style that shows amounts in the named unit, with the number format of the locale. The
+// language of a locale such as en_US.UTF-8 chooses its number format, and an empty unit or locale is the one of
+// DefaultStyle.
+func NewStyle(unit, locale string) (Style, error) {
+	s := DefaultStyle
+	if len(unit) > 0 {
+		var err error
+		s.Unit, err = ParseUnit(unit)
+		if err != nil {
+			return s, err
+		}
+	}
+
+	if len(locale) == 0 {
+		return s, nil
+	}
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_-."); i >= 0 {
+		lang = lang[:i]
+	}
+	for _, l := range locales {
+		if lang == l.name {
+			s.Thousands = l.thousands
+			s.Decimal = l.decimal
+			return s, nil
+		}
+	}
+
+	return s, fmt.Errorf("unknown locale %s; use one of %s", locale, strings.Join(LocaleNames(), ", "))
+}
+
+// FormatNumber returns the amount as a number in the style's unit, without the unit
+func (s Style) FormatNumber(a soterutil.Amount) string {
+	num := FormatNumber(a, s.Unit)
+	sign := ""
+	if strings.HasPrefix(num, "-") {
+		sign = "-"
+		num = num[1:]
+	}
+
+	whole, frac := num, ""
+	if i := strings.Index(num, "."); i >= 0 {
+		whole, frac = num[:i], num[i+1:]
+	}
+	if len(s.Thousands) > 0 {
+		var b strings.Builder
+		for i, d := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(s.Thousands)
+			}
+			b.WriteRune(d)
+		}
+		whole = b.String()
+	}
+
+	if len(frac) == 0 {
+		return sign + whole
+	}
+	decimal := s.Decimal
+	if len(decimal) == 0 {
+		decimal = "."
+	}
+	return sign + whole + decimal + frac
+}
+
+// Format returns the amount in the style's unit, with the unit's name
+func (s Style) Format(a soterutil.Amount) string {
+	return s.FormatNumber(a) + " " + UnitName(s.Unit)
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"strings"
+)
+
+// BalanceOutput is the json output of balance
+type BalanceOutput struct {
+	Header
+	Address          string           `json:"address,omitempty"`
+	Balance          soterutil.Amount `json:"balance"`
+	SpendableBalance soterutil.Amount `json:"spendableBalance"`
+	History          []HistoryOutput  `json:"history,omitempty"`
+	HadError         bool             `json:"hadError"`
+	ErrorCode        ErrorCode        `json:"errorCode,omitempty"`
+	ErrorMsg         string           `json:"errorMsg"`
+}
+
+// HistoryOutput is a transaction in the history of an address
+type HistoryOutput struct {
+	Tx          string           `json:"tx"`
+	Block       string           `json:"block"`
+	BlockHeight int32            `json:"blockHeight"`
+	Received    soterutil.Amount `json:"received"`
+	Sent        soterutil.Amount `json:"sent"`
+	Data        []string         `json:"data,omitempty"`
+}
+
+// AddressHistoryOutput is the json output of history
+type AddressHistoryOutput struct {
+	Header
+	Address string          `json:"address"`
+	History []HistoryOutput `json:"history"`
+}
+
+var balanceCommand = &command{
+	name:    "balance",
+	summary: "Show the balance of an address, by looking through the dag",
+	errorOutput: func(e ErrorOutput) (string, jsonOutput) {
+		return SchemaBalance, &BalanceOutput{
+			Balance:          -1,
+			SpendableBalance: -1,
+			HadError:         true,
+			ErrorCode:        e.ErrorCode,
+			ErrorMsg:         e.ErrorMsg,
+		}
+	},
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var inputAddress string
+		var showHistory, compare bool
+		fs.StringVar(&inputAddress, "address", "", "Address to check balance of")
+		fs.BoolVar(&showHistory, "history", false, "Also list the transactions involving the address, and data embedded in them")
+		fs.BoolVar(&compare, "compare", false, "Compare the dag and balances of every -rpcserver node, and exit with code 8 if they differ. -address can be a comma-separated list of addresses.")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("balance takes no arguments")
+			}
+			if len(inputAddress) == 0 {
+				return usageErrorf("You must specify an address to check the balance of (-address)")
+			}
+			if compare && showHistory {
+				return usageErrorf("-history can't be used with -compare")
+			}
+			params := ctx.Config.Params
+
+			if len(ctx.Config.RPCServer) == 0 {
+				ctx.Warnf("-rpcserver is not set!")
+			}
+			if len(ctx.Config.RPCUser) == 0 {
+				ctx.Warnf("-rpcuser is not set!")
+			}
+			_, err := passphrase(ctx, ctx.Config.RPCPass)
+			if err != nil {
+				return err
+			}
+
+			if compare {
+				var addresses []soterutil.Address
+				for _, a := range strings.Split(inputAddress, ",") {
+					address, err := soterutil.DecodeAddress(strings.TrimSpace(a), params)
+					if err != nil {
+						return errorf(CodeInvalid, "failed to decode address from %s: %s", a, err)
+					}
+					addresses = append(addresses, address)
+				}
+
+				rpcNodes, err := ctx.Config.Nodes()
+				if err != nil {
+					return errorf(CodeConfig, "failed to create soterd rpc client: %s", err)
+				}
+				defer rpcNodes.Shutdown()
+				if len(rpcNodes.Servers()) < 2 {
+					return usageErrorf("-compare needs at least two soterd nodes (-rpcserver HOST:PORT,HOST:PORT)")
+				}
+
+				return compareNodes(ctx, rpcNodes, addresses, params)
+			}
+
+			address, err := soterutil.DecodeAddress(inputAddress, params)
+			if err != nil {
+				return errorf(CodeInvalid, "failed to decode address from %s: %s", inputAddress, err)
+			}
+			addresses := []soterutil.Address{address}
+
+			// The node with the highest dag tips is used, and another node if it fails during the scan
+			rpcNodes, err := ctx.Config.Nodes()
+			if err != nil {
+				return errorf(CodeConfig, "failed to create soterd rpc client: %s", err)
+			}
+			defer rpcNodes.Shutdown()
+
+			var balance, spendable soterutil.Amount
+			err = rpcNodes.Do(func(client *rpcclient.Client) error {
+				var err error
+				balance, spendable, err = wallet.GetBalance(client, addresses, params)
+				return err
+			})
+			if err != nil {
+				return errorf(CodeRPC, "failed to get balance of address %s: %w", address, err)
+			}
+
+			var history []wallet.HistoryEntry
+			if showHistory {
+				history, err = addressHistory(rpcNodes, address, params)
+				if err != nil {
+					return err
+				}
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaBalance, &BalanceOutput{
+					Address:          address.EncodeAddress(),
+					Balance:          balance,
+					SpendableBalance: spendable,
+					History:          historyOutput(history),
+				})
+			}
+
+			ctx.Printf("balance of %s: %s\n", address, ctx.Amount(balance))
+			ctx.Printf("spendable balance of %s: %s\n", address, ctx.Amount(spendable))
+			if showHistory {
+				ctx.Printf("history of %s:\n", address)
+				printHistory(ctx, history)
+			}
+			return nil
+		}
+	},
+}
+
+var historyCommand = &command{
+	name:    "history",
+	summary: "List the transactions involving an address, and data embedded in them",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var inputAddress string
+		fs.StringVar(&inputAddress, "address", "", "Address to list the transactions of")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("history takes no arguments")
+			}
+			if len(inputAddress) == 0 {
+				return usageErrorf("You must specify an address to list the transactions of (-address)")
+			}
+
+			address, err := soterutil.DecodeAddress(inputAddress, ctx.Config.Params)
+			if err != nil {
+				return errorf(CodeInvalid, "failed to decode address from %s: %s", inputAddress, err)
+			}
+
+			rpcNodes, err := ctx.Config.Nodes()
+			if err != nil {
+				return errorf(CodeConfig, "failed to create soterd rpc client: %s", err)
+			}
+			defer rpcNodes.Shutdown()
+
+			history, err := addressHistory(rpcNodes, address, ctx.Config.Params)
+			if err != nil {
+				return err
+			}
+
+			if ctx.JSON() {
+				out := AddressHistoryOutput{Address: address.EncodeAddress(), History: historyOutput(history)}
+				if out.History == nil {
+					out.History = []HistoryOutput{}
+				}
+				return ctx.PrintJSON(SchemaHistory, &out)
+			}
+
+			printHistory(ctx, history)
+			return nil
+		}
+	},
+}
+
+// addressHistory returns the transactions that sent coin to or from the address
+func addressHistory(rpcNodes *nodes.Manager, address soterutil.Address, params *chaincfg.Params) ([]wallet.HistoryEntry, error) {
+	var history []wallet.HistoryEntry
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		history, err = wallet.History(client, []soterutil.Address{address}, params)
+		return err
+	})
+	if err != nil {
+		return nil, errorf(CodeRPC, "failed to get history of address %s: %w", address, err)
+	}
+
+	return history, nil
+}
+
+// historyOutput returns the json output of the history
+func historyOutput(history []wallet.HistoryEntry) []HistoryOutput {
+	var out []HistoryOutput
+	for _, e := range history {
+		h := HistoryOutput{
+			Tx:          e.Info.Tx.TxHash().String(),
+			Block:       e.Info.Block.BlockHash().String(),
+			BlockHeight: e.Info.BlockHeight,
+			Received:    e.Received,
+			Sent:        e.Sent,
+		}
+		for _, d := range e.Data {
+			h.Data = append(h.Data, d.String())
+		}
+		out = append(out, h)
+	}
+
+	return out
+}
+
+// printHistory shows the text output of the history
+func printHistory(ctx *Context, history []wallet.HistoryEntry) {
+	for _, e := range history {
+		ctx.Printf("block %s\theight %d\ttx %s\treceived %s\tsent %s\n",
+			e.Info.Block.BlockHash(), e.Info.BlockHeight, e.Info.Tx.TxHash(), ctx.Amount(e.Received), ctx.Amount(e.Sent))
+		for _, d := range e.Data {
+			if text, ok := d.Text(); ok {
+				ctx.Printf("\tdata output %d: %s (%q)\n", d.VIndex, d, text)
+			} else {
+				ctx.Printf("\tdata output %d: %s\n", d.VIndex, d)
+			}
+		}
+	}
+}
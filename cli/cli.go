@@ -0,0 +1,371 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package cli implements the commands of the soter tool. The single-purpose tools (balance, sendcoin, genwallet,
+// walletweb, senttx, multisig, timelock, signmessage, verifymessage and keyring) run commands of the package too, so
+// that they share flags, output and exit codes.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/config"
+	"github.com/soteria-dag/soterd/soterutil"
+	"io"
+	"os"
+	"strings"
+)
+
+// The output formats that can be chosen with -output
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// The output and errors of commands are written to stdout and stderr
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// SchemaVersion is the version of the json output of commands. It's increased when a field is removed or changes
+// meaning; fields can be added without increasing it.
+const SchemaVersion = 1
+
+// The schemas of the json output of commands, which are named in its header
+const (
+	SchemaError    = "error"
+	SchemaWallet   = "wallet"
+	SchemaBalance  = "balance"
+	SchemaCompare  = "compare"
+	SchemaHistory  = "history"
+	SchemaSend     = "send"
+	SchemaTx       = "tx"
+	SchemaSentTx   = "senttx"
+	SchemaMultisig = "multisig"
+	SchemaTimeLock = "timelock"
+	SchemaMessage  = "message"
+	SchemaKeyring  = "keyring"
+)
+
+// Header starts the json output of every command, naming the schema of the output and its version
+type Header struct {
+	Schema  string `json:"schema"`
+	Version int    `json:"version"`
+}
+
+func (h *Header) header() *Header {
+	return h
+}
+
+// jsonOutput is the json output of a command, which starts with a Header
+type jsonOutput interface {
+	header() *Header
+}
+
+// ErrorOutput is the json output of a command that failed
+type ErrorOutput struct {
+	Header
+	HadError  bool      `json:"hadError"`
+	ErrorCode ErrorCode `json:"errorCode"`
+	ErrorMsg  string    `json:"errorMsg"`
+}
+
+// Context is what a command runs with: the shared configuration, and the format to show its output in
+type Context struct {
+	Config *config.Config
+	Output string
+
+	// WalletName is the wallet file of -w, for commands that use a wallet
+	WalletName string
+
+	// jsonFlag is -json, a shorthand for -output json
+	jsonFlag bool
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// newContext defines the global flags on the flag set, which every command has, and the configuration flags of the
+// command
+func newContext(fs *flag.FlagSet, c *command) *Context {
+	ctx := Context{
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if c.config != nil {
+		ctx.Config = c.config(fs)
+	} else {
+		ctx.Config = config.New(fs)
+	}
+	fs.StringVar(&ctx.Output, "output", OutputText, "Output format: text or json")
+	fs.BoolVar(&ctx.jsonFlag, "json", false, "Output in JSON format (same as -output json)")
+
+	return &ctx
+}
+
+// walletFlag defines the -w flag of the wallet file, for commands that use a wallet
+func (ctx *Context) walletFlag(fs *flag.FlagSet, desc string) {
+	fs.StringVar(&ctx.WalletName, "w", "", desc)
+}
+
+// JSON returns true if the output is json
+func (ctx *Context) JSON() bool {
+	return ctx.jsonFlag || ctx.Output == OutputJSON
+}
+
+// Printf shows a line of text output. Nothing is shown when the output is json, so that the output can be parsed.
+func (ctx *Context) Printf(format string, args ...interface{}) {
+	if ctx.JSON() {
+		return
+	}
+	fmt.Fprintf(ctx.stdout, format, args...)
+}
+
+// Warnf shows a warning, which goes to stderr so that it doesn't mix with the output
+func (ctx *Context) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(ctx.stderr, "WARNING: "+format+"\n", args...)
+}
+
+// Amount returns the amount as it's shown in text output, in the unit and number format of the configuration
+func (ctx *Context) Amount(a soterutil.Amount) string {
+	return ctx.Config.Display.Format(a)
+}
+
+// PrintJSON shows the json output of a command, with the schema and its version in the header
+func (ctx *Context) PrintJSON(schema string, v jsonOutput) error {
+	h := v.header()
+	h.Schema = schema
+	h.Version = SchemaVersion
+
+	js, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.stdout, string(js))
+	return nil
+}
+
+// command is a command of the soter tool, or a group of subcommands
+type command struct {
+	name string
+	// args is the usage of the command's positional arguments
+	args    string
+	summary string
+	// setup defines the command's flags on the flag set, and returns the function that runs the command with its
+	// positional arguments
+	setup func(ctx *Context, fs *flag.FlagSet) func(args []string) error
+	// config defines the configuration flags of the command on the flag set. By default they're the flags of the
+	// shared configuration, from config.New.
+	config func(fs *flag.FlagSet) *config.Config
+	// load reads the configuration, once the flags are parsed. By default the configuration is loaded, and a network
+	// is required.
+	load func(ctx *Context) error
+	// errorOutput returns the schema and json output of the command failing with the error, if it isn't an
+	// ErrorOutput
+	errorOutput func(e ErrorOutput) (string, jsonOutput)
+	// commands are the subcommands of a group
+	commands []*command
+}
+
+// commands are the commands of the soter tool, in the order they're listed in its usage
+var commands []*command
+
+// legacyCommands are run by the single-purpose tools that came before soter, and aren't soter commands
+var legacyCommands []*command
+
+func init() {
+	commands = []*command{
+		walletCommand,
+		balanceCommand,
+		sendCommand,
+		historyCommand,
+		txCommand,
+		serveCommand,
+	}
+	legacyCommands = []*command{
+		genwalletCommand,
+		senttxCommand,
+		multisigCommand,
+		timelockCommand,
+		signmessageCommand,
+		verifymessageCommand,
+		keyringCommand,
+	}
+}
+
+// loadConfig is the default load of commands: it loads the configuration, and requires a network. The network of the
+// wallet is used if none is chosen.
+func loadConfig(ctx *Context) error {
+	err := ctx.Config.LoadWallet(ctx.WalletName)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Config.RequireNetwork()
+}
+
+// findCommand returns the command with the name among the commands, or nil if there isn't one
+func findCommand(cmds []*command, name string) *command {
+	for _, c := range cmds {
+		if c.name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// printCommands lists the commands with their summaries
+func printCommands(w io.Writer, cmds []*command) {
+	for _, c := range cmds {
+		if len(c.commands) > 0 {
+			for _, sub := range c.commands {
+				name := strings.TrimSpace(fmt.Sprintf("%s %s %s", c.name, sub.name, sub.args))
+				fmt.Fprintf(w, "  %-32s %s\n", name, sub.summary)
+			}
+			continue
+		}
+		name := strings.TrimSpace(fmt.Sprintf("%s %s", c.name, c.args))
+		fmt.Fprintf(w, "  %-32s %s\n", name, c.summary)
+	}
+}
+
+// usage shows the usage of the soter tool, or of a group of commands
+func usage(w io.Writer, prog string, cmds []*command) {
+	fmt.Fprintf(w, "Usage: %s COMMAND [flags] [args]\n\nCommands:\n", prog)
+	printCommands(w, cmds)
+	fmt.Fprintf(w, "\nRun '%s COMMAND -h' for the flags of a command.\n", prog)
+}
+
+// Main runs the soter command given by the arguments, which don't include the program name. It returns the exit code.
+func Main(args []string) int {
+	prog := "soter"
+	if len(args) == 0 {
+		usage(stderr, prog, commands)
+		return exitUsage
+	}
+
+	cmds := commands
+	path := prog
+	for {
+		name := args[0]
+		switch name {
+		case "help", "-h", "-help", "--help":
+			usage(stdout, path, cmds)
+			return exitOK
+		}
+
+		c := findCommand(cmds, name)
+		if c == nil {
+			fmt.Fprintf(stderr, "Unknown command %s\n\n", name)
+			usage(stderr, path, cmds)
+			return exitUsage
+		}
+		path += " " + name
+		args = args[1:]
+
+		if len(c.commands) == 0 {
+			return run(path, c, args)
+		}
+		if len(args) == 0 {
+			usage(stderr, path, c.commands)
+			return exitUsage
+		}
+		cmds = c.commands
+	}
+}
+
+// Run runs the named soter command, for the single-purpose tools. prog is the tool's name, which its usage is shown
+// with. It returns the exit code.
+func Run(prog, name string, args []string) int {
+	cmds := make([]*command, 0, len(commands)+len(legacyCommands))
+	cmds = append(append(cmds, commands...), legacyCommands...)
+	var c *command
+	for _, part := range strings.Fields(name) {
+		c = findCommand(cmds, part)
+		if c == nil {
+			break
+		}
+		cmds = c.commands
+	}
+	if c == nil || len(c.commands) > 0 {
+		fmt.Fprintf(stderr, "Unknown command %s\n", name)
+		return exitError
+	}
+
+	return run(prog, c, args)
+}
+
+// run parses the flags of the command, and runs it. It returns the exit code.
+func run(prog string, c *command, args []string) int {
+	fs := flag.NewFlagSet(prog, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	ctx := newContext(fs, c)
+	fn := c.setup(ctx, fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s\n", strings.TrimSpace(fmt.Sprintf("%s [flags] %s", prog, c.args)))
+		if len(c.summary) > 0 {
+			fmt.Fprintf(out, "\n%s\n", c.summary)
+		}
+		fmt.Fprintf(out, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		// The flag package has shown the error and usage
+		return exitUsage
+	}
+
+	if ctx.Output != OutputText && ctx.Output != OutputJSON {
+		return fail(ctx, c, fs, usageErrorf("Invalid output format %s; use %s or %s", ctx.Output, OutputText, OutputJSON))
+	}
+
+	load := c.load
+	if load == nil {
+		load = loadConfig
+	}
+	err = withCode(CodeConfig, load(ctx))
+	if err == nil {
+		err = fn(fs.Args())
+	}
+	if err != nil {
+		return fail(ctx, c, fs, err)
+	}
+
+	return exitOK
+}
+
+// fail shows the error the command failed with, and returns the exit code for it
+func fail(ctx *Context, c *command, fs *flag.FlagSet, err error) int {
+	code := errorCode(err)
+	var ce *codeError
+	if errors.As(err, &ce) && ce.err == nil {
+		return code.ExitCode()
+	}
+
+	if ctx.JSON() {
+		e := ErrorOutput{HadError: true, ErrorCode: code, ErrorMsg: err.Error()}
+		if c.errorOutput != nil {
+			_ = ctx.PrintJSON(c.errorOutput(e))
+		} else {
+			_ = ctx.PrintJSON(SchemaError, &e)
+		}
+	} else {
+		fmt.Fprintln(ctx.stderr, err)
+		if code == CodeUsage {
+			fmt.Fprintln(ctx.stderr)
+			fs.Usage()
+		}
+	}
+
+	return code.ExitCode()
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/sotertools/credentials"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// capture runs the function with the output of commands going to buffers, and returns the buffers
+func capture(t *testing.T, fn func()) (*bytes.Buffer, *bytes.Buffer) {
+	var out, errOut bytes.Buffer
+	oldOut, oldErr := stdout, stderr
+	stdout, stderr = &out, &errOut
+	defer func() {
+		stdout, stderr = oldOut, oldErr
+	}()
+
+	fn()
+	return &out, &errOut
+}
+
+func TestMain(m *testing.M) {
+	// Don't read the user's config file
+	os.Setenv("SOTER_CONFIG", os.DevNull)
+	os.Exit(m.Run())
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"-h"}, exitOK},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"wallet"}, exitUsage},
+		{[]string{"wallet", "bogus"}, exitUsage},
+		{[]string{"wallet", "create", "-h"}, exitOK},
+		{[]string{"wallet", "create", "-bogus"}, exitUsage},
+		{[]string{"balance", "-simnet"}, exitUsage},
+		{[]string{"balance", "-simnet", "-output", "yaml", "-address", "x"}, exitUsage},
+		{[]string{"tx", "decode", "-simnet", "zz"}, CodeInvalid.ExitCode()},
+		// Amounts more precise than the base unit, or with an unknown unit, are invalid
+		{[]string{"send", "-simnet", "-source", "x", "-dest", "y", "-amt", "0.0000000001"}, CodeInvalid.ExitCode()},
+		{[]string{"send", "-simnet", "-source", "x", "-dest", "y", "-amt", "1", "-fee", "1 BTC"}, CodeInvalid.ExitCode()},
+	}
+	legacyTests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"senttx", []string{"-simnet", "-abandon", "x", "-bumpfee", "y"}, exitUsage},
+		{"senttx", []string{"-simnet", "-bumpfee", "x"}, exitUsage},
+		{"multisig", []string{"-simnet"}, exitUsage},
+		{"multisig", []string{"-simnet", "-json", "-sign", "tx.json"}, exitUsage},
+		{"timelock", []string{"-simnet", "-create", "-lockheight", "5", "-locktime", "2030-01-01T00:00:00Z"}, exitUsage},
+		{"signmessage", []string{"-simnet"}, exitUsage},
+		{"verifymessage", []string{"-simnet", "-address", "x", "-signature", "y"}, CodeInvalid.ExitCode()},
+		{"keyring", []string{"-list", "-delete", "x"}, exitUsage},
+		// The tools without an RPC server don't have its flags
+		{"verifymessage", []string{"-simnet", "-rpcserver", "127.0.0.1:1"}, exitUsage},
+		{"keyring", []string{"-simnet", "-list"}, exitUsage},
+	}
+
+	for _, test := range tests {
+		var code int
+		capture(t, func() {
+			code = Main(test.args)
+		})
+		if code != test.code {
+			t.Errorf("wrong exit code of soter %v; got %d, want %d", test.args, code, test.code)
+		}
+	}
+
+	for _, test := range legacyTests {
+		var code int
+		capture(t, func() {
+			code = Run(test.name, test.name, test.args)
+		})
+		if code != test.code {
+			t.Errorf("wrong exit code of %s %v; got %d, want %d", test.name, test.args, code, test.code)
+		}
+	}
+
+	var code int
+	capture(t, func() {
+		code = Run("walletweb", "bogus", nil)
+	})
+	if code != exitError {
+		t.Errorf("wrong exit code of unknown command; got %d, want %d", code, exitError)
+	}
+}
+
+func TestErrorOutput(t *testing.T) {
+	var code int
+	out, _ := capture(t, func() {
+		code = Run("balance", "balance", []string{"-simnet", "-json"})
+	})
+	if code != exitUsage {
+		t.Errorf("wrong exit code; got %d, want %d", code, exitUsage)
+	}
+
+	var result BalanceOutput
+	err := json.Unmarshal(out.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if result.Schema != SchemaBalance || result.Version != SchemaVersion {
+		t.Errorf("wrong header of error output: %+v", result.Header)
+	}
+	if !result.HadError || result.ErrorCode != CodeUsage || result.Balance != -1 || len(result.ErrorMsg) == 0 {
+		t.Errorf("wrong error output: %+v", result)
+	}
+
+	// Commands without their own error output show an ErrorOutput
+	out, _ = capture(t, func() {
+		code = Main([]string{"tx", "decode", "-simnet", "-json", "zz"})
+	})
+	if code != CodeInvalid.ExitCode() {
+		t.Errorf("wrong exit code; got %d, want %d", code, CodeInvalid.ExitCode())
+	}
+	var e ErrorOutput
+	err = json.Unmarshal(out.Bytes(), &e)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if e.Schema != SchemaError || !e.HadError || e.ErrorCode != CodeInvalid || len(e.ErrorMsg) == 0 {
+		t.Errorf("wrong error output: %+v", e)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	// Every error code has its own exit code
+	codes := make(map[int]ErrorCode)
+	for c, exitCode := range exitCodes {
+		if other, ok := codes[exitCode]; ok {
+			t.Errorf("error codes %s and %s have the same exit code %d", c, other, exitCode)
+		}
+		if exitCode == exitOK {
+			t.Errorf("error code %s has the exit code of success", c)
+		}
+		codes[exitCode] = c
+	}
+
+	tests := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{errors.New("failed"), CodeError},
+		{usageErrorf("bad flag"), CodeUsage},
+		{errorf(CodeFunds, "not enough coin"), CodeFunds},
+		{fmt.Errorf("wrapped: %w", errorf(CodeRPC, "no node")), CodeRPC},
+		// An error keeps the code it already has
+		{withCode(CodeConfig, errorf(CodeWallet, "no wallet")), CodeWallet},
+		{withCode(CodeConfig, usageErrorf("bad flag")), CodeUsage},
+		{withCode(CodeConfig, errors.New("bad config")), CodeConfig},
+		// The errors of the wallet package have their own codes, which are more precise than the code they were given
+		{errorf(CodeRPC, "failed to find transactions: %w", &wallet.InsufficientFundsError{}), CodeFunds},
+		{withCode(CodeWallet, wallet.ErrWrongPassphrase), CodePassphrase},
+		{fmt.Errorf("failed to sign: %w", wallet.ErrWalletLocked), CodePassphrase},
+		{&wallet.RejectedError{Reason: "already have transaction"}, CodeRejected},
+		{withCode(CodeConfig, &wallet.NetworkMismatchError{Of: "wallet", Network: "testnet", Want: "simnet"}), CodeNetwork},
+		{errorf(CodeRPC, "failed to scan dag: %w", &wallet.MissingPrevTxError{}), CodeDAG},
+		{fmt.Errorf("failed: %w", wallet.ErrRPCUnavailable), CodeRPC},
+		{fmt.Errorf("failed: %w", nodes.ErrNoHealthyNode), CodeRPC},
+	}
+	for _, test := range tests {
+		got := errorCode(test.err)
+		if got != test.code {
+			t.Errorf("wrong error code of %q; got %s, want %s", test.err, got, test.code)
+		}
+	}
+
+	if withCode(CodeError, nil) != nil {
+		t.Errorf("withCode of nil error isn't nil")
+	}
+}
+
+func TestTxDecode(t *testing.T) {
+	params := &chaincfg.SimNetParams
+	addr, err := soterutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("failed to create output script: %s", err)
+	}
+	dataOut, err := wallet.NullDataOutput([]byte("data"))
+	if err != nil {
+		t.Fatalf("failed to create null-data output: %s", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(chaincfg.SimNetParams.GenesisHash, 1), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, pkScript))
+	tx.AddTxOut(dataOut)
+	var buf bytes.Buffer
+	err = tx.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("failed to serialize transaction: %s", err)
+	}
+
+	var code int
+	out, errOut := capture(t, func() {
+		code = Main([]string{"tx", "decode", "-simnet", "-output", "json", hex.EncodeToString(buf.Bytes())})
+	})
+	if code != exitOK {
+		t.Fatalf("tx decode failed with code %d: %s", code, errOut)
+	}
+
+	var result TxOutput
+	err = json.Unmarshal(out.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if result.Schema != SchemaTx {
+		t.Errorf("wrong schema of tx decode output; got %s, want %s", result.Schema, SchemaTx)
+	}
+	if result.Tx != tx.TxHash().String() {
+		t.Errorf("wrong tx hash; got %s, want %s", result.Tx, tx.TxHash())
+	}
+	if len(result.Inputs) != 1 || result.Inputs[0].Tx != chaincfg.SimNetParams.GenesisHash.String() ||
+		result.Inputs[0].VOut != 1 || result.Inputs[0].Coinbase {
+		t.Errorf("wrong inputs: %+v", result.Inputs)
+	}
+	if len(result.Outputs) != 2 {
+		t.Fatalf("wrong number of outputs; got %d, want 2", len(result.Outputs))
+	}
+	o := result.Outputs[0]
+	if o.Value != 1000 || len(o.Addresses) != 1 || o.Addresses[0] != addr.EncodeAddress() {
+		t.Errorf("wrong pay-to-address output: %+v", o)
+	}
+	o = result.Outputs[1]
+	if o.Data != hex.EncodeToString([]byte("data")) || len(o.Addresses) != 0 {
+		t.Errorf("wrong null-data output: %+v", o)
+	}
+}
+
+func TestWalletCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "wallet.db")
+
+	var code int
+	out, errOut := capture(t, func() {
+		code = Main([]string{"wallet", "create", "-simnet", "-json", "-w", name, "-priv", "priv", "-pub", "pub"})
+	})
+	if code != exitOK {
+		t.Fatalf("wallet create failed with code %d: %s", code, errOut)
+	}
+	var created WalletOutput
+	err = json.Unmarshal(out.Bytes(), &created)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if created.Schema != SchemaWallet || created.Version != SchemaVersion {
+		t.Errorf("wrong header of wallet create output: %+v", created.Header)
+	}
+	if created.Network != chaincfg.SimNetParams.Name || len(created.Address) == 0 {
+		t.Errorf("wrong wallet create output: %+v", created)
+	}
+
+	// The network is read from the wallet
+	out, errOut = capture(t, func() {
+		code = Main([]string{"wallet", "addresses", "-json", "-w", name, "-pub", "pub"})
+	})
+	if code != exitOK {
+		t.Fatalf("wallet addresses failed with code %d: %s", code, errOut)
+	}
+	var listed WalletOutput
+	err = json.Unmarshal(out.Bytes(), &listed)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if len(listed.Accounts) == 0 || len(listed.Accounts[0].Addresses) != 1 ||
+		listed.Accounts[0].Addresses[0] != created.Address {
+		t.Errorf("wrong wallet addresses output: %+v", listed)
+	}
+
+	// An existing wallet isn't created again
+	capture(t, func() {
+		code = Main([]string{"wallet", "create", "-simnet", "-w", name, "-priv", "priv", "-pub", "pub"})
+	})
+	if code != CodeWallet.ExitCode() {
+		t.Errorf("wrong exit code of creating an existing wallet; got %d, want %d", code, CodeWallet.ExitCode())
+	}
+
+	// A wallet isn't created without a private password, which isn't prompted for because stdin isn't a terminal
+	os.Unsetenv(credentials.PrivPassEnv)
+	empty := filepath.Join(dir, "empty.db")
+	for _, args := range [][]string{
+		{"wallet", "create", "-simnet", "-w", empty, "-pub", "pub"},
+		{"genwallet", "-simnet", "-w", empty, "-priv", "", "-pub", "pub"},
+	} {
+		capture(t, func() {
+			if args[0] == "genwallet" {
+				code = Run("genwallet", "genwallet", args[1:])
+			} else {
+				code = Main(args)
+			}
+		})
+		if code != CodeConfig.ExitCode() {
+			t.Errorf("wrong exit code of %v; got %d, want %d", args, code, CodeConfig.ExitCode())
+		}
+		if fileExists(empty) {
+			t.Fatalf("%v created a wallet with an empty private password", args)
+		}
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "keyring")
+	os.Setenv(credentials.KeyringPassEnv, "pass")
+	defer os.Unsetenv(credentials.KeyringPassEnv)
+	os.Setenv(credentials.SecretEnv, "secret")
+	defer os.Unsetenv(credentials.SecretEnv)
+
+	var code int
+	out, errOut := capture(t, func() {
+		code = Run("keyring", "keyring", []string{"-json", "-keyring", name, "-set", "simnet-priv"})
+	})
+	if code != exitOK {
+		t.Fatalf("keyring -set failed with code %d: %s", code, errOut)
+	}
+	var stored KeyringOutput
+	err = json.Unmarshal(out.Bytes(), &stored)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if stored.Schema != SchemaKeyring || stored.Keyring != name || stored.Stored != "simnet-priv" {
+		t.Errorf("wrong keyring -set output: %+v", stored)
+	}
+
+	out, errOut = capture(t, func() {
+		code = Run("keyring", "keyring", []string{"-json", "-keyring", name, "-list"})
+	})
+	if code != exitOK {
+		t.Fatalf("keyring -list failed with code %d: %s", code, errOut)
+	}
+	var listed KeyringOutput
+	err = json.Unmarshal(out.Bytes(), &listed)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if len(listed.Entries) != 1 || listed.Entries[0] != "simnet-priv" {
+		t.Errorf("wrong keyring entries; got %v, want [simnet-priv]", listed.Entries)
+	}
+
+	// Removing an entry that isn't in the keyring fails with a json error
+	out, _ = capture(t, func() {
+		code = Run("keyring", "keyring", []string{"-json", "-keyring", name, "-delete", "bogus"})
+	})
+	var e ErrorOutput
+	err = json.Unmarshal(out.Bytes(), &e)
+	if err != nil {
+		t.Fatalf("failed to parse json output %q: %s", out, err)
+	}
+	if code != CodeInvalid.ExitCode() || e.ErrorCode != CodeInvalid {
+		t.Errorf("wrong error of removing a missing entry; got code %d and %+v, want %s", code, e, CodeInvalid)
+	}
+}
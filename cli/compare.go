@@ -0,0 +1,207 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"strings"
+)
+
+// CompareOutput is the json output of -compare
+type CompareOutput struct {
+	Header
+	Nodes         []NodeOutput         `json:"nodes"`
+	SameTips      bool                 `json:"sameTips"`
+	MissingBlocks []MissingBlockOutput `json:"missingBlocks,omitempty"`
+	Addresses     []AddressOutput      `json:"addresses"`
+	Mismatch      bool                 `json:"mismatch"`
+	HadError      bool                 `json:"hadError"`
+	ErrorCode     ErrorCode            `json:"errorCode,omitempty"`
+	ErrorMsg      string               `json:"errorMsg"`
+}
+
+// NodeOutput is the view of the dag of a node
+type NodeOutput struct {
+	Server    string   `json:"server"`
+	Tips      []string `json:"tips"`
+	MaxHeight int32    `json:"maxHeight"`
+	Blocks    int      `json:"blocks"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// MissingBlockOutput is a block that some nodes have, and others don't
+type MissingBlockOutput struct {
+	Hash        string   `json:"hash"`
+	Height      int32    `json:"height"`
+	MissingFrom []string `json:"missingFrom"`
+}
+
+// AddressOutput is the balance of an address on each node
+type AddressOutput struct {
+	Address  string              `json:"address"`
+	Balances []NodeBalanceOutput `json:"balances"`
+	Mismatch bool                `json:"mismatch"`
+}
+
+// NodeBalanceOutput is the balance of an address on a node
+type NodeBalanceOutput struct {
+	Server           string           `json:"server"`
+	Balance          soterutil.Amount `json:"balance"`
+	SpendableBalance soterutil.Amount `json:"spendableBalance"`
+}
+
+// nodeBalance is the balance and spendable balance of an address on a node
+type nodeBalance struct {
+	balance   soterutil.Amount
+	spendable soterutil.Amount
+}
+
+// compareNodes runs the balance scan of the addresses against every node, and prints how the nodes' views of the dag
+// and balances differ. It returns nil if the nodes agree, and otherwise a codeError: CodeRPC if a node couldn't be
+// scanned, and CodeMismatch if they disagree. The exit code of a mismatch is different from the exit code of errors,
+// so that forks can be alerted on.
+func compareNodes(ctx *Context, m *nodes.Manager, addresses []soterutil.Address, params *chaincfg.Params) error {
+	servers := m.Servers()
+	views := make([]*wallet.DAGView, len(servers))
+	balances := make([][]nodeBalance, len(servers))
+
+	errs := m.ForEach(func(i int, client *rpcclient.Client) error {
+		view, err := wallet.GetDAGView(client)
+		if err != nil {
+			return fmt.Errorf("failed to get view of dag: %s", err)
+		}
+
+		b := make([]nodeBalance, len(addresses))
+		for j, address := range addresses {
+			b[j].balance, b[j].spendable, err = wallet.GetBalance(client, []soterutil.Address{address}, params)
+			if err != nil {
+				return fmt.Errorf("failed to get balance of address %s: %s", address, err)
+			}
+		}
+
+		views[i] = view
+		balances[i] = b
+		return nil
+	})
+
+	out := CompareOutput{SameTips: true}
+	// The nodes that were scanned are compared
+	scanned := make([]int, 0, len(servers))
+	failed := make([]string, 0)
+	for i, server := range servers {
+		n := NodeOutput{Server: server}
+		if errs[i] != nil {
+			n.Error = errs[i].Error()
+			failed = append(failed, fmt.Sprintf("%s: %s", server, errs[i]))
+		} else {
+			n.Tips = views[i].Tips
+			n.MaxHeight = views[i].MaxHeight
+			n.Blocks = len(views[i].Blocks)
+			scanned = append(scanned, i)
+		}
+		out.Nodes = append(out.Nodes, n)
+	}
+	if len(failed) > 0 {
+		out.HadError = true
+		out.ErrorCode = CodeRPC
+		out.ErrorMsg = "failed to scan nodes: " + strings.Join(failed, "; ")
+	}
+
+	scannedViews := make([]*wallet.DAGView, 0, len(scanned))
+	for _, i := range scanned {
+		scannedViews = append(scannedViews, views[i])
+	}
+	if len(scannedViews) > 1 {
+		out.SameTips = wallet.SameTips(scannedViews)
+		out.Mismatch = !out.SameTips
+
+		for _, missing := range wallet.MissingBlocks(scannedViews) {
+			b := MissingBlockOutput{Hash: missing.Hash.String(), Height: missing.Height}
+			for _, j := range missing.MissingFrom {
+				b.MissingFrom = append(b.MissingFrom, servers[scanned[j]])
+			}
+			out.MissingBlocks = append(out.MissingBlocks, b)
+			out.Mismatch = true
+		}
+	}
+
+	for j, address := range addresses {
+		a := AddressOutput{Address: address.EncodeAddress()}
+		for k, i := range scanned {
+			b := balances[i][j]
+			a.Balances = append(a.Balances, NodeBalanceOutput{
+				Server:           servers[i],
+				Balance:          b.balance,
+				SpendableBalance: b.spendable,
+			})
+			if k > 0 && b != balances[scanned[0]][j] {
+				a.Mismatch = true
+				out.Mismatch = true
+			}
+		}
+		out.Addresses = append(out.Addresses, a)
+	}
+
+	if ctx.JSON() {
+		err := ctx.PrintJSON(SchemaCompare, &out)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, n := range out.Nodes {
+			if len(n.Error) > 0 {
+				ctx.Printf("node %s: %s\n", n.Server, n.Error)
+				continue
+			}
+			ctx.Printf("node %s: %d tips at height %d, %d blocks\n", n.Server, len(n.Tips), n.MaxHeight, n.Blocks)
+		}
+		if len(scanned) > 1 && !out.SameTips {
+			ctx.Printf("MISMATCH: the nodes have different tips\n")
+			for _, n := range out.Nodes {
+				if len(n.Error) == 0 {
+					ctx.Printf("\ttips of %s: %s\n", n.Server, strings.Join(n.Tips, " "))
+				}
+			}
+		}
+		for _, b := range out.MissingBlocks {
+			ctx.Printf("MISMATCH: block %s at height %d is missing from %s\n",
+				b.Hash, b.Height, strings.Join(b.MissingFrom, ", "))
+		}
+		for j, a := range out.Addresses {
+			if a.Mismatch {
+				ctx.Printf("MISMATCH: balances of %s differ between the nodes\n", a.Address)
+			} else {
+				ctx.Printf("balances of %s:\n", a.Address)
+			}
+			for k, i := range scanned {
+				ctx.Printf("\t%s: balance %s, spendable balance %s\n",
+					a.Balances[k].Server, ctx.Amount(balances[i][j].balance), ctx.Amount(balances[i][j].spendable))
+			}
+		}
+
+		switch {
+		case out.HadError:
+			ctx.Printf("some nodes couldn't be compared\n")
+		case out.Mismatch:
+			ctx.Printf("the nodes disagree\n")
+		default:
+			ctx.Printf("the nodes agree\n")
+		}
+	}
+
+	switch {
+	case out.HadError:
+		return &codeError{code: CodeRPC}
+	case out.Mismatch:
+		return &codeError{code: CodeMismatch}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+)
+
+// ErrorCode is the stable code of the error a command failed with, which is shown in its json output as errorCode.
+// Each error code has its own exit code, so that scripts can react to a failure without parsing its message.
+type ErrorCode string
+
+const (
+	// CodeError is an error that doesn't have a more specific code
+	CodeError ErrorCode = "error"
+	// CodeUsage means the flags or arguments of the command are invalid
+	CodeUsage ErrorCode = "usage"
+	// CodeInvalid means a value given to the command, such as an address, amount or transaction, is invalid
+	CodeInvalid ErrorCode = "invalid"
+	// CodeConfig means the configuration can't be loaded, such as a config file, network or password source
+	CodeConfig ErrorCode = "config"
+	// CodeWallet means the wallet can't be created or opened
+	CodeWallet ErrorCode = "wallet"
+	// CodeRPC means no soterd node could answer
+	CodeRPC ErrorCode = "rpc"
+	// CodeFunds means there isn't enough spendable coin for the transaction
+	CodeFunds ErrorCode = "funds"
+	// CodeMismatch means the soterd nodes compared by balance -compare disagree
+	CodeMismatch ErrorCode = "mismatch"
+	// CodeTimeout means the wait for confirmations of a transaction timed out, or was interrupted
+	CodeTimeout ErrorCode = "timeout"
+	// CodePassphrase means the wallet's passphrase is wrong, or the wallet is locked
+	CodePassphrase ErrorCode = "passphrase"
+	// CodeRejected means the soterd node rejected the transaction
+	CodeRejected ErrorCode = "rejected"
+	// CodeNetwork means the wallet or a key is for another network than the one in use
+	CodeNetwork ErrorCode = "network"
+	// CodeDAG means the dag is missing a transaction that another transaction spends from
+	CodeDAG ErrorCode = "dag"
+)
+
+// The exit codes of commands
+const (
+	exitOK = 0
+	// exitError is the exit code of errors that don't have a more specific code
+	exitError = 1
+	// exitUsage is the exit code of invalid flags or arguments, which is the code the flag package exits with
+	exitUsage = 2
+)
+
+// exitCodes are the exit codes of the error codes. They must not change, because scripts depend on them.
+var exitCodes = map[ErrorCode]int{
+	CodeError:      exitError,
+	CodeUsage:      exitUsage,
+	CodeInvalid:    3,
+	CodeConfig:     4,
+	CodeWallet:     5,
+	CodeRPC:        6,
+	CodeFunds:      7,
+	CodeMismatch:   8,
+	CodeTimeout:    9,
+	CodePassphrase: 10,
+	CodeRejected:   11,
+	CodeNetwork:    12,
+	CodeDAG:        13,
+}
+
+// ExitCode returns the exit code of the error code
+func (c ErrorCode) ExitCode() int {
+	code, ok := exitCodes[c]
+	if !ok {
+		return exitError
+	}
+
+	return code
+}
+
+// usageError is an error in how a command was called. The command's usage is shown with it.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usageErrorf returns a usageError with the formatted message
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// codeError is an error with its error code. If err is nil, nothing more is shown, because the command has already
+// shown its output.
+type codeError struct {
+	code ErrorCode
+	err  error
+}
+
+func (e *codeError) Error() string {
+	if e.err == nil {
+		return string(e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns the error with the error code. An error that already has a code keeps it, and nil is returned for
+// a nil error.
+func withCode(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ce *codeError
+	var ue *usageError
+	if errors.As(err, &ce) || errors.As(err, &ue) {
+		return err
+	}
+
+	return &codeError{code: code, err: err}
+}
+
+// errorf returns an error with the error code and formatted message
+func errorf(code ErrorCode, format string, args ...interface{}) error {
+	return &codeError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// errorCode returns the error code of the error. The errors of the wallet package are more precise than the code they
+// were given where they happened, so their code is used when they're found.
+func errorCode(err error) ErrorCode {
+	var ce *codeError
+	var ue *usageError
+	switch {
+	case errors.As(err, &ue):
+		return CodeUsage
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return CodeFunds
+	case errors.Is(err, wallet.ErrWrongPassphrase), errors.Is(err, wallet.ErrWalletLocked):
+		return CodePassphrase
+	case errors.Is(err, wallet.ErrRejected):
+		return CodeRejected
+	case errors.Is(err, wallet.ErrNetworkMismatch):
+		return CodeNetwork
+	case errors.Is(err, wallet.ErrMissingPrevTx):
+		return CodeDAG
+	case errors.Is(err, wallet.ErrRPCUnavailable), errors.Is(err, nodes.ErrNoHealthyNode):
+		return CodeRPC
+	case errors.As(err, &ce):
+		return ce.code
+	default:
+		return CodeError
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/config"
+	"github.com/soteria-dag/sotertools/credentials"
+	"os"
+)
+
+// KeyringOutput is the json output of keyring
+type KeyringOutput struct {
+	Header
+	Keyring string `json:"keyring"`
+	// Entries are the names of the keyring's entries, with -list
+	Entries []string `json:"entries,omitempty"`
+	// Stored is the entry that was set, and Removed is the entry that was deleted
+	Stored  string `json:"stored,omitempty"`
+	Removed string `json:"removed,omitempty"`
+}
+
+// keyringCommand is the keyring tool, which manages a file of secrets that passwords can be read from
+var keyringCommand = &command{
+	name:    "keyring",
+	summary: "Store, remove or list the secrets of a keyring, which passwords can be read from with keyring:ENTRY",
+	// The keyring doesn't use a network or RPC server
+	config: func(fs *flag.FlagSet) *config.Config {
+		return &config.Config{}
+	},
+	load: func(ctx *Context) error {
+		return nil
+	},
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var keyringName, setEntry, deleteEntry string
+		var list bool
+
+		fs.StringVar(&keyringName, "keyring", credentials.DefaultKeyringPath(), "Keyring file name (default can be set with $"+credentials.KeyringFileEnv+")")
+		fs.StringVar(&setEntry, "set", "", "Keyring entry to store the secret in")
+		fs.StringVar(&deleteEntry, "delete", "", "Keyring entry to remove")
+		fs.BoolVar(&list, "list", false, "List the names of the keyring's entries")
+		secret := credentials.NewPassphrase(fs, "secret", "Secret to store (with -set)", credentials.SecretEnv)
+		secret.Confirm()
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("keyring takes no arguments")
+			}
+			actions := 0
+			for _, given := range []bool{len(setEntry) > 0, len(deleteEntry) > 0, list} {
+				if given {
+					actions++
+				}
+			}
+			if actions != 1 {
+				return usageErrorf("You must specify one of -set, -delete, -list")
+			}
+
+			exists := fileExists(keyringName)
+			if !exists && len(setEntry) == 0 {
+				return errorf(CodeConfig, "Keyring %s doesn't exist", keyringName)
+			}
+
+			pass, err := keyringPassphrase(exists)
+			if err != nil {
+				return errorf(CodeConfig, "Failed to read keyring passphrase: %s", err)
+			}
+
+			k, err := credentials.OpenKeyring(keyringName, pass)
+			if err != nil {
+				return withCode(CodeConfig, err)
+			}
+			defer k.Close()
+
+			out := KeyringOutput{Keyring: keyringName}
+			switch {
+			case list:
+				out.Entries = k.Entries()
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaKeyring, &out)
+				}
+				for _, name := range out.Entries {
+					ctx.Printf("%s\n", name)
+				}
+				return nil
+			case len(deleteEntry) > 0:
+				err = k.Delete(deleteEntry)
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+				out.Removed = deleteEntry
+			default:
+				value, err := secret.Get()
+				if err != nil {
+					return withCode(CodeConfig, err)
+				}
+				k.Set(setEntry, value)
+				out.Stored = setEntry
+			}
+
+			err = k.Save()
+			if err != nil {
+				return fmt.Errorf("Failed to save keyring %s: %s", keyringName, err)
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaKeyring, &out)
+			}
+			if len(out.Removed) > 0 {
+				ctx.Printf("Removed %s from keyring %s\n", deleteEntry, keyringName)
+			} else {
+				ctx.Printf("Stored %s in keyring %s\n", setEntry, keyringName)
+			}
+			return nil
+		}
+	},
+}
+
+// keyringPassphrase returns the keyring's passphrase. When a new keyring is created, a prompt asks for it twice.
+func keyringPassphrase(exists bool) (string, error) {
+	_, ok := os.LookupEnv(credentials.KeyringPassEnv)
+	if exists || ok {
+		return credentials.KeyringPassphrase()
+	}
+
+	pass, err := credentials.Prompt("New keyring passphrase")
+	if err != nil {
+		return "", err
+	}
+	again, err := credentials.Prompt("New keyring passphrase (again)")
+	if err != nil {
+		return "", err
+	}
+	if again != pass {
+		return "", fmt.Errorf("passphrases don't match")
+	}
+
+	return pass, nil
+}
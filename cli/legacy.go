@@ -0,0 +1,120 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/credentials"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+)
+
+// genwalletCommand is the genwallet tool, which does what the wallet commands do, with the flags it had before them
+var genwalletCommand = &command{
+	name: "genwallet",
+	args: "[backup FILE | restore FILE | passwd]",
+	summary: `Create or open a wallet, and list its accounts. With a command:
+  backup FILE   Write an encrypted backup of the wallet to FILE
+  restore FILE  Create the wallet from the encrypted backup in FILE
+  passwd        Change the wallet's passwords to -newpriv and -newpub`,
+	// A restored wallet is for the network of its backup, so restore checks the network itself
+	load: func(ctx *Context) error {
+		return ctx.Config.LoadWallet(ctx.WalletName)
+	},
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var yes, rescanDAG bool
+		var importKeyStr, exportAddr string
+		var gap uint
+
+		ctx.walletFlag(fs, "Wallet file name")
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		fs.StringVar(&importKeyStr, "importkey", "", "WIF-encoded private key to import into the wallet's imported account")
+		fs.StringVar(&exportAddr, "exportkey", "", "Wallet address to show the WIF-encoded private key of")
+		fs.BoolVar(&yes, "yes", false, "Don't ask for confirmation before showing a private key (with -exportkey)")
+		backupPass := credentials.NewPassphrase(fs, "backuppass",
+			"Passphrase to encrypt or decrypt the backup file with (with backup, restore)", credentials.BackupPassEnv)
+		newPriv := credentials.NewPassphrase(fs, "newpriv", "New private password (with passwd)", credentials.NewPrivPassEnv)
+		newPub := credentials.NewPassphrase(fs, "newpub", "New public password (with passwd)", credentials.NewPubPassEnv)
+		fs.BoolVar(&rescanDAG, "rescan", false, "Look through the dag for used addresses after restoring (with restore)")
+		fs.UintVar(&gap, "gap", defaultRescanGap, "Number of unused addresses in a row to look for when rescanning")
+
+		return func(args []string) error {
+			var command string
+			if len(args) > 0 {
+				command = args[0]
+			}
+			switch command {
+			case "":
+			case "backup", "restore":
+				if len(args) != 2 {
+					return usageErrorf("%s takes the backup FILE", command)
+				}
+			case "passwd":
+				if len(args) != 1 {
+					return usageErrorf("passwd takes no arguments")
+				}
+			default:
+				return usageErrorf("Unknown command %s", command)
+			}
+
+			if len(importKeyStr) > 0 && len(exportAddr) > 0 {
+				return usageErrorf("You can only specify one of -importkey, -exportkey")
+			}
+			if len(command) > 0 && (len(importKeyStr) > 0 || len(exportAddr) > 0) {
+				return usageErrorf("-importkey and -exportkey can't be used with %s", command)
+			}
+			// An empty new password can be given, so the passwords are checked for being given instead of their values
+			if command == "passwd" && !newPriv.Given() && !newPub.Given() {
+				return usageErrorf("-newpriv or -newpub (or -newprivfrom, -newpubfrom) is required with passwd")
+			}
+			if rescanDAG && command != "restore" {
+				return usageErrorf("-rescan can only be used with restore")
+			}
+			if rescanDAG && len(ctx.Config.RPCServer) == 0 {
+				return usageErrorf("-rpcserver is required with -rescan")
+			}
+
+			if command == "restore" {
+				return restore(ctx, priv, pub, args[1], backupPass, rescanDAG, gap)
+			}
+
+			err := ctx.Config.RequireNetwork()
+			if err != nil {
+				return withCode(CodeConfig, err)
+			}
+
+			// The wallet is created if it doesn't exist
+			var w *soterwallet.Wallet
+			if command == "backup" || command == "passwd" || fileExists(walletPath(ctx)) {
+				w, err = openWallet(ctx, pub)
+			} else {
+				priv.Confirm()
+				pub.Confirm()
+				w, err = createWalletFrom(ctx, priv, pub)
+			}
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			switch {
+			case command == "backup":
+				backupPass.Confirm()
+				return backup(ctx, w, priv, args[1], backupPass)
+			case command == "passwd":
+				return passwd(ctx, w, priv, pub, newPriv, newPub)
+			case len(exportAddr) > 0:
+				return exportKey(ctx, w, priv, exportAddr, yes)
+			case len(importKeyStr) > 0:
+				_, err = importKey(ctx, w, priv, importKeyStr)
+				if err != nil {
+					return err
+				}
+			}
+
+			return listAccounts(ctx, w, true)
+		}
+	},
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/config"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/soterutil"
+)
+
+// MessageOutput is the json output of signmessage and verifymessage
+type MessageOutput struct {
+	Header
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	// Valid is true if verifymessage found the signature valid. An invalid signature fails with the invalid error code.
+	Valid bool `json:"valid,omitempty"`
+}
+
+// signmessageCommand is the signmessage tool, which signs a message with the private key of a wallet address
+var signmessageCommand = &command{
+	name:    "signmessage",
+	summary: "Sign a message with the private key of a wallet address, to prove that you own the address",
+	config:  config.NewNetwork,
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var inputAddress, message string
+
+		ctx.walletFlag(fs, "Wallet file name")
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		fs.StringVar(&inputAddress, "address", "", "Wallet address whose private key signs the message")
+		fs.StringVar(&message, "message", "", "Message to sign")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("signmessage takes no arguments")
+			}
+			if len(inputAddress) == 0 {
+				return usageErrorf("You must specify the address to sign the message with (-address)")
+			}
+
+			privPass, err := passphrase(ctx, priv)
+			if err != nil {
+				return err
+			}
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			addr, err := soterutil.DecodeAddress(inputAddress, w.ChainParams())
+			if err != nil {
+				return errorf(CodeInvalid, "Failed to decode address %s: %s", inputAddress, err)
+			}
+
+			sig, err := wallet.SignMessage(w, privPass, addr, message)
+			if err != nil {
+				return withCode(CodeWallet, err)
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaMessage, &MessageOutput{Address: inputAddress, Message: message, Signature: sig})
+			}
+			ctx.Printf("%s\n", sig)
+			return nil
+		}
+	},
+}
+
+// verifymessageCommand is the verifymessage tool, which checks a signature made by signmessage
+var verifymessageCommand = &command{
+	name:    "verifymessage",
+	summary: "Check that a message was signed by the private key of an address",
+	config:  config.NewNetwork,
+	load: func(ctx *Context) error {
+		err := ctx.Config.Load()
+		if err != nil {
+			return err
+		}
+
+		return ctx.Config.RequireNetwork()
+	},
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var inputAddress, signature, message string
+
+		fs.StringVar(&inputAddress, "address", "", "Address that signed the message")
+		fs.StringVar(&signature, "signature", "", "Base64-encoded signature of the message")
+		fs.StringVar(&message, "message", "", "Message that was signed")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("verifymessage takes no arguments")
+			}
+			if len(inputAddress) == 0 {
+				return usageErrorf("You must specify the address that signed the message (-address)")
+			}
+			if len(signature) == 0 {
+				return usageErrorf("You must specify the signature to check (-signature)")
+			}
+
+			addr, err := soterutil.DecodeAddress(inputAddress, ctx.Config.Params)
+			if err != nil {
+				return errorf(CodeInvalid, "Failed to decode address %s: %s", inputAddress, err)
+			}
+
+			valid, err := wallet.VerifyMessage(addr, signature, message, ctx.Config.Params)
+			if err != nil {
+				return errorf(CodeInvalid, "Failed to verify message: %s", err)
+			}
+			if !valid {
+				return errorf(CodeInvalid, "Signature is not valid for address %s", inputAddress)
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaMessage, &MessageOutput{
+					Address:   inputAddress,
+					Message:   message,
+					Signature: signature,
+					Valid:     true,
+				})
+			}
+			ctx.Printf("Signature is valid for address %s\n", inputAddress)
+			return nil
+		}
+	},
+}
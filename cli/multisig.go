@@ -0,0 +1,392 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"strings"
+)
+
+// MultisigOutput is the json output of multisig
+type MultisigOutput struct {
+	Header
+	// Address is the wallet address whose PubKey was shown, or the multisig address that was created
+	Address      string `json:"address,omitempty"`
+	PubKey       string `json:"pubKey,omitempty"`
+	NRequired    int    `json:"nRequired,omitempty"`
+	NKeys        int    `json:"nKeys,omitempty"`
+	RedeemScript string `json:"redeemScript,omitempty"`
+	// Addresses are the multisig addresses of the wallet and their balances, with -list
+	Addresses []MultisigAddressOutput `json:"addresses,omitempty"`
+	// File is the transaction file that was written, signed or sent
+	File   string           `json:"file,omitempty"`
+	Source string           `json:"source,omitempty"`
+	Dest   string           `json:"dest,omitempty"`
+	Amount soterutil.Amount `json:"amount,omitempty"`
+	// Outputs and Spends are what a signed transaction sends, and what it spends from each address. Its Fee is worked
+	// out from the outputs it spends in the dag.
+	Outputs    []TxOutOutput         `json:"outputs,omitempty"`
+	Spends     []MultisigSpendOutput `json:"spends,omitempty"`
+	Fee        soterutil.Amount      `json:"fee,omitempty"`
+	Signatures int                   `json:"signatures,omitempty"`
+	Required   int                   `json:"required,omitempty"`
+	Complete   bool                  `json:"complete,omitempty"`
+	// Tx is the transaction that was sent, with -send
+	Tx string `json:"tx,omitempty"`
+}
+
+// MultisigAddressOutput is a multisig address of the wallet
+type MultisigAddressOutput struct {
+	Address          string           `json:"address"`
+	Balance          soterutil.Amount `json:"balance"`
+	SpendableBalance soterutil.Amount `json:"spendableBalance"`
+}
+
+// MultisigSpendOutput is the amount a multisig transaction spends from an address
+type MultisigSpendOutput struct {
+	Address string           `json:"address"`
+	Amount  soterutil.Amount `json:"amount"`
+}
+
+// multisigCommand is the multisig tool, which creates multisig addresses, and spends from them with the signatures of
+// their cosigners
+var multisigCommand = &command{
+	name:    "multisig",
+	summary: "Create multisig addresses, and create, sign and send transactions spending from them",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var create, list, yes bool
+		var keys, pubKeyAddr, srcAddr, destAddr, spendFile, signFile, sendFile string
+		var nRequired int
+		var amt, fee string
+
+		ctx.walletFlag(fs, "Wallet file name")
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		fs.StringVar(&pubKeyAddr, "pubkey", "", "Show the hex-encoded public key of this wallet address, to share with cosigners")
+		fs.BoolVar(&create, "create", false, "Create a multisig address from -keys, and register it in the wallet")
+		fs.StringVar(&keys, "keys", "", "Comma-separated wallet addresses or hex-encoded public keys of the cosigners (with -create)")
+		fs.IntVar(&nRequired, "nrequired", 0, "Number of signatures required to spend from the multisig address (with -create)")
+		fs.BoolVar(&list, "list", false, "List multisig addresses in the wallet and their balances")
+		fs.StringVar(&spendFile, "spend", "", "Create an unsigned transaction spending from -source, and write it to this file")
+		fs.StringVar(&srcAddr, "source", "", "Multisig address to spend from (with -spend)")
+		fs.StringVar(&destAddr, "dest", "", "Destination address of funds (with -spend)")
+		fs.StringVar(&amt, "amt", "", "Amount of coin to transfer, in SOTER unless a unit is given such as 1500mSOTER (with -spend)")
+		fs.StringVar(&fee, "fee", "", "Fee for transfer, in SOTER unless a unit is given such as 1500mSOTER (with -spend)")
+		fs.StringVar(&signFile, "sign", "", "Add signatures from the wallet to the transaction in this file")
+		fs.BoolVar(&yes, "yes", false, "Don't ask for confirmation before signing the transaction (with -sign)")
+		fs.StringVar(&sendFile, "send", "", "Send the fully-signed transaction in this file to the network")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("multisig takes no arguments")
+			}
+			modes := 0
+			for _, m := range []bool{len(pubKeyAddr) > 0, create, list, len(spendFile) > 0, len(signFile) > 0, len(sendFile) > 0} {
+				if m {
+					modes++
+				}
+			}
+			if modes != 1 {
+				return usageErrorf("You must specify one of -pubkey, -create, -list, -spend, -sign, -send")
+			}
+			if create && len(keys) == 0 {
+				return usageErrorf("You must specify the cosigner keys (-keys)")
+			}
+			// The transaction is shown before asking to sign it, which json output can't do
+			if len(signFile) > 0 && ctx.JSON() && !yes {
+				return usageErrorf("-yes is required to sign a transaction with -output json")
+			}
+			sendAmount, err := parseAmount(amt, "-amt")
+			if err != nil {
+				return err
+			}
+			feeAmount, err := parseAmount(fee, "-fee")
+			if err != nil {
+				return err
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+			params := w.ChainParams()
+
+			switch {
+			case len(pubKeyAddr) > 0:
+				addr, err := soterutil.DecodeAddress(pubKeyAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "Failed to decode address %s: %s", pubKeyAddr, err)
+				}
+
+				pubKey, err := w.PubKeyForAddress(addr)
+				if err != nil {
+					return errorf(CodeWallet, "Failed to get public key of address %s: %s", pubKeyAddr, err)
+				}
+
+				out := MultisigOutput{Address: pubKeyAddr, PubKey: hex.EncodeToString(pubKey.SerializeCompressed())}
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, &out)
+				}
+				ctx.Printf("Public key of %s: %s\n", pubKeyAddr, out.PubKey)
+				return nil
+
+			case create:
+				privPass, err := passphrase(ctx, priv)
+				if err != nil {
+					return err
+				}
+				cosigners := strings.Split(keys, ",")
+				addr, script, err := wallet.NewMultisigAddress(w, privPass, cosigners, nRequired)
+				if err != nil {
+					return fmt.Errorf("Failed to create multisig address: %w", err)
+				}
+
+				out := MultisigOutput{
+					Address:      addr.EncodeAddress(),
+					NRequired:    nRequired,
+					NKeys:        len(cosigners),
+					RedeemScript: hex.EncodeToString(script),
+				}
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, &out)
+				}
+				ctx.Printf("Created %d-of-%d multisig address: %s\n", nRequired, len(cosigners), out.Address)
+				ctx.Printf("Redeem script: %s\n", out.RedeemScript)
+				return nil
+
+			case list:
+				addrs, err := wallet.MultisigAddresses(w)
+				if err != nil {
+					return fmt.Errorf("Failed to list multisig addresses: %w", err)
+				}
+
+				rpcNodes, client, err := ctx.Config.ConnectRPC()
+				if err != nil {
+					return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+				}
+				defer rpcNodes.Shutdown()
+
+				var out MultisigOutput
+				ctx.Printf("Multisig addresses:\n")
+				for _, addr := range addrs {
+					balance, spendable, err := wallet.GetBalance(client, []soterutil.Address{addr}, params)
+					if err != nil {
+						return errorf(CodeRPC, "Failed to get balance of address %s: %w", addr, err)
+					}
+
+					out.Addresses = append(out.Addresses, MultisigAddressOutput{
+						Address:          addr.EncodeAddress(),
+						Balance:          balance,
+						SpendableBalance: spendable,
+					})
+					ctx.Printf("\taddress: %s\tbalance: %s\tspendable: %s\n", addr, ctx.Amount(balance), ctx.Amount(spendable))
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, &out)
+				}
+				return nil
+
+			case len(spendFile) > 0:
+				source, err := soterutil.DecodeAddress(srcAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "Failed to decode source address %s: %s", srcAddr, err)
+				}
+				dest, err := soterutil.DecodeAddress(destAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "Failed to decode destination address %s: %s", destAddr, err)
+				}
+
+				rpcNodes, client, err := ctx.Config.ConnectRPC()
+				if err != nil {
+					return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+				}
+				defer rpcNodes.Shutdown()
+
+				matches, err := wallet.SpendableTxOuts(client, []soterutil.Address{source}, params)
+				if err != nil {
+					return errorf(CodeRPC, "Failed to find matching transactions in dag: %w", err)
+				}
+				matches, err = wallet.ExcludePendingInputs(w, matches)
+				if err != nil {
+					return fmt.Errorf("Failed to read pending transactions from wallet: %w", err)
+				}
+
+				spendable := soterutil.Amount(0)
+				for _, m := range matches {
+					spendable += m.Amount
+				}
+				if sendAmount+feeAmount > spendable {
+					return errorf(CodeFunds, "Not enough coin found to satisfy amount requested for transaction; %s requested + %s fee, %s spendable",
+						ctx.Amount(sendAmount), ctx.Amount(feeAmount), ctx.Amount(spendable))
+				}
+
+				privPass, err := passphrase(ctx, priv)
+				if err != nil {
+					return err
+				}
+				p, err := wallet.NewMultisigSpend(client, w, privPass, matches, dest, sendAmount, feeAmount)
+				if err != nil {
+					return fmt.Errorf("Failed to create transaction: %w", err)
+				}
+
+				err = p.WriteFile(spendFile)
+				if err != nil {
+					return fmt.Errorf("Failed to write transaction to %s: %s", spendFile, err)
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, &MultisigOutput{
+						File:   spendFile,
+						Source: source.EncodeAddress(),
+						Dest:   dest.EncodeAddress(),
+						Amount: sendAmount,
+						Fee:    feeAmount,
+					})
+				}
+				ctx.Printf("Wrote unsigned transaction for %s to %s to %s\n", ctx.Amount(sendAmount), destAddr, spendFile)
+				ctx.Printf("Pass the file to each cosigner, to add their signatures with -sign\n")
+				return nil
+
+			case len(signFile) > 0:
+				p, err := wallet.ReadPartialTx(signFile)
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+
+				// Show what the transaction does before signing it, with the amounts of the outputs it spends from
+				// the dag
+				rpcNodes, client, err := ctx.Config.ConnectRPC()
+				if err != nil {
+					return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+				}
+				defer rpcNodes.Shutdown()
+				spent, err := wallet.SpentOutputs(client, p.Tx)
+				if err != nil {
+					return errorf(CodeRPC, "Failed to find the outputs spent by the transaction: %w", err)
+				}
+				out, err := showPartialTx(ctx, p, spent, params)
+				if err != nil {
+					return errorf(CodeInvalid, "Not signing transaction: %s", err)
+				}
+				if !yes && !confirm(ctx, "Signing lets the transaction send these amounts.") {
+					return fmt.Errorf("Not signing transaction")
+				}
+
+				privPass, err := passphrase(ctx, priv)
+				if err != nil {
+					return err
+				}
+				out.Complete, err = wallet.SignPartialTx(w, privPass, p)
+				if err != nil {
+					return fmt.Errorf("Failed to sign transaction: %w", err)
+				}
+
+				err = p.WriteFile(signFile)
+				if err != nil {
+					return fmt.Errorf("Failed to write transaction to %s: %s", signFile, err)
+				}
+
+				out.Signatures, out.Required, err = p.Signatures()
+				if err != nil {
+					return fmt.Errorf("Failed to count signatures: %s", err)
+				}
+
+				out.File = signFile
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, out)
+				}
+				ctx.Printf("Transaction in %s has %d of %d signatures\n", signFile, out.Signatures, out.Required)
+				if out.Complete {
+					ctx.Printf("Transaction is ready to be sent with -send\n")
+				}
+				return nil
+
+			default:
+				p, err := wallet.ReadPartialTx(sendFile)
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+
+				rpcNodes, client, err := ctx.Config.ConnectRPC()
+				if err != nil {
+					return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+				}
+				defer rpcNodes.Shutdown()
+
+				txHash, err := wallet.SendPartialTx(client, w, p)
+				if err != nil {
+					return err
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaMultisig, &MultisigOutput{File: sendFile, Tx: txHash.String()})
+				}
+				ctx.Printf("Sent transaction with hash %s\n", txHash)
+				return nil
+			}
+		}
+	},
+}
+
+// showPartialTx shows the outputs of the transaction, what it spends from each address and its fee, and returns them
+// as json output. The spent outputs are the ones found in the dag, rather than the ones in the transaction's file,
+// which a cosigner could have changed.
+func showPartialTx(ctx *Context, p *wallet.PartialTx, spent map[wire.OutPoint]*wire.TxOut, params *chaincfg.Params) (*MultisigOutput, error) {
+	var in, sent soterutil.Amount
+	spentFrom := make(map[string]soterutil.Amount)
+	var order []string
+	for _, txIn := range p.Tx.TxIn {
+		prevOut, ok := spent[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, fmt.Errorf("input %s doesn't spend an output", txIn.PreviousOutPoint)
+		}
+		if !bytes.Equal(prevOut.PkScript, p.PrevScripts[txIn.PreviousOutPoint]) {
+			return nil, fmt.Errorf("the script of input %s in the file doesn't match the output it spends", txIn.PreviousOutPoint)
+		}
+
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(prevOut.PkScript, params)
+		if err != nil || len(addrs) != 1 {
+			return nil, fmt.Errorf("input %s doesn't spend from an address", txIn.PreviousOutPoint)
+		}
+		a := addrs[0].EncodeAddress()
+		if _, ok := spentFrom[a]; !ok {
+			order = append(order, a)
+		}
+		spentFrom[a] += soterutil.Amount(prevOut.Value)
+		in += soterutil.Amount(prevOut.Value)
+	}
+
+	out := MultisigOutput{Outputs: txOutput(p.Tx, params).Outputs}
+	for _, o := range out.Outputs {
+		to := strings.Join(o.Addresses, ", ")
+		if len(o.Addresses) == 0 {
+			to = "(no address)"
+		}
+
+		ctx.Printf("Output %d: %s to %s\n", o.Index, ctx.Amount(o.Value), to)
+		sent += o.Value
+	}
+	for _, a := range order {
+		out.Spends = append(out.Spends, MultisigSpendOutput{Address: a, Amount: spentFrom[a]})
+		ctx.Printf("Spends %s from %s\n", ctx.Amount(spentFrom[a]), a)
+	}
+	if sent > in {
+		return nil, fmt.Errorf("the outputs send %s, more than the %s spent", ctx.Amount(sent), ctx.Amount(in))
+	}
+	out.Fee = in - sent
+	ctx.Printf("Fee: %s\n", ctx.Amount(out.Fee))
+
+	return &out, nil
+}
@@ -0,0 +1,436 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// How often the soterd node is polled for the status of a transaction, when waiting for confirmations
+const pollInterval = time.Second * 4
+
+// SendOutput is the json output of send
+type SendOutput struct {
+	Header
+	Tx     string           `json:"tx"`
+	Source string           `json:"source"`
+	Dest   string           `json:"dest"`
+	Amount soterutil.Amount `json:"amount"`
+	Fee    soterutil.Amount `json:"fee"`
+	// Inputs are the outputs spent by the transaction, and RawTx is the hex-encoded signed transaction
+	Inputs []SpentOutput `json:"inputs"`
+	RawTx  string        `json:"rawTx"`
+	// Stored is true if the transaction is locked, and was stored in the wallet without sending it
+	Stored        bool  `json:"stored,omitempty"`
+	Confirmations int32 `json:"confirmations,omitempty"`
+}
+
+// SpentOutput is an output spent by a sent transaction
+type SpentOutput struct {
+	Tx      string           `json:"tx"`
+	VOut    int              `json:"vout"`
+	Amount  soterutil.Amount `json:"amount"`
+	Address string           `json:"address"`
+}
+
+var sendCommand = &command{
+	name:    "send",
+	summary: "Send coin from a wallet address, or sweep the coin of a private key into the wallet",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var srcAddr, destAddr string
+		var dataHex, dataFile, lockTimeStr, sweepKey string
+		var lockHeight, relativeLock int
+		var amt, fee string
+		var waitConfs int
+		var waitTimeout time.Duration
+
+		ctx.walletFlag(fs, "Source wallet file name (default wallet.db in the soterwallet app data directory of the network)")
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		fs.StringVar(&srcAddr, "source", "", "Source address of funds")
+		fs.StringVar(&destAddr, "dest", "", "Destination address of funds")
+		fs.StringVar(&amt, "amt", "", "Amount of coin to transfer, in SOTER unless a unit is given (such as 1500mSOTER)")
+		fs.StringVar(&fee, "fee", "", "Fee for transfer, in SOTER unless a unit is given (such as 1500mSOTER)")
+		fs.StringVar(&dataHex, "data", "", "Hex-encoded data to embed in a null-data (OP_RETURN) output of the transaction")
+		fs.StringVar(&dataFile, "datafile", "", "Embed the SHA-256 digest of this file in a null-data (OP_RETURN) output of the transaction")
+		fs.IntVar(&lockHeight, "lockheight", 0, "Dag height the transaction is locked until (nLockTime)")
+		fs.StringVar(&lockTimeStr, "locktime", "", "Time the transaction is locked until, in RFC3339 format (nLockTime)")
+		fs.IntVar(&relativeLock, "relativelock", 0, "Number of confirmations the spent outputs need before the transaction is valid (sequence lock)")
+		fs.StringVar(&sweepKey, "sweepkey", "", "Send all coin of this WIF-encoded private key's address to a new wallet address, without storing the key")
+		fs.IntVar(&waitConfs, "wait", 0, "Wait until the transaction has this many confirmations")
+		fs.DurationVar(&waitTimeout, "waittimeout", 10*time.Minute, "How long to wait for confirmations (with -wait)")
+
+		return func(args []string) error {
+			params := ctx.Config.Params
+
+			// Validate cli parameters
+			if len(args) > 0 {
+				return usageErrorf("send takes no arguments")
+			}
+			if len(sweepKey) > 0 && (len(srcAddr) > 0 || len(destAddr) > 0 || len(amt) > 0) {
+				return usageErrorf("-sweepkey sends all coin of the key to a new wallet address, so -source, -dest and -amt can't be used with it")
+			}
+			if len(sweepKey) > 0 && (len(dataHex) > 0 || len(dataFile) > 0 || lockHeight > 0 || len(lockTimeStr) > 0 || relativeLock > 0) {
+				return usageErrorf("-data, -datafile, -lockheight, -locktime and -relativelock can't be used with -sweepkey")
+			}
+			if len(srcAddr) == 0 && len(sweepKey) == 0 {
+				return usageErrorf("No source address specified (-source)")
+			}
+			if len(destAddr) == 0 && len(sweepKey) == 0 {
+				return usageErrorf("No destination address specified (-dest)")
+			}
+
+			if waitConfs < 0 {
+				return usageErrorf("Number of confirmations to wait for can't be negative (-wait)")
+			}
+			if len(dataHex) > 0 && len(dataFile) > 0 {
+				return usageErrorf("You can only specify one of -data, -datafile")
+			}
+			if lockHeight > 0 && len(lockTimeStr) > 0 {
+				return usageErrorf("You can only specify one of -lockheight, -locktime")
+			}
+			if relativeLock < 0 {
+				return usageErrorf("Relative lock can't be negative (-relativelock)")
+			}
+
+			// Convert cli params
+			sendAmount, err := parseAmount(amt, "-amt")
+			if err != nil {
+				return err
+			}
+			feeAmount, err := parseAmount(fee, "-fee")
+			if err != nil {
+				return err
+			}
+			if sendAmount == 0 && len(sweepKey) == 0 {
+				ctx.Warnf("Amount to transfer is %s", ctx.Amount(sendAmount))
+			}
+			if feeAmount == 0 {
+				ctx.Warnf("Fee for transfer is %s", ctx.Amount(feeAmount))
+			}
+
+			var opts wallet.SendOptions
+			if len(dataHex) > 0 {
+				opts.Data, err = hex.DecodeString(dataHex)
+				if err != nil {
+					return errorf(CodeInvalid, "failed to decode data %s: %s", dataHex, err)
+				}
+			}
+			if len(dataFile) > 0 {
+				opts.Data, err = wallet.FileDigest(dataFile)
+				if err != nil {
+					return errorf(CodeInvalid, "failed to get digest of file %s: %s", dataFile, err)
+				}
+			}
+			if len(opts.Data) > 0 {
+				// Check the data before doing any work with the wallet or node
+				_, err = wallet.NullDataOutput(opts.Data)
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+			}
+			if lockHeight > 0 {
+				opts.LockTime, err = wallet.LockTimeFromHeight(int32(lockHeight))
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+			}
+			if len(lockTimeStr) > 0 {
+				t, err := time.Parse(time.RFC3339, lockTimeStr)
+				if err != nil {
+					return errorf(CodeInvalid, "failed to parse lock time %s: %s", lockTimeStr, err)
+				}
+				opts.LockTime, err = wallet.LockTimeFromTime(t)
+				if err != nil {
+					return withCode(CodeInvalid, err)
+				}
+			}
+			opts.RelativeLock = uint32(relativeLock)
+
+			var source, dest soterutil.Address
+			if len(sweepKey) == 0 {
+				source, err = soterutil.DecodeAddress(srcAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "failed to decode address from %s: %s", srcAddr, err)
+				}
+				dest, err = soterutil.DecodeAddress(destAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "failed to decode address from %s: %s", destAddr, err)
+				}
+			}
+
+			// The private password isn't needed for sweeping, because the swept key signs the transaction
+			var privPass string
+			if len(sweepKey) == 0 {
+				privPass, err = passphrase(ctx, priv)
+				if err != nil {
+					return err
+				}
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			// Connect to soterd node
+			rpcNodes, client, err := ctx.Config.ConnectRPC()
+			if err != nil {
+				return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+			}
+			defer rpcNodes.Shutdown()
+
+			if len(sweepKey) > 0 {
+				return sweep(ctx, client, w, sweepKey, feeAmount, waitConfs, waitTimeout)
+			}
+
+			addresses := []soterutil.Address{source}
+
+			// Look for transactions with spendable outputs
+			matches, err := wallet.SpendableTxOuts(client, addresses, params)
+			if err != nil {
+				return errorf(CodeRPC, "Failed to find matching transactions in dag: %w", err)
+			}
+
+			// Leave out outputs that are already being spent by our pending transactions
+			matches, err = wallet.ExcludePendingInputs(w, matches)
+			if err != nil {
+				return fmt.Errorf("Failed to read pending transactions from wallet: %w", err)
+			}
+
+			// With a relative lock, only outputs with enough confirmations can be spent right away
+			if relativeLock > 0 {
+				tips, err := client.GetDAGTips()
+				if err != nil {
+					return errorf(CodeRPC, "Failed to get dag tips: %s", err)
+				}
+				matches = wallet.ConfirmedTxOuts(matches, tips.MaxHeight, int32(relativeLock))
+			}
+
+			if len(matches) == 0 {
+				return errorf(CodeFunds, "No matching transactions for source address found in dag")
+			}
+
+			ctx.Printf("Matching transactions:\n")
+			txTotalAmt := soterutil.Amount(0)
+			for _, m := range matches {
+				txTotalAmt += m.Amount
+				ctx.Printf("block %s\theight %d\ttx %s\toutputNum %d\tvalue %s\tmatching wallet addr %s\n",
+					m.Info.Block.BlockHash(), m.Info.BlockHeight, m.Info.Tx.TxHash(), m.VIndex, ctx.Amount(m.Amount), m.Address)
+			}
+
+			// Confirm that there's enough spendable coin
+			if sendAmount+feeAmount > txTotalAmt {
+				return errorf(CodeFunds, "Not enough coin found to satisfy amount requested for transaction; %s requested + %s fee, %s spendable",
+					ctx.Amount(sendAmount), ctx.Amount(feeAmount), ctx.Amount(txTotalAmt))
+			}
+
+			ctx.Printf("\n")
+			ctx.Printf("Creating a transaction for %s to %s\n", ctx.Amount(sendAmount), destAddr)
+			if len(opts.Data) > 0 {
+				ctx.Printf("Embedding data %s\n", hex.EncodeToString(opts.Data))
+			}
+			txHash, err := wallet.SendWithOptions(client, w, privPass, matches, dest, sendAmount, feeAmount, &opts)
+			if err != nil {
+				return err
+			}
+
+			out, sent, err := sendOutput(w, txHash, matches, sendAmount, feeAmount)
+			if err != nil {
+				return err
+			}
+			out.Source = source.EncodeAddress()
+			out.Dest = dest.EncodeAddress()
+
+			if opts.LockTime > 0 {
+				if sent.LastBroadcast.IsZero() {
+					// The node won't accept the transaction yet, so it was only stored in the wallet
+					ctx.Printf("Transaction %s is locked until %s, and was stored in the wallet without sending it\n",
+						txHash, wallet.FormatLockTime(opts.LockTime))
+					ctx.Printf("Send it with senttx -rebroadcast once the lock time passes\n")
+					out.Stored = true
+					if ctx.JSON() {
+						return ctx.PrintJSON(SchemaSend, out)
+					}
+					return nil
+				}
+			}
+
+			ctx.Printf("Sent transaction with hash %s\n", txHash)
+
+			if waitConfs > 0 {
+				err = waitForTx(ctx, client, txHash, waitConfs, waitTimeout)
+				if err != nil {
+					return err
+				}
+				out.Confirmations = int32(waitConfs)
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaSend, out)
+			}
+			return nil
+		}
+	},
+}
+
+// parseAmount returns the amount given to the flag, which is in SOTER unless it has a unit. No amount is 0.
+func parseAmount(s, name string) (soterutil.Amount, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	a, err := amount.Parse(s, soterutil.AmountSOTER)
+	if err != nil {
+		return 0, errorf(CodeInvalid, "%s: %w", name, err)
+	}
+	return a, nil
+}
+
+// sendOutput returns the json output of the sent transaction, with the matches it spent, and the transaction as it's
+// recorded in the wallet
+func sendOutput(w *soterwallet.Wallet, txHash *chainhash.Hash, matches []wallet.TxMatch, amount, fee soterutil.Amount) (*SendOutput, *wallet.SentTx, error) {
+	sent, err := wallet.GetSentTx(w, txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	err = sent.Tx.Serialize(&buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to serialize transaction %s: %s", txHash, err)
+	}
+
+	out := SendOutput{
+		Tx:     txHash.String(),
+		Amount: amount,
+		Fee:    fee,
+		Inputs: []SpentOutput{},
+		RawTx:  hex.EncodeToString(buf.Bytes()),
+	}
+	for _, in := range sent.Inputs {
+		spent := SpentOutput{Tx: in.Hash.String(), VOut: int(in.Index)}
+		for _, m := range matches {
+			if m.Info.Tx.TxHash() == in.Hash && m.VIndex == int(in.Index) {
+				spent.Amount = m.Amount
+				spent.Address = m.Address
+				break
+			}
+		}
+		out.Inputs = append(out.Inputs, spent)
+	}
+
+	return &out, sent, nil
+}
+
+// sweep sends all spendable coin of the private key's address to a new address in the wallet. If waitConfs is more
+// than 0, it waits for the sweep transaction to have that many confirmations, like a send.
+func sweep(ctx *Context, client *rpcclient.Client, w *soterwallet.Wallet, wifKey string, fee soterutil.Amount,
+	waitConfs int, waitTimeout time.Duration) error {
+	params := w.ChainParams()
+	wif, source, err := wallet.SweepKey(wifKey, params)
+	if err != nil {
+		return withCode(CodeInvalid, err)
+	}
+
+	ctx.Printf("Sweeping coin from address %s\n", source)
+
+	matches, err := wallet.SpendableTxOuts(client, []soterutil.Address{source}, params)
+	if err != nil {
+		return errorf(CodeRPC, "Failed to find matching transactions in dag: %w", err)
+	}
+	matches, err = wallet.ExcludePendingInputs(w, matches)
+	if err != nil {
+		return fmt.Errorf("Failed to read pending transactions from wallet: %w", err)
+	}
+	if len(matches) == 0 {
+		return errorf(CodeFunds, "No spendable coin found for address %s", source)
+	}
+
+	dest, err := wallet.NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		return fmt.Errorf("Failed to create wallet address: %s", err)
+	}
+
+	txHash, amount, err := wallet.Sweep(client, w, wif, matches, dest, fee)
+	if err != nil {
+		return err
+	}
+
+	out, _, err := sendOutput(w, txHash, matches, amount, fee)
+	if err != nil {
+		return err
+	}
+	out.Source = source.EncodeAddress()
+	out.Dest = dest.EncodeAddress()
+	ctx.Printf("Sent %s from %s to wallet address %s in transaction %s\n", ctx.Amount(amount), source, dest, txHash)
+
+	if waitConfs > 0 {
+		err = waitForTx(ctx, client, txHash, waitConfs, waitTimeout)
+		if err != nil {
+			return err
+		}
+		out.Confirmations = int32(waitConfs)
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaSend, out)
+	}
+	return nil
+}
+
+// waitForTx waits for the transaction to have the number of confirmations, until the timeout passes or we're
+// interrupted
+func waitForTx(ctx *Context, client *rpcclient.Client, txHash *chainhash.Hash, confs int, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	ctx.Printf("Waiting for %d confirmations of transaction %s\n", confs, txHash)
+	showStatus := func(status wallet.TxStatus) {
+		if status.State == wallet.TxInBlock {
+			ctx.Printf("Transaction is in block %s at height %d, with %d confirmations\n",
+				status.Block.BlockHash(), status.BlockHeight, status.Confirmations)
+		} else {
+			ctx.Printf("Transaction is %s\n", status.State)
+		}
+	}
+
+	_, err := wallet.WaitForTx(waitCtx, client, txHash, int32(confs), pollInterval, showStatus)
+	if err != nil {
+		if waitCtx.Err() != nil {
+			return withCode(CodeTimeout, err)
+		}
+		return err
+	}
+
+	ctx.Printf("Transaction %s has %d confirmations\n", txHash, confs)
+	return nil
+}
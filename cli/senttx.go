@@ -0,0 +1,271 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/credentials"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+	"time"
+)
+
+// SentTxOutput is the json output of senttx
+type SentTxOutput struct {
+	Header
+	// Pending are the wallet's pending transactions, when none of -rebroadcast, -abandon and -bumpfee is given
+	Pending []PendingTxOutput `json:"pending,omitempty"`
+	// Rebroadcast are the transactions that were sent again, and Skipped are the ones that are still locked
+	Rebroadcast []string            `json:"rebroadcast,omitempty"`
+	Skipped     []string            `json:"skipped,omitempty"`
+	Abandoned   []AbandonedTxOutput `json:"abandoned,omitempty"`
+	// Child is the transaction that bumps the fee of Parent, paying Fee
+	Child  string           `json:"child,omitempty"`
+	Parent string           `json:"parent,omitempty"`
+	Fee    soterutil.Amount `json:"fee,omitempty"`
+}
+
+// PendingTxOutput is a pending transaction of the wallet, with its status on the soterd node
+type PendingTxOutput struct {
+	Tx     string `json:"tx"`
+	Inputs int    `json:"inputs"`
+	State  string `json:"state"`
+	// LastBroadcast is in RFC3339 format, and is left out if the transaction was never sent
+	LastBroadcast string `json:"lastBroadcast,omitempty"`
+	// Parent is the transaction this one bumps the fee of
+	Parent   string `json:"parent,omitempty"`
+	LockTime uint32 `json:"lockTime,omitempty"`
+}
+
+// AbandonedTxOutput is a transaction that was abandoned, freeing its inputs
+type AbandonedTxOutput struct {
+	Tx     string `json:"tx"`
+	Inputs int    `json:"inputs"`
+}
+
+// senttxCommand is the senttx tool, which manages the transactions the wallet sent that aren't in a block yet
+var senttxCommand = &command{
+	name:    "senttx",
+	summary: "List, rebroadcast, abandon or bump the fee of the wallet's pending transactions",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var rebroadcastHash, abandonHash, bumpHash string
+		var fee string
+
+		ctx.walletFlag(fs, "Wallet file name")
+		priv := credentials.NewPassphrase(fs, "priv",
+			"Password to use, for unlocking address manager (for private keys and info, needed by -bumpfee)", credentials.PrivPassEnv)
+		pub := pubPassphrase(fs)
+		fs.StringVar(&rebroadcastHash, "rebroadcast", "", "Hash of pending transaction to send to the network again (or 'all')")
+		fs.StringVar(&abandonHash, "abandon", "", "Hash of pending transaction to abandon, freeing its inputs")
+		fs.StringVar(&bumpHash, "bumpfee", "", "Hash of pending transaction to bump the fee of, by spending its change in a child transaction")
+		fs.StringVar(&fee, "fee", "", "Fee for the child transaction, in SOTER unless a unit is given such as 1500mSOTER (with -bumpfee)")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("senttx takes no arguments")
+			}
+			actions := 0
+			for _, a := range []string{rebroadcastHash, abandonHash, bumpHash} {
+				if len(a) > 0 {
+					actions++
+				}
+			}
+			if actions > 1 {
+				return usageErrorf("You can only specify one of -rebroadcast, -abandon, -bumpfee")
+			}
+			feeAmount, err := parseAmount(fee, "-fee")
+			if err != nil {
+				return err
+			}
+			if len(bumpHash) > 0 && feeAmount <= 0 {
+				return usageErrorf("You must specify a fee for the child transaction (-fee)")
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			if len(abandonHash) > 0 {
+				// Abandoning a transaction only changes our records, so we don't need to connect to a soterd node
+				return abandon(ctx, w, abandonHash)
+			}
+
+			// Connect to soterd node
+			rpcNodes, client, err := ctx.Config.ConnectRPC()
+			if err != nil {
+				return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+			}
+			defer rpcNodes.Shutdown()
+
+			switch {
+			case len(rebroadcastHash) > 0:
+				return rebroadcast(ctx, client, w, rebroadcastHash)
+			case len(bumpHash) > 0:
+				h, err := parseTxHash(bumpHash)
+				if err != nil {
+					return err
+				}
+				privPass, err := passphrase(ctx, priv)
+				if err != nil {
+					return err
+				}
+
+				childHash, err := wallet.BumpFee(client, w, privPass, h, feeAmount)
+				if err != nil {
+					return fmt.Errorf("Failed to bump fee of transaction %s: %w", bumpHash, err)
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaSentTx, &SentTxOutput{Child: childHash.String(), Parent: h.String(), Fee: feeAmount})
+				}
+				ctx.Printf("Sent child transaction %s paying %s for %s\n", childHash, ctx.Amount(feeAmount), bumpHash)
+				return nil
+			default:
+				return listPending(ctx, client, w)
+			}
+		}
+	},
+}
+
+// parseTxHash returns the transaction hash of the hex string
+func parseTxHash(hash string) (*chainhash.Hash, error) {
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return nil, errorf(CodeInvalid, "Failed to parse transaction hash %s: %s", hash, err)
+	}
+
+	return h, nil
+}
+
+// listPending shows the pending transactions in the wallet, along with their status on the soterd node
+func listPending(ctx *Context, client *rpcclient.Client, w *soterwallet.Wallet) error {
+	pending, statuses, err := wallet.UpdateSentTxs(client, w)
+	if err != nil {
+		return fmt.Errorf("Failed to update status of sent transactions: %w", err)
+	}
+
+	out := SentTxOutput{Pending: []PendingTxOutput{}}
+	ctx.Printf("Pending transactions:\n")
+	for i, s := range pending {
+		status := statuses[i]
+		if status.State == wallet.TxInBlock {
+			// The transaction was confirmed by UpdateSentTxs, so it's not pending anymore
+			continue
+		}
+
+		p := PendingTxOutput{
+			Tx:       s.Hash.String(),
+			Inputs:   len(s.Inputs),
+			State:    status.State.String(),
+			LockTime: s.Tx.LockTime,
+		}
+		parent := ""
+		if s.Parent != nil {
+			p.Parent = s.Parent.String()
+			parent = fmt.Sprintf("\tbumps %s", s.Parent)
+		}
+		if s.Tx.LockTime > 0 {
+			parent += fmt.Sprintf("\tlocked until %s", wallet.FormatLockTime(s.Tx.LockTime))
+		}
+
+		lastBroadcast := "never"
+		if !s.LastBroadcast.IsZero() {
+			p.LastBroadcast = s.LastBroadcast.Format(time.RFC3339)
+			lastBroadcast = s.LastBroadcast.Format("2006-01-02 15:04:05")
+		}
+
+		out.Pending = append(out.Pending, p)
+		ctx.Printf("tx %s\tinputs %d\tnode status %s\tlast broadcast %s%s\n",
+			s.Hash, len(s.Inputs), status.State, lastBroadcast, parent)
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaSentTx, &out)
+	}
+	return nil
+}
+
+// rebroadcast sends the pending transaction with the given hash to the network again. If the hash is "all", every
+// pending transaction whose lock time has passed is sent again.
+func rebroadcast(ctx *Context, client *rpcclient.Client, w *soterwallet.Wallet, hash string) error {
+	var out SentTxOutput
+	var hashes []chainhash.Hash
+	if hash == "all" {
+		pending, err := wallet.PendingTxs(w)
+		if err != nil {
+			return fmt.Errorf("Failed to read pending transactions: %w", err)
+		}
+
+		tips, err := client.GetDAGTips()
+		if err != nil {
+			return errorf(CodeRPC, "Failed to get dag tips: %s", err)
+		}
+		now, err := wallet.MedianTime(client)
+		if err != nil {
+			return errorf(CodeRPC, "Failed to get median time of dag: %s", err)
+		}
+
+		for _, s := range pending {
+			if !wallet.TxLockTimeMet(s.Tx, tips.MaxHeight+1, now) {
+				ctx.Printf("Skipping transaction %s, locked until %s\n", s.Hash, wallet.FormatLockTime(s.Tx.LockTime))
+				out.Skipped = append(out.Skipped, s.Hash.String())
+				continue
+			}
+
+			hashes = append(hashes, s.Hash)
+		}
+	} else {
+		h, err := parseTxHash(hash)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, *h)
+	}
+
+	for i := range hashes {
+		err := wallet.Rebroadcast(client, w, &hashes[i])
+		if err != nil {
+			return fmt.Errorf("Failed to rebroadcast transaction %s: %w", hashes[i], err)
+		}
+
+		out.Rebroadcast = append(out.Rebroadcast, hashes[i].String())
+		ctx.Printf("Rebroadcast transaction %s\n", hashes[i])
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaSentTx, &out)
+	}
+	return nil
+}
+
+// abandon abandons the pending transaction with the given hash, and the transactions that spend from it
+func abandon(ctx *Context, w *soterwallet.Wallet, hash string) error {
+	h, err := parseTxHash(hash)
+	if err != nil {
+		return err
+	}
+
+	abandoned, err := wallet.Abandon(w, h)
+	if err != nil {
+		return fmt.Errorf("Failed to abandon transaction %s: %w", hash, err)
+	}
+
+	var out SentTxOutput
+	for _, s := range abandoned {
+		out.Abandoned = append(out.Abandoned, AbandonedTxOutput{Tx: s.Hash.String(), Inputs: len(s.Inputs)})
+		ctx.Printf("Abandoned transaction %s, freeing %d inputs\n", s.Hash, len(s.Inputs))
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaSentTx, &out)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/walletweb"
+	"log"
+	"time"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: "Serve the web ui for retrieving wallet address balance and sending coin",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var opts walletweb.Options
+
+		fs.StringVar(&opts.Addr, "l", ":5077", "Which [ip]:port to listen on")
+		ctx.walletFlag(fs, "Wallet file name (for sending coin)")
+		pub := pubPassphrase(fs)
+		fs.DurationVar(&opts.ReserveTimeout, "reservetimeout", 10*time.Minute, "How long outputs used by a sent transaction are held, before they can be used by another")
+		fs.DurationVar(&opts.LockTimeout, "locktimeout", time.Minute, "Longest time the wallet can stay unlocked, before it's locked again")
+		fs.DurationVar(&opts.CheckInterval, "checkinterval", 30*time.Second, "How often the health of the soterd nodes is checked, to fail over from nodes that are down")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("serve takes no arguments")
+			}
+
+			// The private password isn't read at startup; it's entered on the page of each action that needs it,
+			// and isn't kept.
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			// Connect to soterd nodes
+			rpcNodes, err := ctx.Config.Nodes()
+			if err != nil {
+				return withCode(CodeConfig, err)
+			}
+			defer rpcNodes.Shutdown()
+			_, err = rpcNodes.Client()
+			if err != nil {
+				return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+			}
+			log.Printf("Using soterd node %s", rpcNodes.Server())
+
+			opts.Display = ctx.Config.Display
+			return walletweb.Serve(w, rpcNodes, ctx.Config.Params, opts)
+		}
+	},
+}
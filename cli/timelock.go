@@ -0,0 +1,272 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/soterutil"
+	"time"
+)
+
+// TimeLockOutput is the json output of timelock
+type TimeLockOutput struct {
+	Header
+	// Address is the time-locked address that was created, which Owner can spend from once LockTime passes
+	Address      string `json:"address,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	LockTime     uint32 `json:"lockTime,omitempty"`
+	RedeemScript string `json:"redeemScript,omitempty"`
+	// Addresses and Outputs are the time-locked addresses of the wallet and the outputs paying to them, with -list
+	Addresses []TimeLockAddressOutput `json:"addresses,omitempty"`
+	Outputs   []TimeLockedOutput      `json:"outputs,omitempty"`
+	// Tx is the transaction that spent from the unlocked outputs, with -spend
+	Tx     string           `json:"tx,omitempty"`
+	Dest   string           `json:"dest,omitempty"`
+	Amount soterutil.Amount `json:"amount,omitempty"`
+	Fee    soterutil.Amount `json:"fee,omitempty"`
+}
+
+// TimeLockAddressOutput is a time-locked address of the wallet
+type TimeLockAddressOutput struct {
+	Address  string `json:"address"`
+	Owner    string `json:"owner"`
+	LockTime uint32 `json:"lockTime"`
+}
+
+// TimeLockedOutput is an output paying to a time-locked address of the wallet
+type TimeLockedOutput struct {
+	Tx       string           `json:"tx"`
+	VOut     int              `json:"vout"`
+	Value    soterutil.Amount `json:"value"`
+	Address  string           `json:"address"`
+	Unlocked bool             `json:"unlocked"`
+	LockTime uint32           `json:"lockTime"`
+}
+
+// timelockCommand is the timelock tool, which creates and spends from addresses that are locked until a height or time
+var timelockCommand = &command{
+	name:    "timelock",
+	summary: "Create addresses that can't be spent from until a dag height or time, list them, and spend from them",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		var create, list, spend bool
+		var ownerAddr, lockTimeStr, destAddr string
+		var lockHeight int
+		var amt, fee string
+
+		ctx.walletFlag(fs, "Wallet file name")
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		fs.BoolVar(&create, "create", false, "Create a time-locked address, spendable by -address once the lock time passes")
+		fs.StringVar(&ownerAddr, "address", "", "Wallet address whose key can spend from the time-locked address (with -create)")
+		fs.IntVar(&lockHeight, "lockheight", 0, "Dag height the address is locked until (with -create)")
+		fs.StringVar(&lockTimeStr, "locktime", "", "Time the address is locked until, in RFC3339 format (with -create)")
+		fs.BoolVar(&list, "list", false, "List time-locked addresses in the wallet, and the outputs paying to them")
+		fs.BoolVar(&spend, "spend", false, "Send coin from unlocked outputs of time-locked addresses")
+		fs.StringVar(&destAddr, "dest", "", "Destination address of funds (with -spend)")
+		fs.StringVar(&amt, "amt", "", "Amount of coin to transfer, in SOTER unless a unit is given such as 1500mSOTER (with -spend)")
+		fs.StringVar(&fee, "fee", "", "Fee for transfer, in SOTER unless a unit is given such as 1500mSOTER (with -spend)")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("timelock takes no arguments")
+			}
+			modes := 0
+			for _, m := range []bool{create, list, spend} {
+				if m {
+					modes++
+				}
+			}
+			if modes != 1 {
+				return usageErrorf("You must specify one of -create, -list, -spend")
+			}
+
+			var lockTime uint32
+			var sendAmount, feeAmount soterutil.Amount
+			var err error
+			if create {
+				lockTime, err = parseLockTime(lockHeight, lockTimeStr)
+				if err != nil {
+					return err
+				}
+			}
+			if spend {
+				sendAmount, err = parseAmount(amt, "-amt")
+				if err != nil {
+					return err
+				}
+				feeAmount, err = parseAmount(fee, "-fee")
+				if err != nil {
+					return err
+				}
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+			params := w.ChainParams()
+
+			if create {
+				owner, err := soterutil.DecodeAddress(ownerAddr, params)
+				if err != nil {
+					return errorf(CodeInvalid, "Failed to decode address %s: %s", ownerAddr, err)
+				}
+
+				lock, err := wallet.NewTimeLockAddress(w, owner, lockTime)
+				if err != nil {
+					return fmt.Errorf("Failed to create time-locked address: %w", err)
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaTimeLock, &TimeLockOutput{
+						Address:      lock.Address.EncodeAddress(),
+						Owner:        owner.EncodeAddress(),
+						LockTime:     lockTime,
+						RedeemScript: hex.EncodeToString(lock.Script),
+					})
+				}
+				ctx.Printf("Created address %s, spendable by %s once the dag passes %s\n",
+					lock.Address, ownerAddr, wallet.FormatLockTime(lockTime))
+				ctx.Printf("Redeem script: %s\n", hex.EncodeToString(lock.Script))
+				return nil
+			}
+
+			// Connect to soterd node
+			rpcNodes, client, err := ctx.Config.ConnectRPC()
+			if err != nil {
+				return errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+			}
+			defer rpcNodes.Shutdown()
+
+			outs, err := wallet.TimeLockedTxOuts(client, w, params)
+			if err != nil {
+				return errorf(CodeRPC, "Failed to find time-locked outputs in dag: %w", err)
+			}
+
+			if list {
+				locks, err := wallet.TimeLockAddresses(w)
+				if err != nil {
+					return fmt.Errorf("Failed to list time-locked addresses: %w", err)
+				}
+
+				var out TimeLockOutput
+				ctx.Printf("Time-locked addresses:\n")
+				for _, l := range locks {
+					out.Addresses = append(out.Addresses, TimeLockAddressOutput{
+						Address:  l.Address.EncodeAddress(),
+						Owner:    l.Owner.EncodeAddress(),
+						LockTime: l.LockTime,
+					})
+					ctx.Printf("\taddress: %s\towner: %s\tlocked until: %s\n", l.Address, l.Owner, wallet.FormatLockTime(l.LockTime))
+				}
+
+				ctx.Printf("Outputs:\n")
+				for _, o := range outs {
+					out.Outputs = append(out.Outputs, TimeLockedOutput{
+						Tx:       o.Info.Tx.TxHash().String(),
+						VOut:     o.VIndex,
+						Value:    o.Amount,
+						Address:  o.Address,
+						Unlocked: o.Unlocked,
+						LockTime: o.Lock.LockTime,
+					})
+
+					state := "locked"
+					if o.Unlocked {
+						state = "unlocked"
+					}
+					ctx.Printf("\ttx %s\toutputNum %d\tvalue %s\taddress %s\t%s until %s\n",
+						o.Info.Tx.TxHash(), o.VIndex, ctx.Amount(o.Amount), o.Address, state, wallet.FormatLockTime(o.Lock.LockTime))
+				}
+
+				if ctx.JSON() {
+					return ctx.PrintJSON(SchemaTimeLock, &out)
+				}
+				return nil
+			}
+
+			// Spend from the unlocked outputs
+			dest, err := soterutil.DecodeAddress(destAddr, params)
+			if err != nil {
+				return errorf(CodeInvalid, "Failed to decode destination address %s: %s", destAddr, err)
+			}
+
+			matches := make([]wallet.TxMatch, 0)
+			for _, o := range outs {
+				if o.Unlocked {
+					matches = append(matches, o.TxMatch)
+				}
+			}
+			matches, err = wallet.ExcludePendingInputs(w, matches)
+			if err != nil {
+				return fmt.Errorf("Failed to read pending transactions from wallet: %w", err)
+			}
+
+			unlocked := make([]wallet.TimeLockedTxOut, 0)
+			spendable := soterutil.Amount(0)
+			for _, o := range outs {
+				for _, m := range matches {
+					if m.Info == o.Info && m.VIndex == o.VIndex {
+						unlocked = append(unlocked, o)
+						spendable += o.Amount
+					}
+				}
+			}
+
+			if sendAmount+feeAmount > spendable {
+				return errorf(CodeFunds, "Not enough unlocked coin found to satisfy amount requested for transaction; %s requested + %s fee, %s spendable",
+					ctx.Amount(sendAmount), ctx.Amount(feeAmount), ctx.Amount(spendable))
+			}
+
+			privPass, err := passphrase(ctx, priv)
+			if err != nil {
+				return err
+			}
+			txHash, err := wallet.SpendTimeLocked(client, w, privPass, unlocked, dest, sendAmount, feeAmount)
+			if err != nil {
+				return err
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaTimeLock, &TimeLockOutput{
+					Tx:     txHash.String(),
+					Dest:   dest.EncodeAddress(),
+					Amount: sendAmount,
+					Fee:    feeAmount,
+				})
+			}
+			ctx.Printf("Sent transaction with hash %s\n", txHash)
+			return nil
+		}
+	},
+}
+
+// parseLockTime returns the lock time from either the height or the RFC3339 time
+func parseLockTime(height int, timeStr string) (uint32, error) {
+	if height > 0 && len(timeStr) > 0 {
+		return 0, usageErrorf("You can only specify one of -lockheight, -locktime")
+	}
+
+	if height > 0 {
+		lockTime, err := wallet.LockTimeFromHeight(int32(height))
+		return lockTime, withCode(CodeInvalid, err)
+	}
+
+	if len(timeStr) > 0 {
+		t, err := time.Parse(time.RFC3339, timeStr)
+		if err != nil {
+			return 0, errorf(CodeInvalid, "failed to parse lock time %s: %s", timeStr, err)
+		}
+
+		lockTime, err := wallet.LockTimeFromTime(t)
+		return lockTime, withCode(CodeInvalid, err)
+	}
+
+	return 0, usageErrorf("You must specify a lock time (-lockheight or -locktime)")
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// TxOutput is the json output of tx decode
+type TxOutput struct {
+	Header
+	Tx       string          `json:"tx"`
+	Version  int32           `json:"version"`
+	LockTime uint32          `json:"lockTime"`
+	Inputs   []TxInputOutput `json:"inputs"`
+	Outputs  []TxOutOutput   `json:"outputs"`
+}
+
+// TxInputOutput is an input of a decoded transaction
+type TxInputOutput struct {
+	Tx       string `json:"tx,omitempty"`
+	VOut     uint32 `json:"vout"`
+	Sequence uint32 `json:"sequence"`
+	Coinbase bool   `json:"coinbase,omitempty"`
+}
+
+// TxOutOutput is an output of a decoded transaction
+type TxOutOutput struct {
+	Index     int              `json:"index"`
+	Value     soterutil.Amount `json:"value"`
+	Type      string           `json:"type"`
+	Addresses []string         `json:"addresses,omitempty"`
+	Data      string           `json:"data,omitempty"`
+}
+
+var txCommand = &command{
+	name: "tx",
+	commands: []*command{
+		txDecodeCommand,
+	},
+}
+
+var txDecodeCommand = &command{
+	name:    "decode",
+	args:    "[HEX]",
+	summary: "Show the inputs and outputs of a hex-encoded transaction (read from stdin without HEX, or with -)",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		return func(args []string) error {
+			if len(args) > 1 {
+				return usageErrorf("decode takes at most one transaction")
+			}
+
+			var txHex string
+			if len(args) == 0 || args[0] == "-" {
+				b, err := ioutil.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read transaction from stdin: %s", err)
+				}
+				txHex = string(b)
+			} else {
+				txHex = args[0]
+			}
+
+			tx, err := decodeTx(strings.TrimSpace(txHex))
+			if err != nil {
+				return err
+			}
+
+			out := txOutput(tx, ctx.Config.Params)
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaTx, &out)
+			}
+
+			ctx.Printf("tx %s\tversion %d\tlockTime %d\n", out.Tx, out.Version, out.LockTime)
+			for i, in := range out.Inputs {
+				if in.Coinbase {
+					ctx.Printf("input %d\tcoinbase\tsequence %d\n", i, in.Sequence)
+					continue
+				}
+				ctx.Printf("input %d\ttx %s\tvout %d\tsequence %d\n", i, in.Tx, in.VOut, in.Sequence)
+			}
+			for _, o := range out.Outputs {
+				ctx.Printf("output %d\tvalue %s\ttype %s", o.Index, ctx.Amount(o.Value), o.Type)
+				if len(o.Addresses) > 0 {
+					ctx.Printf("\taddresses %s", strings.Join(o.Addresses, ","))
+				}
+				if len(o.Data) > 0 {
+					ctx.Printf("\tdata %s", o.Data)
+				}
+				ctx.Printf("\n")
+			}
+			return nil
+		}
+	},
+}
+
+// decodeTx returns the transaction of the hex-encoded bytes
+func decodeTx(txHex string) (*wire.MsgTx, error) {
+	b, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, errorf(CodeInvalid, "failed to decode transaction hex: %s", err)
+	}
+
+	var tx wire.MsgTx
+	err = tx.Deserialize(bytes.NewReader(b))
+	if err != nil {
+		return nil, errorf(CodeInvalid, "failed to decode transaction: %s", err)
+	}
+
+	return &tx, nil
+}
+
+// txOutput returns the json output of the transaction, with the addresses of its outputs for the network
+func txOutput(tx *wire.MsgTx, params *chaincfg.Params) TxOutput {
+	out := TxOutput{
+		Tx:       tx.TxHash().String(),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Inputs:   []TxInputOutput{},
+		Outputs:  []TxOutOutput{},
+	}
+
+	for _, txIn := range tx.TxIn {
+		prev := txIn.PreviousOutPoint
+		in := TxInputOutput{VOut: prev.Index, Sequence: txIn.Sequence}
+		// A coinbase input doesn't spend an output
+		if prev.Index == wire.MaxPrevOutIndex && prev.Hash == (chainhash.Hash{}) {
+			in.Coinbase = true
+		} else {
+			in.Tx = prev.Hash.String()
+		}
+		out.Inputs = append(out.Inputs, in)
+	}
+
+	for i, txOut := range tx.TxOut {
+		o := TxOutOutput{Index: i, Value: soterutil.Amount(txOut.Value)}
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+		if err == nil {
+			for _, a := range addrs {
+				o.Addresses = append(o.Addresses, a.EncodeAddress())
+			}
+		}
+		o.Type = class.String()
+		if data, ok := wallet.ExtractNullData(txOut.PkScript); ok {
+			o.Data = hex.EncodeToString(data)
+		}
+		out.Outputs = append(out.Outputs, o)
+	}
+
+	return out
+}
@@ -0,0 +1,692 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/credentials"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// This is imported, so that the wallet driver is included in programs that use the commands
+	_ "github.com/soteria-dag/soterwallet/walletdb/bdb"
+)
+
+const (
+	defaultWalletName = "wallet.db"
+
+	// The number of unused addresses in a row to look for, when rescanning the dag for used addresses
+	defaultRescanGap = 20
+
+	walletFlagDesc = "Wallet file name (default wallet.db in the soterwallet app data directory of the network)"
+)
+
+// WalletOutput is the json output of the wallet commands
+type WalletOutput struct {
+	Header
+	Wallet   string          `json:"wallet"`
+	Network  string          `json:"network"`
+	Accounts []AccountOutput `json:"accounts,omitempty"`
+	// Address is the address that was created or imported, or whose private key was exported
+	Address    string `json:"address,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	// Backup is the backup file that was written or restored from, and BackupKeys is the number of private keys in it
+	// if it doesn't hold the wallet's seed
+	Backup     string `json:"backup,omitempty"`
+	BackupKeys int    `json:"backupKeys,omitempty"`
+	// Found is the number of used addresses found by a rescan
+	Found           int  `json:"found,omitempty"`
+	ChangedPrivPass bool `json:"changedPrivPass,omitempty"`
+	ChangedPubPass  bool `json:"changedPubPass,omitempty"`
+}
+
+// AccountOutput is an account of a wallet
+type AccountOutput struct {
+	Name      string           `json:"name"`
+	Number    uint32           `json:"number"`
+	Balance   soterutil.Amount `json:"balance"`
+	Addresses []string         `json:"addresses"`
+}
+
+var walletCommand = &command{
+	name:    "wallet",
+	summary: "Create and manage offline wallets",
+	commands: []*command{
+		walletCreateCommand,
+		walletAddressesCommand,
+		walletBackupCommand,
+		walletRestoreCommand,
+		walletPasswdCommand,
+		walletImportKeyCommand,
+		walletExportKeyCommand,
+	},
+}
+
+var walletCreateCommand = &command{
+	name:    "create",
+	summary: "Create a wallet, with an address to receive coin",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("wallet create takes no arguments")
+			}
+			priv.Confirm()
+			pub.Confirm()
+
+			name := walletPath(ctx)
+			if fileExists(name) {
+				return errorf(CodeWallet, "Wallet %s already exists", name)
+			}
+
+			w, err := createWalletFrom(ctx, priv, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			addr, err := wallet.NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+			if err != nil {
+				return fmt.Errorf("Failed to create new address: %s", err)
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaWallet, &WalletOutput{Wallet: name, Network: ctx.Config.Params.Name, Address: addr.EncodeAddress()})
+			}
+			ctx.Printf("Address: %s\n", addr)
+			return nil
+		}
+	},
+}
+
+var walletAddressesCommand = &command{
+	name:    "addresses",
+	summary: "List the accounts and addresses of a wallet",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		pub := pubPassphrase(fs)
+		var newAddr bool
+		fs.BoolVar(&newAddr, "new", false, "Create a new address in the default account first")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("wallet addresses takes no arguments")
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			if newAddr {
+				addr, err := wallet.NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+				if err != nil {
+					return fmt.Errorf("Failed to create new address: %s", err)
+				}
+				ctx.Printf("Created address %s\n", addr)
+			}
+
+			return listAccounts(ctx, w, false)
+		}
+	},
+}
+
+var walletBackupCommand = &command{
+	name:    "backup",
+	args:    "FILE",
+	summary: "Write an encrypted backup of a wallet to FILE",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		backupPass := backupPassphrase(fs)
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageErrorf("wallet backup takes the backup FILE")
+			}
+			backupPass.Confirm()
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			return backup(ctx, w, priv, args[0], backupPass)
+		}
+	},
+}
+
+var walletRestoreCommand = &command{
+	name:    "restore",
+	args:    "FILE",
+	summary: "Create a wallet from the encrypted backup in FILE",
+	// A restored wallet is for the network of its backup
+	load: func(ctx *Context) error {
+		return ctx.Config.LoadWallet(ctx.WalletName)
+	},
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		backupPass := backupPassphrase(fs)
+		var rescanDAG bool
+		var gap uint
+		fs.BoolVar(&rescanDAG, "rescan", false, "Look through the dag for used addresses after restoring")
+		fs.UintVar(&gap, "gap", defaultRescanGap, "Number of unused addresses in a row to look for when rescanning")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageErrorf("wallet restore takes the backup FILE")
+			}
+			if rescanDAG && len(ctx.Config.RPCServer) == 0 {
+				return usageErrorf("-rpcserver is required with -rescan")
+			}
+
+			return restore(ctx, priv, pub, args[0], backupPass, rescanDAG, gap)
+		}
+	},
+}
+
+var walletPasswdCommand = &command{
+	name:    "passwd",
+	summary: "Change the passwords of a wallet to -newpriv and -newpub",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		newPriv := credentials.NewPassphrase(fs, "newpriv", "New private password", credentials.NewPrivPassEnv)
+		newPub := credentials.NewPassphrase(fs, "newpub", "New public password", credentials.NewPubPassEnv)
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return usageErrorf("wallet passwd takes no arguments")
+			}
+			// An empty new password can be given, so the passwords are checked for being given instead of their values
+			if !newPriv.Given() && !newPub.Given() {
+				return usageErrorf("-newpriv or -newpub (or -newprivfrom, -newpubfrom) is required")
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			return passwd(ctx, w, priv, pub, newPriv, newPub)
+		}
+	},
+}
+
+var walletImportKeyCommand = &command{
+	name:    "importkey",
+	args:    "KEY",
+	summary: "Import the WIF-encoded private KEY into the wallet's imported account",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageErrorf("wallet importkey takes the private KEY")
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			addr, err := importKey(ctx, w, priv, args[0])
+			if err != nil {
+				return err
+			}
+
+			if ctx.JSON() {
+				return ctx.PrintJSON(SchemaWallet, &WalletOutput{Wallet: walletPath(ctx), Network: w.ChainParams().Name, Address: addr.EncodeAddress()})
+			}
+			return nil
+		}
+	},
+}
+
+var walletExportKeyCommand = &command{
+	name:    "exportkey",
+	args:    "ADDRESS",
+	summary: "Show the WIF-encoded private key of a wallet ADDRESS",
+	setup: func(ctx *Context, fs *flag.FlagSet) func(args []string) error {
+		ctx.walletFlag(fs, walletFlagDesc)
+		priv := privPassphrase(fs)
+		pub := pubPassphrase(fs)
+		var yes bool
+		fs.BoolVar(&yes, "yes", false, "Don't ask for confirmation before showing the private key")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usageErrorf("wallet exportkey takes the ADDRESS")
+			}
+
+			w, err := openWallet(ctx, pub)
+			if err != nil {
+				return err
+			}
+			defer w.Database().Close()
+
+			return exportKey(ctx, w, priv, args[0], yes)
+		}
+	},
+}
+
+// privPassphrase defines the flags of the wallet's private passphrase
+func privPassphrase(fs *flag.FlagSet) *credentials.Passphrase {
+	return credentials.NewPassphrase(fs, "priv",
+		"Password to use, for unlocking address manager (for private keys and info)", credentials.PrivPassEnv)
+}
+
+// pubPassphrase defines the flags of the wallet's public passphrase
+func pubPassphrase(fs *flag.FlagSet) *credentials.Passphrase {
+	return credentials.NewPassphrase(fs, "pub", "Password to use, for opening address manager", credentials.PubPassEnv)
+}
+
+// backupPassphrase defines the flags of the passphrase of a backup file
+func backupPassphrase(fs *flag.FlagSet) *credentials.Passphrase {
+	return credentials.NewPassphrase(fs, "backuppass", "Passphrase to encrypt or decrypt the backup file with",
+		credentials.BackupPassEnv)
+}
+
+// passphrase returns the passphrase, warning if it's empty
+func passphrase(ctx *Context, p *credentials.Passphrase) (string, error) {
+	value, err := p.Get()
+	if err != nil {
+		return "", withCode(CodeConfig, err)
+	}
+	if len(value) == 0 {
+		ctx.Warnf("-%s is not set!", p.Name())
+	}
+
+	return value, nil
+}
+
+// newPrivPassphrase returns a new private passphrase. It can't be empty, because the wallet's private keys would
+// only be protected by the empty passphrase.
+func newPrivPassphrase(p *credentials.Passphrase) (string, error) {
+	value, err := p.Get()
+	if err != nil {
+		return "", withCode(CodeConfig, err)
+	}
+	if len(value) == 0 {
+		return "", errorf(CodeConfig, "-%s is empty or not set; give a private password with -%sfrom, $%s or a prompt",
+			p.Name(), p.Name(), p.Env())
+	}
+
+	return value, nil
+}
+
+// fileExists returns true if a file with the name exists
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// confirm asks the user to type yes to continue, and returns true if they did
+func confirm(ctx *Context, prompt string) bool {
+	fmt.Fprintf(ctx.stderr, "%s Type 'yes' to continue: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// soterwalletPath returns a default soterwallet file name
+func soterwalletPath(name string, params *chaincfg.Params) string {
+	base := soterutil.AppDataDir("soterwallet", false)
+	return filepath.Join(base, params.Name, name)
+}
+
+// walletPath returns the wallet file of -w, or the default wallet file of the network if it isn't given
+func walletPath(ctx *Context) string {
+	if len(ctx.WalletName) > 0 {
+		return ctx.WalletName
+	}
+
+	return soterwalletPath(defaultWalletName, ctx.Config.Params)
+}
+
+// createWalletFrom creates the wallet of -w with the passphrases, and opens it
+func createWalletFrom(ctx *Context, priv, pub *credentials.Passphrase) (*soterwallet.Wallet, error) {
+	pubPass, err := passphrase(ctx, pub)
+	if err != nil {
+		return nil, err
+	}
+	privPass, err := newPrivPassphrase(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	name := walletPath(ctx)
+	err = wallet.CreateWallet(name, privPass, pubPass, ctx.Config.Params)
+	if err != nil {
+		return nil, errorf(CodeWallet, "Failed to create wallet: %s", err)
+	}
+	ctx.Printf("Created wallet: %s\n", name)
+
+	w, err := wallet.OpenWallet(name, pubPass, ctx.Config.Params)
+	if err != nil {
+		return nil, withCode(CodeWallet, err)
+	}
+
+	return w, nil
+}
+
+// openWallet opens the wallet of -w, which has to exist
+func openWallet(ctx *Context, pub *credentials.Passphrase) (*soterwallet.Wallet, error) {
+	name := walletPath(ctx)
+	if !fileExists(name) {
+		return nil, errorf(CodeWallet, "Wallet %s doesn't exist", name)
+	}
+
+	pubPass, err := passphrase(ctx, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := wallet.OpenWallet(name, pubPass, ctx.Config.Params)
+	if err != nil {
+		return nil, withCode(CodeWallet, err)
+	}
+	ctx.Printf("Opened wallet %s\n", name)
+
+	return w, nil
+}
+
+// listAccounts shows the accounts of the wallet and their addresses. If createDefault is true, an address is created
+// in the default account if it doesn't have one.
+func listAccounts(ctx *Context, w *soterwallet.Wallet, createDefault bool) error {
+	resp, err := w.Accounts(waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve accounts from wallet: %s", err)
+	}
+
+	out := WalletOutput{Wallet: walletPath(ctx), Network: w.ChainParams().Name}
+	for _, a := range resp.Accounts {
+		account := AccountOutput{
+			Name:      a.AccountName,
+			Number:    a.AccountNumber,
+			Balance:   a.TotalBalance,
+			Addresses: []string{},
+		}
+
+		addresses, err := w.AccountAddresses(a.AccountNumber)
+		if err != nil {
+			return fmt.Errorf("Failed to retrieve account addresses for account %s (%d): %s", a.AccountName, a.AccountNumber, err)
+		}
+		for _, addr := range addresses {
+			account.Addresses = append(account.Addresses, addr.EncodeAddress())
+		}
+
+		if createDefault && a.AccountName == "default" && len(addresses) == 0 {
+			// Create an address that could be used for transactions
+			newAddr, err := wallet.NewAddress(w, a.AccountNumber, waddrmgr.KeyScopeBIP0044)
+			if err != nil {
+				return fmt.Errorf("Failed to create new address for account %s (%d): %s", a.AccountName, a.AccountNumber, err)
+			}
+			account.Addresses = append(account.Addresses, newAddr.EncodeAddress())
+		}
+
+		out.Accounts = append(out.Accounts, account)
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaWallet, &out)
+	}
+
+	ctx.Printf("Accounts:\n")
+	for i, a := range out.Accounts {
+		ctx.Printf("\tname: %s\tnumber: %d\tbalance: %s\n", a.Name, a.Number, ctx.Amount(resp.Accounts[i].TotalBalance))
+		for _, addr := range a.Addresses {
+			ctx.Printf("\t\taddress: %s\n", addr)
+		}
+	}
+
+	return nil
+}
+
+// backup writes an encrypted backup of the wallet to the named file
+func backup(ctx *Context, w *soterwallet.Wallet, priv *credentials.Passphrase, name string, backupPass *credentials.Passphrase) error {
+	if fileExists(name) {
+		return errorf(CodeInvalid, "Backup file %s already exists", name)
+	}
+
+	backupPassphrase, err := backupPass.Get()
+	if err != nil {
+		return withCode(CodeConfig, err)
+	}
+	if len(backupPassphrase) == 0 {
+		return usageErrorf("-backuppass (backup passphrase) is required")
+	}
+	privPass, err := passphrase(ctx, priv)
+	if err != nil {
+		return err
+	}
+
+	b, err := wallet.NewBackup(w, privPass)
+	if err != nil {
+		return withCode(CodeWallet, err)
+	}
+
+	err = b.WriteFile(name, backupPassphrase)
+	if err != nil {
+		return fmt.Errorf("Failed to write backup %s: %s", name, err)
+	}
+
+	out := WalletOutput{Wallet: walletPath(ctx), Network: w.ChainParams().Name, Backup: name}
+	if b.Seed == nil {
+		out.BackupKeys = len(b.Keys)
+	}
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaWallet, &out)
+	}
+
+	ctx.Printf("Wrote backup of wallet to %s\n", name)
+	if b.Seed == nil {
+		ctx.Printf("The wallet was created without keeping its seed, so the backup holds the private keys of its %d addresses instead.\n", len(b.Keys))
+		ctx.Printf("Addresses created after the backup won't be restored from it.\n")
+	}
+	return nil
+}
+
+// restore creates the wallet of -w from the named backup file, and looks through the dag for its used addresses if
+// rescanDAG is true
+func restore(ctx *Context, priv, pub *credentials.Passphrase, name string, backupPass *credentials.Passphrase, rescanDAG bool, gap uint) error {
+	backupPassphrase, err := backupPass.Get()
+	if err != nil {
+		return withCode(CodeConfig, err)
+	}
+	if len(backupPassphrase) == 0 {
+		return usageErrorf("-backuppass (backup passphrase) is required")
+	}
+
+	b, err := wallet.ReadBackup(name, backupPassphrase)
+	if err != nil {
+		return withCode(CodeInvalid, err)
+	}
+
+	// The backup knows which network the wallet is for
+	if ctx.Config.Params != nil && ctx.Config.Params.Name != b.Network {
+		return errorf(CodeConfig, "Backup %s is of a %s wallet, not %s", name, b.Network, ctx.Config.Params.Name)
+	}
+	ctx.Config.Params, err = wallet.NetworkParams(b.Network)
+	if err != nil {
+		return withCode(CodeConfig, err)
+	}
+
+	walletName := walletPath(ctx)
+	if fileExists(walletName) {
+		return errorf(CodeWallet, "Wallet %s already exists; restore creates a new wallet", walletName)
+	}
+
+	pubPass, err := passphrase(ctx, pub)
+	if err != nil {
+		return err
+	}
+	privPass, err := passphrase(ctx, priv)
+	if err != nil {
+		return err
+	}
+
+	w, err := wallet.RestoreWallet(walletName, privPass, pubPass, b)
+	if err != nil {
+		return withCode(CodeWallet, err)
+	}
+	defer w.Database().Close()
+	ctx.Printf("Restored wallet %s from %s\n", walletName, name)
+
+	out := WalletOutput{Wallet: walletName, Network: b.Network, Backup: name}
+	if rescanDAG {
+		out.Found, err = rescan(ctx, w, gap)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaWallet, &out)
+	}
+	return nil
+}
+
+// rescan looks through the dag for used addresses of the wallet, adds them to the wallet, and returns how many were
+// found
+func rescan(ctx *Context, w *soterwallet.Wallet, gap uint) (int, error) {
+	rpcNodes, client, err := ctx.Config.ConnectRPC()
+	if err != nil {
+		return 0, errorf(CodeRPC, "RPC connection to %s failed: %s", ctx.Config.RPCServer, err)
+	}
+	defer rpcNodes.Shutdown()
+
+	found, err := wallet.RescanAddresses(client, w, uint32(gap))
+	if err != nil {
+		return 0, withCode(CodeRPC, err)
+	}
+
+	ctx.Printf("Found %d used addresses in the dag\n", found)
+	return found, nil
+}
+
+// passwd changes the wallet's public and private passphrases, for the ones that have a new passphrase given
+func passwd(ctx *Context, w *soterwallet.Wallet, priv, pub, newPriv, newPub *credentials.Passphrase) error {
+	changes := make([]wallet.PassphraseChange, 0, 2)
+	if newPriv.Given() {
+		privPass, err := passphrase(ctx, priv)
+		if err != nil {
+			return err
+		}
+		newPriv.Confirm()
+		newPrivPass, err := newPrivPassphrase(newPriv)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, wallet.PassphraseChange{Old: privPass, New: newPrivPass, Private: true})
+	}
+	if newPub.Given() {
+		pubPass, err := pub.Get()
+		if err != nil {
+			return withCode(CodeConfig, err)
+		}
+		newPub.Confirm()
+		newPubPass, err := newPub.Get()
+		if err != nil {
+			return withCode(CodeConfig, err)
+		}
+		changes = append(changes, wallet.PassphraseChange{Old: pubPass, New: newPubPass})
+	}
+
+	err := wallet.ChangePassphrases(w, changes...)
+	if err != nil {
+		return errorf(CodeWallet, "%w; the wallet's passphrases weren't changed", err)
+	}
+
+	out := WalletOutput{
+		Wallet:          walletPath(ctx),
+		Network:         w.ChainParams().Name,
+		ChangedPrivPass: newPriv.Given(),
+		ChangedPubPass:  newPub.Given(),
+	}
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaWallet, &out)
+	}
+
+	if out.ChangedPrivPass {
+		ctx.Printf("Changed private passphrase\n")
+	}
+	if out.ChangedPubPass {
+		ctx.Printf("Changed public passphrase\n")
+	}
+	return nil
+}
+
+// importKey imports the WIF-encoded private key into the wallet, and returns its address
+func importKey(ctx *Context, w *soterwallet.Wallet, priv *credentials.Passphrase, key string) (soterutil.Address, error) {
+	privPass, err := passphrase(ctx, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := wallet.ImportPrivateKey(w, privPass, key)
+	if err != nil {
+		return nil, withCode(CodeWallet, err)
+	}
+
+	ctx.Printf("Imported private key of address %s\n", addr.EncodeAddress())
+	return addr, nil
+}
+
+// exportKey shows the WIF-encoded private key of the wallet address, after asking for confirmation unless yes is true
+func exportKey(ctx *Context, w *soterwallet.Wallet, priv *credentials.Passphrase, address string, yes bool) error {
+	addr, err := soterutil.DecodeAddress(address, w.ChainParams())
+	if err != nil {
+		return errorf(CodeInvalid, "Failed to decode address %s: %s", address, err)
+	}
+
+	if !yes && !confirm(ctx, fmt.Sprintf("Anyone who sees the private key of %s can spend its coin.", address)) {
+		return fmt.Errorf("Not exporting private key")
+	}
+
+	privPass, err := passphrase(ctx, priv)
+	if err != nil {
+		return err
+	}
+
+	wif, err := wallet.ExportPrivateKey(w, privPass, addr)
+	if err != nil {
+		return withCode(CodeWallet, err)
+	}
+
+	if ctx.JSON() {
+		return ctx.PrintJSON(SchemaWallet, &WalletOutput{Wallet: walletPath(ctx), Network: w.ChainParams().Name, Address: address, PrivateKey: wif})
+	}
+	ctx.Printf("Private key of %s: %s\n", address, wif)
+	return nil
+}
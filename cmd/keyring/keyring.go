@@ -0,0 +1,14 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/soteria-dag/sotertools/cli"
+	"os"
+)
+
+func main() {
+	os.Exit(cli.Run("keyring", "keyring", os.Args[1:]))
+}
@@ -0,0 +1,558 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/credentials"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The environment variables that settings are read from, when they aren't given by a flag
+const (
+	ConfigFileEnv    = "SOTER_CONFIG"
+	NetworkEnv       = "SOTER_NETWORK"
+	NetworkFileEnv   = "SOTER_NETWORKFILE"
+	RPCServerEnv     = "SOTER_RPCSERVER"
+	RPCUserEnv       = "SOTER_RPCUSER"
+	RPCCertEnv       = "SOTER_RPCCERT"
+	RPCTransportEnv  = "SOTER_RPCTRANSPORT"
+	RPCProxyEnv      = "SOTER_RPCPROXY"
+	RPCProxyUserEnv  = "SOTER_RPCPROXYUSER"
+	RPCTimeoutEnv    = "SOTER_RPCTIMEOUT"
+	RPCCAEnv         = "SOTER_RPCCA"
+	RPCSkipVerifyEnv = "SOTER_RPCSKIPVERIFY"
+	UnitEnv          = "SOTER_UNIT"
+	LocaleEnv        = "SOTER_LOCALE"
+)
+
+// The settings of a config file
+const (
+	networkKey          = "network"
+	networkFileKey      = "networkfile"
+	rpcServerKey        = "rpcserver"
+	rpcUserKey          = "rpcuser"
+	rpcCertKey          = "rpccert"
+	rpcPassFromKey      = "rpcpassfrom"
+	rpcTransportKey     = "rpctransport"
+	rpcProxyKey         = "rpcproxy"
+	rpcProxyUserKey     = "rpcproxyuser"
+	rpcProxyPassFromKey = "rpcproxypassfrom"
+	rpcTimeoutKey       = "rpctimeout"
+	rpcCAKey            = "rpcca"
+	rpcSkipVerifyKey    = "rpcskipverify"
+	unitKey             = "unit"
+	localeKey           = "locale"
+)
+
+// knownKeys are the settings that can be given in a config file
+var knownKeys = map[string]bool{
+	networkKey:          true,
+	networkFileKey:      true,
+	rpcServerKey:        true,
+	rpcUserKey:          true,
+	rpcCertKey:          true,
+	rpcPassFromKey:      true,
+	rpcTransportKey:     true,
+	rpcProxyKey:         true,
+	rpcProxyUserKey:     true,
+	rpcProxyPassFromKey: true,
+	rpcTimeoutKey:       true,
+	rpcCAKey:            true,
+	rpcSkipVerifyKey:    true,
+	unitKey:             true,
+	localeKey:           true,
+}
+
+// The RPC transports that can be chosen
+const (
+	// TransportWebsocket sends requests over a websocket
+	TransportWebsocket = "ws"
+	// TransportHTTP sends each request as an HTTP POST, for proxies that block websockets. Notifications from the node
+	// need a websocket, so the features that use them aren't available.
+	TransportHTTP = "http"
+)
+
+// ErrNoNetwork is returned by RequireNetwork when no network was chosen
+var ErrNoNetwork = errors.New("No network chosen; use -mainnet, -testnet, -simnet or -network NAME, or set network in the config file")
+
+// networks are the standard networks that can be chosen, by the name of their flag and config file section. Custom
+// networks are chosen by the name in their network definition file.
+var networks = []struct {
+	name   string
+	params *chaincfg.Params
+}{
+	{"mainnet", &chaincfg.MainNetParams},
+	{"testnet", &chaincfg.TestNet1Params},
+	{"simnet", &chaincfg.SimNetParams},
+}
+
+// Config is the configuration shared by the commands: which network to use, and how to connect to a soterd node's RPC
+// server. Each setting is taken from the first of these that gives it: a flag, an environment variable, the config
+// file section of the network, and the start of the config file. The network can also be detected from a wallet file
+// or the RPC server.
+type Config struct {
+	// Network is the name of the chosen network, and Params are its params. They're empty if no network was chosen.
+	Network string
+	Params  *chaincfg.Params
+	// Custom is the network loaded from a network definition file, if one was given
+	Custom *Network
+
+	RPCServer string
+	RPCUser   string
+	RPCCert   string
+	// RPCPass is read when it's first needed, so that it's only prompted for if the RPC server is used
+	RPCPass *credentials.Passphrase
+
+	// RPCTransport is TransportWebsocket or TransportHTTP
+	RPCTransport string
+	// RPCProxy is a SOCKS5 proxy to connect to the RPC server through. RPCProxyPass is only read if RPCProxyUser is
+	// given.
+	RPCProxy     string
+	RPCProxyUser string
+	RPCProxyPass *credentials.Passphrase
+	// RPCTimeout is the longest time to wait for the RPC server to connect or start answering a request, or zero for no
+	// limit
+	RPCTimeout time.Duration
+	// RPCCA is a file of CA certs that the RPC server's cert can be signed by, besides the cert chain
+	RPCCA string
+	// RPCSkipVerify accepts any cert from the RPC server. It's only allowed on simnet.
+	RPCSkipVerify bool
+
+	// Display is how amounts are shown: the unit, and the number format of the locale
+	Display amount.Style
+
+	fs          *flag.FlagSet
+	configFile  string
+	network     string
+	networkFile string
+	nets        map[string]*bool
+}
+
+// DefaultConfigFile returns the config file named by the SOTER_CONFIG environment variable, or the default config
+// file in the sotertools app data directory.
+func DefaultConfigFile() string {
+	name, ok := os.LookupEnv(ConfigFileEnv)
+	if ok && len(name) > 0 {
+		return name
+	}
+
+	return filepath.Join(soterutil.AppDataDir("sotertools", false), "sotertools.conf")
+}
+
+// NewNetwork defines the flags for choosing a network on the flag set, for commands that don't use an RPC server.
+// Load reads the configuration, once the flags are parsed.
+func NewNetwork(fs *flag.FlagSet) *Config {
+	c := Config{
+		fs:   fs,
+		nets: make(map[string]*bool),
+	}
+
+	fs.StringVar(&c.configFile, "configfile", "",
+		fmt.Sprintf("Config file to read settings from (default $%s if set, otherwise sotertools.conf in the sotertools app data directory)", ConfigFileEnv))
+	for _, n := range networks {
+		c.nets[n.name] = fs.Bool(n.name, false, fmt.Sprintf("Use %s params", n.name))
+	}
+	fs.StringVar(&c.network, "network", "", "Name of the network to use, such as a custom network from -networkfile")
+	fs.StringVar(&c.networkFile, "networkfile", "",
+		fmt.Sprintf("Network definition file of a custom network (default $%s if set)", NetworkFileEnv))
+
+	return &c
+}
+
+// New defines the flags of the shared configuration on the flag set. Load reads the configuration, once the flags are
+// parsed.
+func New(fs *flag.FlagSet) *Config {
+	c := NewNetwork(fs)
+	fs.String(rpcServerKey, "",
+		"Soterd RPC server to use (ip:port), or a comma-separated list of servers to fail over between")
+	fs.String(rpcUserKey, "", "Soterd RPC server username to use")
+	c.RPCPass = credentials.NewPassphrase(fs, "rpcpass", "Soterd RPC server password to use", credentials.RPCPassEnv)
+	fs.String(rpcCertKey, "", "Soterd RPC server cert chain")
+	fs.String(rpcTransportKey, "",
+		"RPC transport to use: ws (websockets) or http (HTTP POST requests, for proxies that block websockets) (default ws)")
+	fs.String(rpcProxyKey, "", "SOCKS5 proxy to connect to the RPC server through (ip:port)")
+	fs.String(rpcProxyUserKey, "", "SOCKS5 proxy username to use")
+	c.RPCProxyPass = credentials.NewPassphrase(fs, "rpcproxypass", "SOCKS5 proxy password to use",
+		credentials.RPCProxyPassEnv)
+	fs.Duration(rpcTimeoutKey, 0, "Longest time to wait for the RPC server to connect or start answering a request, such as 30s (default no limit)")
+	fs.String(rpcCAKey, "", "File of CA certs that the RPC server's cert can be signed by")
+	fs.Bool(rpcSkipVerifyKey, false, "Accept any cert from the RPC server. INSECURE: only allowed on simnet")
+	fs.String(unitKey, "", fmt.Sprintf("Unit to show amounts in: %s (default SOTER)", strings.Join(amount.UnitNames(), ", ")))
+	fs.String(localeKey, "",
+		fmt.Sprintf("Number format to show amounts with, by the language of a locale: %s (default none, which doesn't group thousands)",
+			strings.Join(amount.LocaleNames(), ", ")))
+
+	return c
+}
+
+// flagGiven returns true if the named flag was given on the command line
+func (c *Config) flagGiven(name string) bool {
+	given := false
+	c.fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+
+	return given
+}
+
+// Load reads the config file and environment variables, and sets the configuration from them and the flags. A custom
+// network from a network definition file is registered, so that it can be used. Load returns an error if more than
+// one network is chosen, but not if none is; RequireNetwork checks for that.
+func (c *Config) Load() error {
+	return c.load("")
+}
+
+// LoadWallet is Load for commands that use a wallet file. If no network is chosen by a flag or environment variable,
+// the network of the wallet is used over the one in the config file.
+func (c *Config) LoadWallet(walletFile string) error {
+	return c.load(walletFile)
+}
+
+// walletNetwork returns the name of the wallet's network, or "" if the wallet doesn't exist or its network can't be
+// detected.
+func walletNetwork(walletFile string) (string, error) {
+	if len(walletFile) == 0 {
+		return "", nil
+	}
+	_, err := os.Stat(walletFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	params, err := wallet.WalletNetwork(walletFile)
+	if err == wallet.ErrUnknownWalletNetwork {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return params.Name, nil
+}
+
+// load reads the configuration, using the network of the wallet file if it's given and exists
+func (c *Config) load(walletFile string) error {
+	s := settings{}
+	name := c.configFile
+	if len(name) == 0 {
+		name = DefaultConfigFile()
+	}
+	file, err := readFile(name, knownKeys)
+	switch {
+	case err == nil:
+		s = file
+	case os.IsNotExist(err) && len(c.configFile) == 0:
+		// The config file is optional, unless it's given by the flag
+	default:
+		return fmt.Errorf("Failed to read config file: %s", err)
+	}
+
+	// Load a custom network
+	networkFile := c.networkFile
+	if len(networkFile) == 0 {
+		networkFile = os.Getenv(NetworkFileEnv)
+	}
+	if len(networkFile) == 0 {
+		networkFile, _ = s.get("", networkFileKey)
+	}
+	if len(networkFile) > 0 {
+		c.Custom, err = LoadNetwork(expandPath(networkFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Choose the network
+	chosen := make([]string, 0)
+	for _, n := range networks {
+		if *c.nets[n.name] {
+			chosen = append(chosen, n.name)
+		}
+	}
+	if len(c.network) > 0 {
+		chosen = append(chosen, c.network)
+	}
+	if len(chosen) > 1 {
+		return fmt.Errorf("You can only specify one net param (-%s, -network)", strings.Join(networkNames(), ", -"))
+	}
+	switch {
+	case len(chosen) == 1:
+		c.Network = chosen[0]
+	case len(os.Getenv(NetworkEnv)) > 0:
+		c.Network = os.Getenv(NetworkEnv)
+	default:
+		c.Network, err = walletNetwork(walletFile)
+		if err != nil {
+			return err
+		}
+		if len(c.Network) == 0 {
+			c.Network, _ = s.get("", networkKey)
+		}
+	}
+	if len(c.Network) == 0 && c.Custom != nil {
+		// A custom network is used if no other network is chosen
+		c.Network = c.Custom.Params.Name
+	}
+	if len(c.Network) > 0 {
+		c.Network, c.Params, err = findNetwork(c.Network)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Read the other settings
+	lookup := func(key, env string) string {
+		if c.flagGiven(key) {
+			return c.fs.Lookup(key).Value.String()
+		}
+		value, ok := os.LookupEnv(env)
+		if ok {
+			return value
+		}
+		value, _ = s.get(c.Network, key)
+		return value
+	}
+	c.RPCServer = lookup(rpcServerKey, RPCServerEnv)
+	c.RPCUser = lookup(rpcUserKey, RPCUserEnv)
+	c.RPCCert = expandPath(lookup(rpcCertKey, RPCCertEnv))
+	c.RPCProxy = lookup(rpcProxyKey, RPCProxyEnv)
+	c.RPCProxyUser = lookup(rpcProxyUserKey, RPCProxyUserEnv)
+	c.RPCCA = expandPath(lookup(rpcCAKey, RPCCAEnv))
+
+	c.RPCTransport = lookup(rpcTransportKey, RPCTransportEnv)
+	switch c.RPCTransport {
+	case "":
+		c.RPCTransport = TransportWebsocket
+	case TransportWebsocket, TransportHTTP:
+	default:
+		return fmt.Errorf("Invalid rpctransport %s; use %s or %s", c.RPCTransport, TransportWebsocket, TransportHTTP)
+	}
+
+	timeout := lookup(rpcTimeoutKey, RPCTimeoutEnv)
+	if len(timeout) > 0 {
+		c.RPCTimeout, err = time.ParseDuration(timeout)
+		if err != nil || c.RPCTimeout < 0 {
+			return fmt.Errorf("Invalid rpctimeout %s; use a duration such as 30s", timeout)
+		}
+	}
+
+	skipVerify := lookup(rpcSkipVerifyKey, RPCSkipVerifyEnv)
+	if len(skipVerify) > 0 {
+		c.RPCSkipVerify, err = strconv.ParseBool(skipVerify)
+		if err != nil {
+			return fmt.Errorf("Invalid rpcskipverify %s; use true or false", skipVerify)
+		}
+	}
+	unit := lookup(unitKey, UnitEnv)
+	locale := lookup(localeKey, LocaleEnv)
+	c.Display, err = amount.NewStyle(unit, locale)
+	if err != nil {
+		return fmt.Errorf("Invalid unit or locale: %s", err)
+	}
+
+	if len(c.RPCServer) == 0 && c.Custom != nil && c.Custom.Params == c.Params && len(c.Custom.RPCPort) > 0 {
+		c.RPCServer = "127.0.0.1:" + c.Custom.RPCPort
+	}
+
+	from, ok := s.get(c.Network, rpcPassFromKey)
+	if ok && len(from) > 0 && c.RPCPass != nil {
+		c.RPCPass.DefaultFrom(from)
+	}
+	from, ok = s.get(c.Network, rpcProxyPassFromKey)
+	if ok && len(from) > 0 && c.RPCProxyPass != nil {
+		c.RPCProxyPass.DefaultFrom(from)
+	}
+
+	return nil
+}
+
+// RequireNetwork returns ErrNoNetwork if no network was chosen. If an RPC server is configured, its network is used
+// when none was chosen.
+func (c *Config) RequireNetwork() error {
+	if c.Params != nil {
+		return nil
+	}
+	if c.RPCPass == nil || len(c.RPCServers()) == 0 {
+		return ErrNoNetwork
+	}
+
+	m, client, err := c.ConnectRPC()
+	if err != nil {
+		return fmt.Errorf("%s, and the network of the RPC server couldn't be found: %s", ErrNoNetwork, err)
+	}
+	defer m.Shutdown()
+
+	info, err := client.GetBlockChainInfo()
+	if err != nil {
+		return fmt.Errorf("%s, and the network of the RPC server couldn't be found: %s", ErrNoNetwork, err)
+	}
+	c.Network, c.Params, err = findNetwork(info.Chain)
+	if err != nil {
+		return fmt.Errorf("RPC server %s is for network %s: %s", c.RPCServer, info.Chain, err)
+	}
+
+	return nil
+}
+
+// RPCServers returns the configured RPC servers. Several servers can be given as a comma-separated list.
+func (c *Config) RPCServers() []string {
+	servers := make([]string, 0)
+	for _, server := range strings.Split(c.RPCServer, ",") {
+		server = strings.TrimSpace(server)
+		if len(server) > 0 {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+// Nodes returns a manager of the configured RPC servers, that fails over between them. If no cert chain is
+// configured, the default one of a local soterd node is used if it exists.
+func (c *Config) Nodes() (*nodes.Manager, error) {
+	servers := c.RPCServers()
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("No RPC server configured (-rpcserver)")
+	}
+	// Skipping verification lets anyone in between read the RPC password, which is only acceptable on a test network
+	if c.RPCSkipVerify && c.Params != &chaincfg.SimNetParams {
+		return nil, fmt.Errorf("rpcskipverify can only be used on simnet")
+	}
+
+	var certs []byte
+	var err error
+	if len(c.RPCCert) > 0 {
+		certs, err = ioutil.ReadFile(c.RPCCert)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read RPC cert chain: %s", err)
+		}
+	} else {
+		// Try a default cert path
+		soterdDir := soterutil.AppDataDir("soterd", false)
+		certs, _ = ioutil.ReadFile(filepath.Join(soterdDir, "rpc.cert"))
+	}
+	if len(c.RPCCA) > 0 {
+		ca, err := ioutil.ReadFile(c.RPCCA)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read RPC CA certs: %s", err)
+		}
+		certs = append(append(certs, '\n'), ca...)
+	}
+
+	pass, err := c.RPCPass.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := nodes.Options{
+		Timeout:            c.RPCTimeout,
+		InsecureSkipVerify: c.RPCSkipVerify,
+		Proxy:              c.RPCProxy,
+		ProxyUser:          c.RPCProxyUser,
+	}
+	if len(c.RPCProxy) > 0 && len(c.RPCProxyUser) > 0 {
+		opts.ProxyPass, err = c.RPCProxyPass.Get()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	configs := make([]rpcclient.ConnConfig, 0, len(servers))
+	for _, server := range servers {
+		configs = append(configs, rpcclient.ConnConfig{
+			Host:         server,
+			Endpoint:     "ws",
+			User:         c.RPCUser,
+			Pass:         pass,
+			Certificates: certs,
+			HTTPPostMode: c.RPCTransport == TransportHTTP,
+			// The nodes manager reconnects, so that it can fail over to another node instead
+			DisableAutoReconnect: true,
+		})
+	}
+
+	return nodes.New(configs, opts), nil
+}
+
+// ConnectRPC returns an RPC client connection to the configured soterd node. If several nodes are configured, the
+// healthy one with the highest dag tips is connected to. The returned Manager holds the connections to all of the
+// nodes, and must be shut down once the client isn't needed anymore.
+func (c *Config) ConnectRPC() (*nodes.Manager, *rpcclient.Client, error) {
+	m, err := c.Nodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := m.Client()
+	if err != nil {
+		m.Shutdown()
+		return nil, nil, err
+	}
+
+	return m, client, nil
+}
+
+// NetworkParams returns the params of the named network. Both the names of the network flags and the names in the
+// params are accepted, and custom networks are found by their name once they're loaded.
+func NetworkParams(name string) (*chaincfg.Params, error) {
+	_, params, err := findNetwork(name)
+	return params, err
+}
+
+// findNetwork returns the config file section name and params of the named network
+func findNetwork(name string) (string, *chaincfg.Params, error) {
+	for _, n := range networks {
+		if n.name == name || n.params.Name == name {
+			return n.name, n.params, nil
+		}
+	}
+
+	params, err := wallet.NetworkParams(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return params.Name, params, nil
+}
+
+// networkNames returns the names of the networks that can be chosen
+func networkNames() []string {
+	names := make([]string, 0, len(networks))
+	for _, n := range networks {
+		names = append(names, n.name)
+	}
+
+	return names
+}
+
+// expandPath replaces a leading ~ in the path with the home directory
+func expandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes the config file contents to a temporary file, and returns its name
+func writeConfig(t *testing.T, contents string) string {
+	tmpfile, err := ioutil.TempFile("", "sotertools_config-*.conf")
+	if err != nil {
+		t.Fatalf("failed to create config file: %s", err)
+	}
+	_, _ = tmpfile.WriteString(contents)
+	_ = tmpfile.Close()
+
+	return tmpfile.Name()
+}
+
+func TestLoad(t *testing.T) {
+	name := writeConfig(t, `; Settings for every network
+network = simnet
+rpcuser = "user"
+
+[simnet]
+rpcserver = 127.0.0.1:18556
+rpcpassfrom = env:SOTERTOOLS_TEST_RPCPASS
+
+# Settings for testnet
+[testnet]
+rpcserver = 'testnet.example.com:18334'
+rpcuser = testuser
+`)
+	defer os.Remove(name)
+
+	_ = os.Setenv("SOTERTOOLS_TEST_RPCPASS", "rpcpass")
+	defer os.Unsetenv("SOTERTOOLS_TEST_RPCPASS")
+	for _, env := range []string{NetworkEnv, RPCServerEnv, RPCUserEnv, RPCCertEnv, "SOTER_RPCPASS"} {
+		_ = os.Unsetenv(env)
+	}
+
+	tests := []struct {
+		args    []string
+		env     map[string]string
+		params  *chaincfg.Params
+		server  string
+		user    string
+		rpcPass string
+	}{
+		// Settings of the network section are used over those at the start of the file
+		{[]string{}, nil, &chaincfg.SimNetParams, "127.0.0.1:18556", "user", "rpcpass"},
+		{[]string{"-testnet"}, nil, &chaincfg.TestNet1Params, "testnet.example.com:18334", "testuser", ""},
+		// The environment is used over the config file
+		{[]string{}, map[string]string{NetworkEnv: "testnet1"}, &chaincfg.TestNet1Params, "testnet.example.com:18334", "testuser", ""},
+		{[]string{}, map[string]string{RPCUserEnv: "envuser"}, &chaincfg.SimNetParams, "127.0.0.1:18556", "envuser", "rpcpass"},
+		// Flags are used over the environment
+		{[]string{"-rpcuser", "flaguser", "-rpcpass", "flagpass"}, map[string]string{RPCUserEnv: "envuser"},
+			&chaincfg.SimNetParams, "127.0.0.1:18556", "flaguser", "flagpass"},
+		{[]string{"-mainnet", "-rpcserver", "127.0.0.1:10255"}, map[string]string{NetworkEnv: "testnet"},
+			&chaincfg.MainNetParams, "127.0.0.1:10255", "user", ""},
+	}
+
+	for _, test := range tests {
+		for k, v := range test.env {
+			_ = os.Setenv(k, v)
+		}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, test.args...))
+		if err != nil {
+			t.Fatalf("failed to parse %v: %s", test.args, err)
+		}
+		err = c.Load()
+		if err != nil {
+			t.Fatalf("failed to load config for %v: %s", test.args, err)
+		}
+
+		for k := range test.env {
+			_ = os.Unsetenv(k)
+		}
+
+		if c.Params != test.params {
+			t.Errorf("wrong network for %v %v; got %s, want %s", test.args, test.env, c.Params.Name, test.params.Name)
+		}
+		if c.RPCServer != test.server {
+			t.Errorf("wrong rpcserver for %v %v; got %s, want %s", test.args, test.env, c.RPCServer, test.server)
+		}
+		if c.RPCUser != test.user {
+			t.Errorf("wrong rpcuser for %v %v; got %s, want %s", test.args, test.env, c.RPCUser, test.user)
+		}
+
+		// Only check passwords that aren't prompted for
+		if len(test.rpcPass) > 0 {
+			pass, err := c.RPCPass.Get()
+			if err != nil {
+				t.Errorf("failed to get rpcpass for %v: %s", test.args, err)
+			} else if pass != test.rpcPass {
+				t.Errorf("wrong rpcpass for %v; got %s, want %s", test.args, pass, test.rpcPass)
+			}
+		}
+	}
+
+	// Choosing more than one network, or a missing or invalid config file, is an error
+	bad := writeConfig(t, "rpcservr = 127.0.0.1:18556\n")
+	defer os.Remove(bad)
+	for _, args := range [][]string{
+		{"-configfile", name, "-simnet", "-testnet"},
+		{"-configfile", "/nonexistent/sotertools.conf"},
+		{"-configfile", bad},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		_ = fs.Parse(args)
+		err := c.Load()
+		if err == nil {
+			t.Errorf("loaded config for %v", args)
+		}
+	}
+}
+
+func TestLoadWallet(t *testing.T) {
+	name := writeConfig(t, "network = testnet\n")
+	defer os.Remove(name)
+	for _, env := range []string{NetworkEnv, NetworkFileEnv} {
+		_ = os.Unsetenv(env)
+	}
+
+	dir, err := ioutil.TempDir("", "sotertools_wallet")
+	if err != nil {
+		t.Fatalf("failed to create wallet dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	walletFile := filepath.Join(dir, "wallet.db")
+	err = wallet.CreateWallet(walletFile, "priv", "pub", &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to create wallet: %s", err)
+	}
+
+	tests := []struct {
+		args       []string
+		walletFile string
+		params     *chaincfg.Params
+	}{
+		// The wallet's network is used over the config file
+		{[]string{}, walletFile, &chaincfg.SimNetParams},
+		{[]string{}, filepath.Join(dir, "new.db"), &chaincfg.TestNet1Params},
+		// A network flag is used over the wallet's network, and OpenWallet reports the mismatch
+		{[]string{"-mainnet"}, walletFile, &chaincfg.MainNetParams},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := NewNetwork(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, test.args...))
+		if err != nil {
+			t.Fatalf("failed to parse %v: %s", test.args, err)
+		}
+		err = c.LoadWallet(test.walletFile)
+		if err != nil {
+			t.Fatalf("failed to load config for %v %s: %s", test.args, test.walletFile, err)
+		}
+
+		if c.Params != test.params {
+			t.Errorf("wrong network for %v %s; got %s, want %s", test.args, test.walletFile, c.Params.Name, test.params.Name)
+		}
+	}
+}
+
+func TestRPCServers(t *testing.T) {
+	tests := []struct {
+		server string
+		want   []string
+	}{
+		{"", []string{}},
+		{"127.0.0.1:18556", []string{"127.0.0.1:18556"}},
+		{"127.0.0.1:18556, node.example.com:18556,", []string{"127.0.0.1:18556", "node.example.com:18556"}},
+	}
+
+	for _, test := range tests {
+		c := Config{RPCServer: test.server}
+		got := c.RPCServers()
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("wrong servers for %q; got %v, want %v", test.server, got, test.want)
+		}
+	}
+}
+
+func TestLoadTransport(t *testing.T) {
+	name := writeConfig(t, `rpcserver = 127.0.0.1:18556
+rpcpassfrom = env:SOTERTOOLS_TEST_RPCPASS
+
+[simnet]
+rpctransport = http
+rpctimeout = 30s
+rpcskipverify = true
+
+[testnet]
+rpcskipverify = true
+`)
+	defer os.Remove(name)
+	_ = os.Setenv("SOTERTOOLS_TEST_RPCPASS", "rpcpass")
+	defer os.Unsetenv("SOTERTOOLS_TEST_RPCPASS")
+	for _, env := range []string{NetworkEnv, RPCTransportEnv, RPCTimeoutEnv, RPCSkipVerifyEnv} {
+		_ = os.Unsetenv(env)
+	}
+
+	tests := []struct {
+		args       []string
+		transport  string
+		timeout    time.Duration
+		skipVerify bool
+		// Whether Nodes accepts the settings
+		ok bool
+	}{
+		{[]string{"-simnet"}, TransportHTTP, 30 * time.Second, true, true},
+		{[]string{"-simnet", "-rpctransport", "ws", "-rpctimeout", "1m", "-rpcskipverify=false"},
+			TransportWebsocket, time.Minute, false, true},
+		{[]string{"-mainnet"}, TransportWebsocket, 0, false, true},
+		// Certs can only go unverified on simnet
+		{[]string{"-testnet"}, TransportWebsocket, 0, true, false},
+		{[]string{"-mainnet", "-rpcskipverify"}, TransportWebsocket, 0, true, false},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, test.args...))
+		if err != nil {
+			t.Fatalf("failed to parse %v: %s", test.args, err)
+		}
+		err = c.Load()
+		if err != nil {
+			t.Fatalf("failed to load config for %v: %s", test.args, err)
+		}
+
+		if c.RPCTransport != test.transport {
+			t.Errorf("wrong rpctransport for %v; got %s, want %s", test.args, c.RPCTransport, test.transport)
+		}
+		if c.RPCTimeout != test.timeout {
+			t.Errorf("wrong rpctimeout for %v; got %s, want %s", test.args, c.RPCTimeout, test.timeout)
+		}
+		if c.RPCSkipVerify != test.skipVerify {
+			t.Errorf("wrong rpcskipverify for %v; got %t, want %t", test.args, c.RPCSkipVerify, test.skipVerify)
+		}
+
+		m, err := c.Nodes()
+		if test.ok && err != nil {
+			t.Errorf("failed to configure nodes for %v: %s", test.args, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("configured nodes for %v", test.args)
+		}
+		if m != nil {
+			m.Shutdown()
+		}
+	}
+
+	// Invalid settings are errors
+	for _, args := range [][]string{
+		{"-simnet", "-rpctransport", "tcp"},
+		{"-simnet", "-rpctimeout", "-5s"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, args...))
+		if err == nil {
+			err = c.Load()
+		}
+		if err == nil {
+			t.Errorf("loaded config for %v", args)
+		}
+	}
+}
+
+func TestLoadDisplay(t *testing.T) {
+	name := writeConfig(t, `locale = en
+
+[testnet]
+unit = mSOTER
+`)
+	defer os.Remove(name)
+	for _, env := range []string{NetworkEnv, UnitEnv, LocaleEnv} {
+		_ = os.Unsetenv(env)
+	}
+
+	tests := []struct {
+		args []string
+		env  string
+		want amount.Style
+	}{
+		{[]string{"-simnet"}, "", amount.Style{Unit: soterutil.AmountSOTER, Thousands: ",", Decimal: "."}},
+		{[]string{"-testnet"}, "", amount.Style{Unit: soterutil.AmountMilliSOTER, Thousands: ",", Decimal: "."}},
+		{[]string{"-testnet", "-unit", "nSoter", "-locale", "de"}, "",
+			amount.Style{Unit: amount.BaseUnit, Thousands: ".", Decimal: ","}},
+		// The environment variable is used over the config file, and the flag over both
+		{[]string{"-simnet"}, "none", amount.DefaultStyle},
+		{[]string{"-simnet", "-locale", "en"}, "de", amount.Style{Unit: soterutil.AmountSOTER, Thousands: ",", Decimal: "."}},
+	}
+
+	for _, test := range tests {
+		if len(test.env) > 0 {
+			_ = os.Setenv(LocaleEnv, test.env)
+		} else {
+			_ = os.Unsetenv(LocaleEnv)
+		}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, test.args...))
+		if err != nil {
+			t.Fatalf("failed to parse %v: %s", test.args, err)
+		}
+		err = c.Load()
+		if err != nil {
+			t.Fatalf("failed to load config for %v: %s", test.args, err)
+		}
+
+		if c.Display != test.want {
+			t.Errorf("wrong display of amounts for %v with %s=%q; got %+v, want %+v",
+				test.args, LocaleEnv, test.env, c.Display, test.want)
+		}
+	}
+	_ = os.Unsetenv(LocaleEnv)
+
+	// Invalid settings are errors
+	for _, args := range [][]string{
+		{"-simnet", "-unit", "BTC"},
+		{"-simnet", "-locale", "xx"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		c := New(fs)
+		err := fs.Parse(append([]string{"-configfile", name}, args...))
+		if err == nil {
+			err = c.Load()
+		}
+		if err == nil {
+			t.Errorf("loaded config for %v", args)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// settings are the settings of a config file, by section. Settings given before the first section are in the ""
+// section.
+type settings map[string]map[string]string
+
+// get returns the setting from the section, or from the "" section if the section doesn't have it
+func (s settings) get(section, key string) (string, bool) {
+	value, ok := s[section][key]
+	if ok {
+		return value, true
+	}
+
+	value, ok = s[""][key]
+	return value, ok
+}
+
+// readFile reads the settings of an INI-style config file, which is also a subset of TOML:
+//
+//   ; Comments start with ; or #
+//   network = simnet
+//
+//   [simnet]
+//   rpcserver = "127.0.0.1:18556"
+//
+// Values can be quoted. Keys that aren't in known are an error, to catch typos.
+func readFile(name string, known map[string]bool) (settings, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := settings{"": make(map[string]string)}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("%s line %d: invalid section %s", name, n, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := s[section]; !ok {
+				s[section] = make(map[string]string)
+			}
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%s line %d: expected key = value", name, n)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := unquote(strings.TrimSpace(line[i+1:]))
+		if !known[key] {
+			return nil, fmt.Errorf("%s line %d: unknown setting %s", name, n, key)
+		}
+
+		s[section][key] = value
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// unquote removes matching double or single quotes around the value
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/wire"
+	"io/ioutil"
+)
+
+// Network is a custom network, loaded from a network definition file
+type Network struct {
+	Params *chaincfg.Params
+	// RPCPort is the default RPC port of the network's soterd nodes, used when no RPC server is configured
+	RPCPort string
+}
+
+// networkFile is the format of a network definition file. The params of the base network are used for the fields
+// that aren't given.
+type networkFile struct {
+	Name string `json:"name"`
+	Base string `json:"base"`
+	// Net is the magic that identifies the network's messages, which has to be different from other networks
+	Net *uint32 `json:"net"`
+
+	DefaultPort *string `json:"defaultPort"`
+	RPCPort     string  `json:"rpcPort"`
+
+	// GenesisBlock is the hex-encoded serialized genesis block
+	GenesisBlock     string  `json:"genesisBlock"`
+	CoinbaseMaturity *uint16 `json:"coinbaseMaturity"`
+
+	PubKeyHashAddrID        *byte   `json:"pubKeyHashAddrID"`
+	ScriptHashAddrID        *byte   `json:"scriptHashAddrID"`
+	PrivateKeyID            *byte   `json:"privateKeyID"`
+	WitnessPubKeyHashAddrID *byte   `json:"witnessPubKeyHashAddrID"`
+	WitnessScriptHashAddrID *byte   `json:"witnessScriptHashAddrID"`
+	Bech32HRPSegwit         *string `json:"bech32HRPSegwit"`
+	// HDPrivateKeyID and HDPublicKeyID are hex-encoded 4-byte magics
+	HDPrivateKeyID string  `json:"hdPrivateKeyID"`
+	HDPublicKeyID  string  `json:"hdPublicKeyID"`
+	HDCoinType     *uint32 `json:"hdCoinType"`
+}
+
+// decodeKeyID decodes a hex-encoded 4-byte HD key magic
+func decodeKeyID(s string) ([4]byte, error) {
+	var id [4]byte
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	}
+	if len(b) != len(id) {
+		return id, fmt.Errorf("%s is %d bytes, not %d", s, len(b), len(id))
+	}
+	copy(id[:], b)
+
+	return id, nil
+}
+
+// LoadNetwork reads the network definition file, and registers the network so that the commands can use it
+func LoadNetwork(name string) (*Network, error) {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read network file: %s", err)
+	}
+
+	var f networkFile
+	err = json.Unmarshal(content, &f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read network file %s: %s", name, err)
+	}
+
+	if len(f.Name) == 0 {
+		return nil, fmt.Errorf("No network name in %s", name)
+	}
+	_, err = NetworkParams(f.Name)
+	if err == nil {
+		return nil, fmt.Errorf("Network %s in %s already exists", f.Name, name)
+	}
+	if f.Net == nil {
+		return nil, fmt.Errorf("No network magic (net) in %s", name)
+	}
+	if len(f.Base) == 0 {
+		f.Base = "simnet"
+	}
+	base, err := NetworkParams(f.Base)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid base network in %s: %s", name, err)
+	}
+
+	params := *base
+	params.Name = f.Name
+	params.Net = wire.SoterNet(*f.Net)
+	if f.DefaultPort != nil {
+		params.DefaultPort = *f.DefaultPort
+	}
+	if len(f.GenesisBlock) > 0 {
+		b, err := hex.DecodeString(f.GenesisBlock)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid genesis block in %s: %s", name, err)
+		}
+		var block wire.MsgBlock
+		err = block.Deserialize(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid genesis block in %s: %s", name, err)
+		}
+		hash := block.BlockHash()
+		params.GenesisBlock = &block
+		params.GenesisHash = &hash
+	}
+	if f.CoinbaseMaturity != nil {
+		params.CoinbaseMaturity = *f.CoinbaseMaturity
+	}
+	if f.PubKeyHashAddrID != nil {
+		params.PubKeyHashAddrID = *f.PubKeyHashAddrID
+	}
+	if f.ScriptHashAddrID != nil {
+		params.ScriptHashAddrID = *f.ScriptHashAddrID
+	}
+	if f.PrivateKeyID != nil {
+		params.PrivateKeyID = *f.PrivateKeyID
+	}
+	if f.WitnessPubKeyHashAddrID != nil {
+		params.WitnessPubKeyHashAddrID = *f.WitnessPubKeyHashAddrID
+	}
+	if f.WitnessScriptHashAddrID != nil {
+		params.WitnessScriptHashAddrID = *f.WitnessScriptHashAddrID
+	}
+	if f.Bech32HRPSegwit != nil {
+		params.Bech32HRPSegwit = *f.Bech32HRPSegwit
+	}
+	if len(f.HDPrivateKeyID) > 0 {
+		params.HDPrivateKeyID, err = decodeKeyID(f.HDPrivateKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hdPrivateKeyID in %s: %s", name, err)
+		}
+	}
+	if len(f.HDPublicKeyID) > 0 {
+		params.HDPublicKeyID, err = decodeKeyID(f.HDPublicKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hdPublicKeyID in %s: %s", name, err)
+		}
+	}
+	if f.HDCoinType != nil {
+		params.HDCoinType = *f.HDCoinType
+	}
+
+	err = wallet.RegisterNetwork(&params)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to register network from %s: %s", name, err)
+	}
+
+	n := Network{
+		Params:  &params,
+		RPCPort: f.RPCPort,
+	}
+	return &n, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"os"
+	"testing"
+)
+
+func TestLoadNetwork(t *testing.T) {
+	name := writeConfig(t, `{
+	"name": "testprivnet",
+	"base": "simnet",
+	"net": 305441741,
+	"defaultPort": "28555",
+	"rpcPort": "28556",
+	"coinbaseMaturity": 5,
+	"pubKeyHashAddrID": 80,
+	"scriptHashAddrID": 85,
+	"privateKeyID": 90,
+	"hdPrivateKeyID": "04a1b2c3",
+	"hdPublicKeyID": "04a1b2c4"
+}`)
+	defer os.Remove(name)
+	empty := writeConfig(t, "")
+	defer os.Remove(empty)
+	_ = os.Unsetenv(NetworkEnv)
+	_ = os.Unsetenv(NetworkFileEnv)
+	_ = os.Unsetenv(RPCServerEnv)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c := New(fs)
+	_ = fs.Parse([]string{"-configfile", empty, "-networkfile", name})
+	err := c.Load()
+	if err != nil {
+		t.Fatalf("failed to load config with network file: %s", err)
+	}
+
+	// The custom network is used, because no other network was chosen
+	params := c.Params
+	if params == nil || params.Name != "testprivnet" {
+		t.Fatalf("custom network not chosen; got %v", c.Network)
+	}
+	if params.DefaultPort != "28555" || params.CoinbaseMaturity != 5 || params.PubKeyHashAddrID != 80 {
+		t.Errorf("wrong params for custom network: %+v", params)
+	}
+	if params.GenesisHash != chaincfg.SimNetParams.GenesisHash {
+		t.Errorf("genesis of custom network not taken from its base network")
+	}
+	if c.RPCServer != "127.0.0.1:28556" {
+		t.Errorf("wrong default RPC server for custom network; got %s", c.RPCServer)
+	}
+
+	// The network's addresses can be decoded, and it can be found by name
+	addr, err := soterutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	decoded, err := soterutil.DecodeAddress(addr.EncodeAddress(), params)
+	if err != nil {
+		t.Errorf("failed to decode address of custom network: %s", err)
+	} else if !decoded.IsForNet(params) {
+		t.Errorf("decoded address isn't for the custom network")
+	}
+	found, err := wallet.NetworkParams("testprivnet")
+	if err != nil || found != params {
+		t.Errorf("custom network not registered with the wallet package")
+	}
+
+	// A network can't be defined twice
+	_, err = LoadNetwork(name)
+	if err == nil {
+		t.Errorf("loaded the same network twice")
+	}
+
+	// Without any network chosen, RequireNetwork fails
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	c = New(fs)
+	_ = fs.Parse([]string{"-configfile", empty})
+	err = c.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %s", err)
+	}
+	if c.RequireNetwork() != ErrNoNetwork {
+		t.Errorf("no error from RequireNetwork without a network")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/snacl"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// The version of the keyring file format written by Keyring.Save
+	keyringVersion = 1
+
+	// KeyringFileEnv is the environment variable that a keyring file other than the default one can be given in
+	KeyringFileEnv = "SOTER_KEYRING"
+	// KeyringPassEnv is the environment variable that the keyring's passphrase can be given in
+	KeyringPassEnv = "SOTER_KEYRING_PASS"
+)
+
+// Keyring is a file of named secrets, such as wallet and RPC passphrases, encrypted with a single passphrase
+type Keyring struct {
+	name    string
+	key     *snacl.SecretKey
+	entries map[string]string
+}
+
+// keyringFile is the format of a keyring file. The entries are encrypted with a key derived from the keyring
+// passphrase.
+type keyringFile struct {
+	Version int    `json:"version"`
+	Key     []byte `json:"key"`
+	Data    []byte `json:"data"`
+}
+
+// DefaultKeyringPath returns the keyring file named by the SOTER_KEYRING environment variable, or the default
+// keyring file in the sotertools app data directory.
+func DefaultKeyringPath() string {
+	name, ok := os.LookupEnv(KeyringFileEnv)
+	if ok && len(name) > 0 {
+		return name
+	}
+
+	return filepath.Join(soterutil.AppDataDir("sotertools", false), "keyring")
+}
+
+// OpenKeyring opens the named keyring file, decrypting it with the passphrase. If the file doesn't exist, an empty
+// keyring is returned, which is encrypted with the passphrase when it's saved.
+func OpenKeyring(name, pass string) (*Keyring, error) {
+	passBytes := []byte(pass)
+
+	content, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		key, err := snacl.NewSecretKey(&passBytes, snacl.DefaultN, snacl.DefaultR, snacl.DefaultP)
+		if err != nil {
+			return nil, err
+		}
+
+		k := Keyring{
+			name:    name,
+			key:     key,
+			entries: make(map[string]string),
+		}
+		return &k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f keyringFile
+	err = json.Unmarshal(content, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring %s: %s", name, err)
+	}
+	if f.Version != keyringVersion {
+		return nil, fmt.Errorf("unsupported keyring version %d in %s", f.Version, name)
+	}
+
+	var key snacl.SecretKey
+	err = key.Unmarshal(f.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring key from %s: %s", name, err)
+	}
+
+	err = key.DeriveKey(&passBytes)
+	if err == snacl.ErrInvalidPassword {
+		return nil, fmt.Errorf("wrong keyring passphrase for %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := key.Decrypt(f.Data)
+	if err != nil {
+		key.Zero()
+		return nil, fmt.Errorf("failed to decrypt keyring %s: %s", name, err)
+	}
+
+	k := Keyring{
+		name:    name,
+		key:     &key,
+		entries: make(map[string]string),
+	}
+	err = json.Unmarshal(plain, &k.entries)
+	if err != nil {
+		key.Zero()
+		return nil, fmt.Errorf("failed to read keyring %s: %s", name, err)
+	}
+
+	return &k, nil
+}
+
+// Get returns the secret of the keyring entry
+func (k *Keyring) Get(entry string) (string, error) {
+	secret, ok := k.entries[entry]
+	if !ok {
+		return "", fmt.Errorf("no entry %s in keyring %s", entry, k.name)
+	}
+
+	return secret, nil
+}
+
+// Set sets the secret of the keyring entry. The keyring needs to be saved for the change to be kept.
+func (k *Keyring) Set(entry, secret string) {
+	k.entries[entry] = secret
+}
+
+// Delete removes the keyring entry. The keyring needs to be saved for the change to be kept.
+func (k *Keyring) Delete(entry string) error {
+	_, ok := k.entries[entry]
+	if !ok {
+		return fmt.Errorf("no entry %s in keyring %s", entry, k.name)
+	}
+
+	delete(k.entries, entry)
+	return nil
+}
+
+// Entries returns the names of the keyring's entries, sorted
+func (k *Keyring) Entries() []string {
+	names := make([]string, 0, len(k.entries))
+	for name := range k.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Save writes the keyring to its file. The file is replaced in a single rename, so that an interrupted save doesn't
+// leave a partly-written keyring.
+func (k *Keyring) Save() error {
+	plain, err := json.Marshal(k.entries)
+	if err != nil {
+		return err
+	}
+
+	data, err := k.key.Encrypt(plain)
+	if err != nil {
+		return err
+	}
+
+	f := keyringFile{
+		Version: keyringVersion,
+		Key:     k.key.Marshal(),
+		Data:    data,
+	}
+	content, err := json.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	dir, _ := filepath.Split(k.name)
+	if len(dir) > 0 {
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpName := k.name + ".tmp"
+	err = ioutil.WriteFile(tmpName, content, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, k.name)
+}
+
+// Close clears the keyring's key and secrets from memory
+func (k *Keyring) Close() {
+	k.key.Zero()
+	k.entries = nil
+}
+
+// KeyringPassphrase returns the keyring's passphrase, from the SOTER_KEYRING_PASS environment variable or a prompt
+func KeyringPassphrase() (string, error) {
+	pass, ok := os.LookupEnv(KeyringPassEnv)
+	if ok {
+		return pass, nil
+	}
+
+	return Prompt("Keyring passphrase")
+}
+
+// ReadKeyringEntry returns the secret of the entry in the default keyring
+func ReadKeyringEntry(entry string) (string, error) {
+	pass, err := KeyringPassphrase()
+	if err != nil {
+		return "", err
+	}
+
+	k, err := OpenKeyring(DefaultKeyringPath(), pass)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+
+	return k.Get(entry)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sotertools_keyring")
+	if err != nil {
+		t.Fatalf("failed to create keyring dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "keyring")
+
+	k, err := OpenKeyring(name, "secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %s", err)
+	}
+	k.Set("wallet", "walletpass")
+	k.Set("rpc", "rpcpass")
+	err = k.Save()
+	if err != nil {
+		t.Fatalf("failed to save keyring: %s", err)
+	}
+	k.Close()
+
+	_, err = OpenKeyring(name, "wrong")
+	if err == nil {
+		t.Errorf("opened keyring with the wrong passphrase")
+	}
+
+	k, err = OpenKeyring(name, "secret")
+	if err != nil {
+		t.Fatalf("failed to open keyring: %s", err)
+	}
+	entries := k.Entries()
+	if len(entries) != 2 || entries[0] != "rpc" || entries[1] != "wallet" {
+		t.Errorf("wrong keyring entries %v", entries)
+	}
+
+	err = k.Delete("rpc")
+	if err != nil {
+		t.Errorf("failed to delete keyring entry: %s", err)
+	}
+	err = k.Save()
+	if err != nil {
+		t.Fatalf("failed to save keyring: %s", err)
+	}
+	k.Close()
+
+	// Entries can be read from the default keyring, named by the environment
+	_ = os.Setenv(KeyringFileEnv, name)
+	defer os.Unsetenv(KeyringFileEnv)
+	_ = os.Setenv(KeyringPassEnv, "secret")
+	defer os.Unsetenv(KeyringPassEnv)
+
+	secret, err := ReadKeyringEntry("wallet")
+	if err != nil {
+		t.Fatalf("failed to read keyring entry: %s", err)
+	}
+	if secret != "walletpass" {
+		t.Errorf("wrong keyring secret; got %q, want %q", secret, "walletpass")
+	}
+
+	_, err = ReadKeyringEntry("rpc")
+	if err == nil {
+		t.Errorf("read deleted keyring entry")
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package credentials
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The environment variables that passphrases are read from, when no other source is given for them
+const (
+	PrivPassEnv     = "SOTER_PRIVPASS"
+	PubPassEnv      = "SOTER_PUBPASS"
+	RPCPassEnv      = "SOTER_RPCPASS"
+	RPCProxyPassEnv = "SOTER_RPCPROXYPASS"
+	BackupPassEnv   = "SOTER_BACKUPPASS"
+	NewPrivPassEnv  = "SOTER_NEWPRIVPASS"
+	NewPubPassEnv   = "SOTER_NEWPUBPASS"
+	SecretEnv       = "SOTER_SECRET"
+)
+
+// Passphrase is a passphrase given to a command. It can be read from an interactive prompt, an environment variable,
+// a file, a file descriptor or the keyring. Giving the passphrase itself as a flag value is still supported as an
+// insecure fallback, because the value is visible to other users in the process list, and is kept in shell history.
+type Passphrase struct {
+	fs   *flag.FlagSet
+	name string
+	desc string
+	env  string
+
+	// The value of the insecure flag, and the source given by the -<name>from flag
+	value string
+	from  string
+
+	// The source used when the passphrase isn't given by a flag or the environment variable, for example from a
+	// config file
+	defaultFrom string
+
+	// Whether a prompt asks for the passphrase twice, to catch typos in new passphrases
+	confirm bool
+
+	read   bool
+	result string
+}
+
+// NewPassphrase defines the flags of a passphrase on the flag set: -<name> for giving the passphrase itself, and
+// -<name>from for giving a source to read it from. If neither flag is given, the passphrase is read from the
+// environment variable if it's set, otherwise from a prompt if stdin is a terminal.
+func NewPassphrase(fs *flag.FlagSet, name, desc, env string) *Passphrase {
+	p := Passphrase{
+		fs:   fs,
+		name: name,
+		desc: desc,
+		env:  env,
+	}
+
+	fs.StringVar(&p.value, name, "",
+		fmt.Sprintf("%s. INSECURE: visible in the process list and shell history, use -%sfrom instead", desc, name))
+	fs.StringVar(&p.from, name+"from", "",
+		fmt.Sprintf("Read -%s from prompt, env:NAME, file:PATH, fd:N or keyring:ENTRY (default $%s if set, otherwise prompt)", name, env))
+
+	return &p
+}
+
+// Name returns the name of the passphrase's flag
+func (p *Passphrase) Name() string {
+	return p.name
+}
+
+// Env returns the name of the passphrase's environment variable
+func (p *Passphrase) Env() string {
+	return p.env
+}
+
+// Confirm makes a prompt for the passphrase ask for it twice. It's meant for new passphrases.
+func (p *Passphrase) Confirm() {
+	p.confirm = true
+}
+
+// DefaultFrom sets the source the passphrase is read from, when it isn't given by a flag or its environment variable
+func (p *Passphrase) DefaultFrom(source string) {
+	p.defaultFrom = source
+}
+
+// flagGiven returns true if the named flag was given on the command line
+func (p *Passphrase) flagGiven(name string) bool {
+	given := false
+	p.fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+
+	return given
+}
+
+// Given returns true if the passphrase was given by a flag or its environment variable. An empty passphrase can be
+// given, so this is different from the passphrase being empty.
+func (p *Passphrase) Given() bool {
+	if p.flagGiven(p.name) || p.flagGiven(p.name+"from") {
+		return true
+	}
+
+	_, ok := os.LookupEnv(p.env)
+	return ok
+}
+
+// Get returns the passphrase. The first of these sources that's given is used: the insecure flag, the -<name>from
+// source, the environment variable, the default source, and a prompt if stdin is a terminal. If none of them are
+// available, an empty passphrase is returned.
+func (p *Passphrase) Get() (string, error) {
+	if p.read {
+		return p.result, nil
+	}
+
+	var value string
+	var err error
+	switch {
+	case p.flagGiven(p.name):
+		fmt.Fprintf(os.Stderr, "WARNING: -%s can be seen by other users and is kept in shell history; use -%sfrom instead\n",
+			p.name, p.name)
+		value = p.value
+	case len(p.from) > 0:
+		value, err = p.readFrom(p.from)
+	default:
+		var ok bool
+		value, ok = os.LookupEnv(p.env)
+		switch {
+		case ok:
+		case len(p.defaultFrom) > 0:
+			value, err = p.readFrom(p.defaultFrom)
+		case IsTerminal():
+			value, err = p.prompt()
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to read -%s: %s", p.name, err)
+	}
+
+	p.read = true
+	p.result = value
+	return value, nil
+}
+
+// prompt asks for the passphrase, twice if it needs to be confirmed
+func (p *Passphrase) prompt() (string, error) {
+	value, err := Prompt(p.desc)
+	if err != nil || !p.confirm {
+		return value, err
+	}
+
+	again, err := Prompt(p.desc + " (again)")
+	if err != nil {
+		return "", err
+	}
+	if again != value {
+		return "", fmt.Errorf("passphrases don't match")
+	}
+
+	return value, nil
+}
+
+// readFrom reads the passphrase from a source
+func (p *Passphrase) readFrom(source string) (string, error) {
+	kind, arg := source, ""
+	i := strings.Index(source, ":")
+	if i >= 0 {
+		kind, arg = source[:i], source[i+1:]
+	}
+
+	switch kind {
+	case "prompt":
+		return p.prompt()
+	case "env":
+		value, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return value, nil
+	case "file":
+		b, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return "", err
+		}
+		return firstLine(string(b)), nil
+	case "fd":
+		fd, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid file descriptor %s", arg)
+		}
+		f := os.NewFile(uintptr(fd), "fd"+arg)
+		if f == nil {
+			return "", fmt.Errorf("invalid file descriptor %s", arg)
+		}
+		defer f.Close()
+
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return firstLine(line), nil
+	case "keyring":
+		return ReadKeyringEntry(arg)
+	}
+
+	return "", fmt.Errorf("unknown passphrase source %s", source)
+}
+
+// firstLine returns the first line of the text, without its line ending
+func firstLine(text string) string {
+	i := strings.IndexAny(text, "\r\n")
+	if i >= 0 {
+		return text[:i]
+	}
+
+	return text
+}
+
+// IsTerminal returns true if stdin is a terminal, that a passphrase can be prompted for
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Prompt asks for a passphrase on the terminal, without echoing it
+func Prompt(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", fmt.Errorf("can't prompt for %s, because stdin is not a terminal", label)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+	b, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package credentials
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPassphraseSources(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "sotertools_pass-*")
+	if err != nil {
+		t.Fatalf("failed to create passphrase file: %s", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	_, _ = tmpfile.WriteString("fromfile\nignored\n")
+	_ = tmpfile.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %s", err)
+	}
+	_, _ = w.WriteString("fromfd\n")
+	_ = w.Close()
+
+	env := "SOTERTOOLS_TEST_PASS"
+	_ = os.Setenv(env, "fromenv")
+	defer os.Unsetenv(env)
+
+	tests := []struct {
+		args  []string
+		want  string
+		given bool
+	}{
+		{[]string{"-pass", "fromflag"}, "fromflag", true},
+		{[]string{"-pass", ""}, "", true},
+		{[]string{"-passfrom", "env:" + env}, "fromenv", true},
+		{[]string{"-passfrom", "file:" + tmpfile.Name()}, "fromfile", true},
+		{[]string{"-passfrom", fmt.Sprintf("fd:%d", r.Fd())}, "fromfd", true},
+		{[]string{}, "fromenv", true},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		p := NewPassphrase(fs, "pass", "Test passphrase", env)
+		err := fs.Parse(test.args)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %s", test.args, err)
+		}
+
+		if p.Given() != test.given {
+			t.Errorf("wrong Given for %v; got %v, want %v", test.args, p.Given(), test.given)
+		}
+
+		got, err := p.Get()
+		if err != nil {
+			t.Errorf("failed to get passphrase for %v: %s", test.args, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("wrong passphrase for %v; got %q, want %q", test.args, got, test.want)
+		}
+	}
+
+	// Sources that can't be read are errors
+	for _, source := range []string{"env:SOTERTOOLS_TEST_UNSET", "file:/nonexistent", "fd:x", "unknown:x"} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		p := NewPassphrase(fs, "pass", "Test passphrase", env)
+		_ = fs.Parse([]string{"-passfrom", source})
+
+		_, err := p.Get()
+		if err == nil {
+			t.Errorf("read passphrase from %s", source)
+		}
+	}
+
+	// Without any source, the passphrase isn't given
+	_ = os.Unsetenv(env)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	p := NewPassphrase(fs, "pass", "Test passphrase", env)
+	_ = fs.Parse([]string{})
+	if p.Given() {
+		t.Errorf("passphrase is given without any source")
+	}
+
+	// The default source is used when the passphrase isn't given, but not over the flags
+	p.DefaultFrom("file:" + tmpfile.Name())
+	got, err := p.Get()
+	if err != nil {
+		t.Fatalf("failed to get passphrase from default source: %s", err)
+	}
+	if got != "fromfile" {
+		t.Errorf("wrong passphrase from default source; got %q, want %q", got, "fromfile")
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	p = NewPassphrase(fs, "pass", "Test passphrase", env)
+	_ = fs.Parse([]string{"-pass", "fromflag"})
+	p.DefaultFrom("file:" + tmpfile.Name())
+	got, err = p.Get()
+	if err != nil {
+		t.Fatalf("failed to get passphrase: %s", err)
+	}
+	if got != "fromflag" {
+		t.Errorf("default source used over flag; got %q, want %q", got, "fromflag")
+	}
+}
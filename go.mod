@@ -1,6 +1,6 @@
 module github.com/soteria-dag/sotertools
 
-go 1.12
+go 1.13
 
 require (
 	github.com/Qitmeer/qitmeer-lib v0.0.0-20190929044832-b10740b316a8 // indirect
@@ -11,5 +11,6 @@ require (
 	github.com/soteria-dag/soterd v0.0.0-20191101002720-80c48f0843ed
 	github.com/soteria-dag/soterwallet v0.0.0-20191101003144-4a67c726065f
 	github.com/wcharczuk/go-chart v2.0.1+incompatible // indirect
+	golang.org/x/crypto v0.0.0-20190621222207-cc06ce4a13d4
 	golang.org/x/image v0.0.0-20190910094157-69e4b8554b2a // indirect
 )
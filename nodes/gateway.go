@@ -0,0 +1,197 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package nodes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options are the settings of the connections to the nodes that rpcclient doesn't support itself
+type Options struct {
+	// Timeout is the longest time to wait for a node to accept a connection, or to start answering a request. Zero
+	// means no timeout. A websocket to a node that doesn't answer in time is closed, which fails the requests that
+	// were sent on it.
+	Timeout time.Duration
+	// InsecureSkipVerify accepts any TLS certificate from the nodes. It should only be used on test networks.
+	InsecureSkipVerify bool
+	// Proxy is the address of a SOCKS5 proxy to connect to the nodes through, and ProxyUser and ProxyPass are its
+	// credentials if it needs them.
+	Proxy     string
+	ProxyUser string
+	ProxyPass string
+}
+
+// gateway is a local HTTP server that forwards the RPC requests and websocket connections of rpcclient to a node.
+// rpcclient connects to the gateway without TLS, and the gateway connects to the node with the Options, so that they
+// work the same for both websockets and HTTP POST requests.
+type gateway struct {
+	listener net.Listener
+	server   *http.Server
+	// timeouts is the number of websockets that were closed because the node didn't answer in time. It's accessed
+	// atomically.
+	timeouts uint64
+}
+
+// watchdogConn is the node's side of a websocket through the gateway. The requests on a websocket share one
+// connection, so the transport's timeouts don't apply to them. Instead, the connection is closed if the node doesn't
+// send anything within the timeout of a request being sent to it, and rpcclient fails the requests that are waiting.
+type watchdogConn struct {
+	io.ReadWriteCloser
+	timeout time.Duration
+	// expired is called when the connection is closed for timing out
+	expired func()
+
+	mtx sync.Mutex
+	// timer runs while the node hasn't answered since a request was sent to it
+	timer *time.Timer
+}
+
+// Write sends a request to the node, and starts waiting for it to answer
+func (c *watchdogConn) Write(b []byte) (int, error) {
+	c.mtx.Lock()
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.timeout, func() {
+			c.expired()
+			_ = c.ReadWriteCloser.Close()
+		})
+	}
+	c.mtx.Unlock()
+
+	return c.ReadWriteCloser.Write(b)
+}
+
+// Read receives from the node, which means it's still answering
+func (c *watchdogConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	if n > 0 {
+		c.mtx.Lock()
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+		c.mtx.Unlock()
+	}
+
+	return n, err
+}
+
+// Close closes the connection, and stops waiting for the node
+func (c *watchdogConn) Close() error {
+	c.mtx.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mtx.Unlock()
+
+	return c.ReadWriteCloser.Close()
+}
+
+// newGateway starts a gateway to the node of the config
+func newGateway(config *rpcclient.ConnConfig, opts *Options) (*gateway, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if len(config.Certificates) > 0 {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(config.Certificates)
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := net.Dialer{Timeout: opts.Timeout}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+	}
+	if len(opts.Proxy) > 0 {
+		proxy := url.URL{Scheme: "socks5", Host: opts.Proxy}
+		if len(opts.ProxyUser) > 0 {
+			proxy.User = url.UserPassword(opts.ProxyUser, opts.ProxyPass)
+		}
+		transport.Proxy = http.ProxyURL(&proxy)
+	}
+
+	g := gateway{}
+	target := url.URL{Scheme: "https", Host: config.Host}
+	if config.DisableTLS {
+		target.Scheme = "http"
+	}
+	rp := httputil.NewSingleHostReverseProxy(&target)
+	rp.Transport = transport
+	// rpcclient includes the response in its error, so the reason a request failed is given instead of logged
+	rp.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+	rp.ModifyResponse = func(res *http.Response) error {
+		if opts.Timeout <= 0 || res.StatusCode != http.StatusSwitchingProtocols {
+			return nil
+		}
+		conn, ok := res.Body.(io.ReadWriteCloser)
+		if ok {
+			res.Body = &watchdogConn{
+				ReadWriteCloser: conn,
+				timeout:         opts.Timeout,
+				expired: func() {
+					atomic.AddUint64(&g.timeouts, 1)
+				},
+			}
+		}
+		return nil
+	}
+	director := rp.Director
+	rp.Director = func(r *http.Request) {
+		director(r)
+		// Reverse proxies in front of the node can route requests by their host
+		r.Host = target.Host
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	g.listener = listener
+	g.server = &http.Server{Handler: rp}
+	go func() {
+		_ = g.server.Serve(listener)
+	}()
+
+	return &g, nil
+}
+
+// connConfig returns the config that rpcclient connects to the gateway with
+func (g *gateway) connConfig(config rpcclient.ConnConfig) rpcclient.ConnConfig {
+	config.Host = g.listener.Addr().String()
+	config.DisableTLS = true
+	config.Certificates = nil
+	config.Proxy = ""
+	config.ProxyUser = ""
+	config.ProxyPass = ""
+
+	return config
+}
+
+// timedOut returns the number of websockets that were closed because the node didn't answer in time
+func (g *gateway) timedOut() uint64 {
+	return atomic.LoadUint64(&g.timeouts)
+}
+
+// close stops the gateway
+func (g *gateway) close() {
+	_ = g.server.Close()
+}
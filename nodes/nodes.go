@@ -0,0 +1,358 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package nodes
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Health is the result of a node's last health check
+type Health struct {
+	Server string
+	// Height is the highest height of the node's dag tips, and Tips is the number of tips
+	Height int32
+	Tips   int
+	Peers  int64
+	// Err is the reason the node failed its health check, or nil if it's healthy
+	Err     error
+	Checked time.Time
+}
+
+// OK returns true if the node passed its health check
+func (h *Health) OK() bool {
+	return !h.Checked.IsZero() && h.Err == nil
+}
+
+// node is a soterd node's RPC server, and the connection to it
+type node struct {
+	mtx     sync.Mutex
+	config  rpcclient.ConnConfig
+	opts    *Options
+	gateway *gateway
+	client  *rpcclient.Client
+	health  Health
+}
+
+// check connects to the node if it isn't connected, and checks its health
+func (n *node) check() Health {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.health = Health{
+		Server:  n.config.Host,
+		Checked: time.Now(),
+	}
+
+	client, err := n.connect()
+	if err != nil {
+		n.health.Err = err
+		return n.health
+	}
+
+	tips, err := client.GetDAGTips()
+	if err == nil {
+		n.health.Height = tips.MaxHeight
+		n.health.Tips = len(tips.Tips)
+		n.health.Peers, err = client.GetConnectionCount()
+	}
+	if err != nil {
+		n.health.Err = err
+		n.disconnect()
+	}
+
+	return n.health
+}
+
+// connect returns the client of the node, connecting to it if it isn't connected. The node's mtx must be held.
+func (n *node) connect() (*rpcclient.Client, error) {
+	if n.client != nil && n.client.Disconnected() {
+		n.disconnect()
+	}
+	if n.gateway == nil {
+		g, err := newGateway(&n.config, n.opts)
+		if err != nil {
+			return nil, err
+		}
+		n.gateway = g
+	}
+	if n.client == nil {
+		config := n.gateway.connConfig(n.config)
+		client, err := rpcclient.New(&config, nil)
+		if err != nil {
+			return nil, err
+		}
+		n.client = client
+	}
+
+	return n.client, nil
+}
+
+// disconnect shuts down the connection to the node, so that the next check reconnects. The node's mtx must be held.
+func (n *node) disconnect() {
+	if n.client == nil {
+		return
+	}
+
+	n.client.Shutdown()
+	n.client = nil
+}
+
+// timedOut returns the number of websockets to the node that were closed because it didn't answer in time
+func (n *node) timedOut() uint64 {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.gateway == nil {
+		return 0
+	}
+	return n.gateway.timedOut()
+}
+
+// Manager holds connections to several soterd nodes, and chooses a healthy one to use. The node with the highest dag
+// tips is preferred, and then the one with the most peers, so that the most up to date view of the dag is used.
+type Manager struct {
+	mtx     sync.Mutex
+	nodes   []*node
+	current int
+	checked bool
+}
+
+// New returns a Manager of the nodes with the connection configs, which are connected to with the opts. Nothing is
+// connected to until the nodes are checked, or a client is asked for.
+func New(configs []rpcclient.ConnConfig, opts Options) *Manager {
+	m := Manager{current: -1}
+	for _, config := range configs {
+		m.nodes = append(m.nodes, &node{config: config, opts: &opts})
+	}
+
+	return &m
+}
+
+// best returns the index of the best healthy node, or -1 if none of them are healthy. The current node is kept if no
+// other node is better than it, so that the nodes aren't switched between needlessly.
+func best(health []Health, current int) int {
+	chosen := -1
+	if current >= 0 && current < len(health) && health[current].OK() {
+		chosen = current
+	}
+
+	for i, h := range health {
+		if !h.OK() {
+			continue
+		}
+		if chosen < 0 || h.Height > health[chosen].Height ||
+			(h.Height == health[chosen].Height && h.Peers > health[chosen].Peers) {
+			chosen = i
+		}
+	}
+
+	return chosen
+}
+
+// Check checks the health of all nodes, reconnecting to the ones that were disconnected, and chooses the best
+// healthy node to use. It returns the health of each node.
+func (m *Manager) Check() []Health {
+	health := make([]Health, len(m.nodes))
+	var wg sync.WaitGroup
+	for i, n := range m.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			health[i] = n.check()
+		}(i, n)
+	}
+	wg.Wait()
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.current = best(health, m.current)
+	m.checked = true
+
+	return health
+}
+
+// Health returns the result of each node's last health check
+func (m *Manager) Health() []Health {
+	health := make([]Health, len(m.nodes))
+	for i, n := range m.nodes {
+		n.mtx.Lock()
+		health[i] = n.health
+		n.mtx.Unlock()
+	}
+
+	return health
+}
+
+// ErrNoHealthyNode means none of the soterd nodes is healthy, so a request can't be made to any of them
+var ErrNoHealthyNode = errors.New("No healthy soterd node")
+
+// errNoHealthyNode returns the error for none of the nodes being healthy
+func errNoHealthyNode(health []Health) error {
+	reasons := make([]string, 0, len(health))
+	for _, h := range health {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", h.Server, h.Err))
+	}
+
+	return fmt.Errorf("%w (%s)", ErrNoHealthyNode, strings.Join(reasons, "; "))
+}
+
+// choose returns the index and client of the node to use, skipping the nodes in failed. The nodes are checked first
+// if they haven't been, or if the chosen node has failed.
+func (m *Manager) choose(failed map[int]bool) (int, *rpcclient.Client, error) {
+	m.mtx.Lock()
+	i, checked := m.current, m.checked
+	m.mtx.Unlock()
+
+	if !checked || i < 0 || failed[i] {
+		health := m.Check()
+		for j := range failed {
+			health[j].Err = fmt.Errorf("request failed")
+		}
+
+		m.mtx.Lock()
+		m.current = best(health, m.current)
+		i = m.current
+		m.mtx.Unlock()
+
+		if i < 0 {
+			return -1, nil, errNoHealthyNode(m.Health())
+		}
+	}
+
+	n := m.nodes[i]
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.client == nil {
+		return i, nil, fmt.Errorf("Not connected to %s", n.config.Host)
+	}
+
+	return i, n.client, nil
+}
+
+// Client returns the client of the best healthy node
+func (m *Manager) Client() (*rpcclient.Client, error) {
+	_, client, err := m.choose(nil)
+	return client, err
+}
+
+// Server returns the RPC server of the node in use, or "" if no node is healthy
+func (m *Manager) Server() string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.current < 0 {
+		return ""
+	}
+	return m.nodes[m.current].config.Host
+}
+
+// Do calls fn with the client of the best healthy node. If fn fails and the node is no longer healthy, or it didn't
+// answer within the Timeout, fn is tried again with the next best node, until it succeeds or there are no healthy nodes
+// left. fn should only be used with requests that are safe to repeat.
+func (m *Manager) Do(fn func(client *rpcclient.Client) error) error {
+	failed := make(map[int]bool)
+	var lastErr error
+	for {
+		i, client, err := m.choose(failed)
+		if err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		n := m.nodes[i]
+		timeouts := n.timedOut()
+		err = fn(client)
+		if err == nil {
+			return nil
+		}
+
+		// A node that stopped answering may still pass its health check, but it's not used for the request again
+		if n.timedOut() > timeouts {
+			failed[i] = true
+			lastErr = fmt.Errorf("%s didn't answer within %s: %w", n.config.Host, n.opts.Timeout, err)
+			continue
+		}
+
+		// An error from a healthy node is the request's own error, rather than a reason to fail over
+		health := n.check()
+		if health.OK() {
+			return err
+		}
+		failed[i] = true
+		lastErr = err
+	}
+}
+
+// Servers returns the RPC servers of the nodes
+func (m *Manager) Servers() []string {
+	servers := make([]string, 0, len(m.nodes))
+	for _, n := range m.nodes {
+		servers = append(servers, n.config.Host)
+	}
+
+	return servers
+}
+
+// ForEach calls fn with the client of every node at once, whether or not it's healthy, and returns the error from
+// each node. It's used to compare what the nodes report.
+func (m *Manager) ForEach(fn func(i int, client *rpcclient.Client) error) []error {
+	errs := make([]error, len(m.nodes))
+	var wg sync.WaitGroup
+	for i, n := range m.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+
+			n.mtx.Lock()
+			client, err := n.connect()
+			n.mtx.Unlock()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			errs[i] = fn(i, client)
+		}(i, n)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// Monitor checks the nodes every interval until quit is closed, so that a node that went down is switched from, and
+// reconnected to when it's back up.
+func (m *Manager) Monitor(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// Shutdown disconnects from all nodes
+func (m *Manager) Shutdown() {
+	for _, n := range m.nodes {
+		n.mtx.Lock()
+		n.disconnect()
+		if n.gateway != nil {
+			n.gateway.close()
+			n.gateway = nil
+		}
+		n.mtx.Unlock()
+	}
+}
@@ -0,0 +1,415 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package nodes
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRequest is the part of an RPC request that the fake nodes use
+type fakeRequest struct {
+	ID     interface{} `json:"id"`
+	Method string      `json:"method"`
+}
+
+// fakeReply returns the reply to an RPC request of a health check, or getblockcount, like a soterd node with dag tips
+// at the height
+func fakeReply(req fakeRequest, height int32) map[string]interface{} {
+	var result interface{}
+	switch req.Method {
+	case "getdagtips":
+		result = map[string]interface{}{"tips": []string{"00"}, "maxheight": height}
+	case "getconnectioncount":
+		result = 1
+	case "getblockcount":
+		result = height
+	}
+
+	return map[string]interface{}{"result": result, "error": nil, "id": req.ID}
+}
+
+// fakeNode returns a server that answers the RPC requests of a health check, and getblockcount, like a soterd node
+// with dag tips at the height
+func fakeNode(height int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fakeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(fakeReply(req, height))
+	}))
+}
+
+// readFrame reads the payload and opcode of a masked websocket frame from a client
+func readFrame(r io.Reader) ([]byte, byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, 0, err
+	}
+
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, 0, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, 0, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return nil, 0, err
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return payload, head[0] & 0x0f, nil
+}
+
+// writeFrame writes the payload as an unmasked websocket text frame from a server
+func writeFrame(w io.Writer, payload []byte) error {
+	frame := []byte{0x81}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+// fakeWSNode returns a server that answers the same requests as fakeNode over a websocket, except for requests of the
+// stalled method, which it never answers
+func fakeWSNode(height int32, stalled string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n"+
+			"Connection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+		if err != nil {
+			return
+		}
+
+		reader := bufio.NewReader(buf)
+		for {
+			payload, opcode, err := readFrame(reader)
+			// Opcode 8 closes the websocket, and only text frames hold requests
+			if err != nil || opcode == 8 {
+				return
+			}
+			var req fakeRequest
+			if opcode != 1 || json.Unmarshal(payload, &req) != nil || req.Method == stalled {
+				continue
+			}
+
+			reply, _ := json.Marshal(fakeReply(req, height))
+			if err := writeFrame(conn, reply); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+// fakeWSConfig returns the websocket connection config of the fake node
+func fakeWSConfig(s *httptest.Server) rpcclient.ConnConfig {
+	return rpcclient.ConnConfig{
+		Host:                 strings.TrimPrefix(s.URL, "http://"),
+		Endpoint:             "ws",
+		DisableTLS:           true,
+		DisableAutoReconnect: true,
+	}
+}
+
+// fakeConfig returns the connection config of the fake node
+func fakeConfig(s *httptest.Server) rpcclient.ConnConfig {
+	return rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(s.URL, "http://"),
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}
+}
+
+func TestBest(t *testing.T) {
+	now := time.Now()
+	down := fmt.Errorf("connection refused")
+
+	tests := []struct {
+		health  []Health
+		current int
+		want    int
+	}{
+		// The node with the highest tips is preferred, and then the one with the most peers
+		{[]Health{{Height: 10, Checked: now}, {Height: 12, Checked: now}}, -1, 1},
+		{[]Health{{Height: 12, Peers: 2, Checked: now}, {Height: 12, Peers: 5, Checked: now}}, -1, 1},
+		// Unhealthy and unchecked nodes aren't used
+		{[]Health{{Height: 10, Checked: now}, {Height: 12, Err: down, Checked: now}}, -1, 0},
+		{[]Health{{Height: 10, Checked: now}, {Height: 12}}, -1, 0},
+		{[]Health{{Err: down, Checked: now}, {Err: down, Checked: now}}, 0, -1},
+		// The current node is kept, unless another node is better or it's down
+		{[]Health{{Height: 12, Peers: 5, Checked: now}, {Height: 12, Peers: 5, Checked: now}}, 1, 1},
+		{[]Health{{Height: 12, Checked: now}, {Height: 12, Err: down, Checked: now}}, 1, 0},
+		{[]Health{{Height: 13, Checked: now}, {Height: 12, Checked: now}}, 1, 0},
+	}
+
+	for i, test := range tests {
+		got := best(test.health, test.current)
+		if got != test.want {
+			t.Errorf("test %d: wrong best node; got %d, want %d", i, got, test.want)
+		}
+	}
+}
+
+func TestNoHealthyNode(t *testing.T) {
+	// Nothing listens on these ports, so none of the nodes are healthy
+	m := New([]rpcclient.ConnConfig{
+		{Host: "127.0.0.1:1", Endpoint: "ws", DisableTLS: true, DisableAutoReconnect: true},
+		{Host: "127.0.0.1:2", Endpoint: "ws", DisableTLS: true, DisableAutoReconnect: true},
+	}, Options{})
+	defer m.Shutdown()
+
+	_, err := m.Client()
+	if err == nil {
+		t.Fatalf("got a client without a healthy node")
+	}
+	if !errors.Is(err, ErrNoHealthyNode) {
+		t.Errorf("error %v is not ErrNoHealthyNode", err)
+	}
+
+	calls := 0
+	err = m.Do(func(client *rpcclient.Client) error {
+		calls++
+		return nil
+	})
+	if err == nil || calls > 0 {
+		t.Errorf("Do called fn %d times without a healthy node; err %v", calls, err)
+	}
+
+	for _, h := range m.Health() {
+		if h.OK() {
+			t.Errorf("node %s is healthy", h.Server)
+		}
+	}
+	if len(m.Server()) > 0 {
+		t.Errorf("node %s is in use without being healthy", m.Server())
+	}
+}
+
+func TestFailover(t *testing.T) {
+	low := fakeNode(5)
+	defer low.Close()
+	high := fakeNode(9)
+	defer high.Close()
+
+	m := New([]rpcclient.ConnConfig{fakeConfig(low), fakeConfig(high)}, Options{})
+	defer m.Shutdown()
+
+	// The node with the highest tips is used
+	var count int64
+	err := m.Do(func(client *rpcclient.Client) error {
+		var err error
+		count, err = client.GetBlockCount()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to get block count: %s", err)
+	}
+	if count != 9 || m.Server() != fakeConfig(high).Host {
+		t.Errorf("wrong node used; got %s with count %d, want %s", m.Server(), count, fakeConfig(high).Host)
+	}
+
+	// When it goes down, the request is tried again with the other node
+	high.Close()
+	err = m.Do(func(client *rpcclient.Client) error {
+		var err error
+		count, err = client.GetBlockCount()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to get block count after failover: %s", err)
+	}
+	if count != 5 || m.Server() != fakeConfig(low).Host {
+		t.Errorf("wrong node used after failover; got %s with count %d, want %s", m.Server(), count, fakeConfig(low).Host)
+	}
+
+	for _, h := range m.Health() {
+		if h.Server == fakeConfig(high).Host && h.OK() {
+			t.Errorf("node %s is healthy after going down", h.Server)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	low := fakeNode(5)
+	defer low.Close()
+	high := fakeNode(9)
+	defer high.Close()
+	down := fakeNode(7)
+	down.Close()
+
+	m := New([]rpcclient.ConnConfig{fakeConfig(low), fakeConfig(high), fakeConfig(down)}, Options{})
+	defer m.Shutdown()
+
+	counts := make([]int64, len(m.Servers()))
+	errs := m.ForEach(func(i int, client *rpcclient.Client) error {
+		var err error
+		counts[i], err = client.GetBlockCount()
+		return err
+	})
+
+	if counts[0] != 5 || counts[1] != 9 {
+		t.Errorf("wrong block counts from nodes; got %v, want [5 9 0]", counts)
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("failed to get block count from healthy nodes: %v", errs)
+	}
+	if errs[2] == nil {
+		t.Errorf("got block count from node that's down")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fake := fakeNode(5)
+	defer fake.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	m := New([]rpcclient.ConnConfig{fakeConfig(slow)}, Options{Timeout: 100 * time.Millisecond})
+	defer m.Shutdown()
+
+	start := time.Now()
+	_, err := m.Client()
+	if err == nil {
+		t.Errorf("got a client from a node that's slower than the timeout")
+	}
+	if time.Since(start) >= time.Second {
+		t.Errorf("request wasn't timed out; took %s", time.Since(start))
+	}
+
+	stalled := fakeWSNode(9, "getblockcount")
+	defer stalled.Close()
+	low := fakeWSNode(5, "")
+	defer low.Close()
+
+	// Over a websocket the requests share one connection, so a request that isn't answered fails without waiting for
+	// the node forever
+	m = New([]rpcclient.ConnConfig{fakeWSConfig(stalled)}, Options{Timeout: 100 * time.Millisecond})
+	defer m.Shutdown()
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("failed to get a client: %s", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.GetBlockCount()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("got block count from a node that didn't answer")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("request wasn't timed out")
+	}
+
+	// Do tries the request again with another node, even though the node that didn't answer is still healthy
+	m = New([]rpcclient.ConnConfig{fakeWSConfig(stalled), fakeWSConfig(low)}, Options{Timeout: 100 * time.Millisecond})
+	defer m.Shutdown()
+
+	var count int64
+	start = time.Now()
+	err = m.Do(func(client *rpcclient.Client) error {
+		var err error
+		count, err = client.GetBlockCount()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to get block count after timeout: %s", err)
+	}
+	if count != 5 || m.Server() != fakeWSConfig(low).Host {
+		t.Errorf("wrong node used after timeout; got %s with count %d, want %s", m.Server(), count,
+			fakeWSConfig(low).Host)
+	}
+	if time.Since(start) >= time.Second {
+		t.Errorf("request wasn't timed out; took %s", time.Since(start))
+	}
+}
+
+func TestTLS(t *testing.T) {
+	fake := fakeNode(5)
+	defer fake.Close()
+	s := httptest.NewTLSServer(fake.Config.Handler)
+	defer s.Close()
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+
+	config := rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(s.URL, "https://"),
+		HTTPPostMode: true,
+	}
+	trusted := config
+	trusted.Certificates = cert
+
+	tests := []struct {
+		config rpcclient.ConnConfig
+		opts   Options
+		ok     bool
+	}{
+		// The node's certificate isn't signed by a known CA
+		{config, Options{}, false},
+		{trusted, Options{}, true},
+		{config, Options{InsecureSkipVerify: true}, true},
+	}
+
+	for i, test := range tests {
+		m := New([]rpcclient.ConnConfig{test.config}, test.opts)
+		_, err := m.Client()
+		m.Shutdown()
+		if test.ok && err != nil {
+			t.Errorf("test %d: failed to connect: %s", i, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("test %d: connected to node with untrusted certificate", i)
+		}
+	}
+}
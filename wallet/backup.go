@@ -0,0 +1,586 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/soterutil/hdkeychain"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterwallet/snacl"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// The version of the backup file format written by Backup.WriteFile
+const backupVersion = 1
+
+var (
+	// The wallet db bucket that the wallet's seed is stored in, encrypted with the wallet's private key
+	seedNamespaceKey = []byte("sotertoolsseed")
+	seedKey          = []byte("seed")
+
+	// The wallet db buckets managed by these tools, that are copied into backups as they are
+	backupNamespaceKeys = [][]byte{sentTxNamespaceKey, timeLockNamespaceKey}
+)
+
+// Backup is the content of a wallet, that's needed to rebuild it
+type Backup struct {
+	Network  string    `json:"network"`
+	Birthday time.Time `json:"birthday"`
+
+	// The seed that the wallet's keys are derived from. Wallets created before seeds were kept don't have one, and
+	// Keys holds the private keys of all of their addresses instead.
+	Seed     []byte          `json:"seed,omitempty"`
+	Accounts []BackupAccount `json:"accounts,omitempty"`
+
+	// WIF-encoded private keys of imported addresses
+	Keys []string `json:"keys,omitempty"`
+	// Imported redeem scripts, such as for multisig addresses
+	Scripts [][]byte `json:"scripts,omitempty"`
+
+	Buckets []BackupBucket `json:"buckets,omitempty"`
+}
+
+// BackupAccount is an account derived from the wallet's seed
+type BackupAccount struct {
+	Number       uint32 `json:"number"`
+	Name         string `json:"name"`
+	ExternalKeys uint32 `json:"externalKeys"`
+	InternalKeys uint32 `json:"internalKeys"`
+}
+
+// BackupBucket holds the content of a wallet db bucket managed by these tools, such as the sent transactions and
+// time-locked addresses.
+type BackupBucket struct {
+	Name  string       `json:"name"`
+	Items []BackupItem `json:"items"`
+}
+
+// BackupItem is a key and value of a BackupBucket
+type BackupItem struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// backupFile is the format of a backup file. The backup is encrypted with a key derived from the backup passphrase.
+type backupFile struct {
+	Version int    `json:"version"`
+	Key     []byte `json:"key"`
+	Data    []byte `json:"data"`
+}
+
+// putSeed stores the seed in the wallet db, encrypted with the wallet's private key
+func putSeed(tx walletdb.ReadWriteTx, pub, priv, seed []byte, params *chaincfg.Params) error {
+	addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+	manager, err := waddrmgr.Open(addrmgrNs, pub, params)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	err = manager.Unlock(addrmgrNs, priv)
+	if err != nil {
+		return err
+	}
+
+	enc, err := manager.Encrypt(waddrmgr.CKTPrivate, seed)
+	if err != nil {
+		return err
+	}
+
+	ns, err := tx.CreateTopLevelBucket(seedNamespaceKey)
+	if err != nil {
+		return err
+	}
+
+	return ns.Put(seedKey, enc)
+}
+
+// fetchSeed returns the wallet's seed, or nil if the wallet doesn't have one stored. The wallet must be unlocked.
+func fetchSeed(w *wallet.Wallet) ([]byte, error) {
+	var enc []byte
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(seedNamespaceKey)
+		if ns == nil {
+			return nil
+		}
+
+		enc = ns.Get(seedKey)
+		return nil
+	})
+	if err != nil || enc == nil {
+		return nil, err
+	}
+
+	return w.Manager.Decrypt(waddrmgr.CKTPrivate, enc)
+}
+
+// isBackupNamespace returns true if the wallet db bucket is one that's copied into backups
+func isBackupNamespace(name string) bool {
+	for _, key := range backupNamespaceKeys {
+		if string(key) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewBackup returns a backup of the wallet's seed, accounts, imported keys and scripts, and of the data these tools
+// store in it.
+func NewBackup(w *wallet.Wallet, privPass string) (*Backup, error) {
+	if w.ChainParams() == nil {
+		return nil, fmt.Errorf("the wallet's network params are needed to back it up")
+	}
+
+	err := unlock(w, privPass)
+	if err != nil {
+		return nil, err
+	}
+	defer lock(w)
+
+	seed, err := fetchSeed(w)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read wallet seed: %s", err)
+	}
+
+	b := Backup{
+		Network:  w.ChainParams().Name,
+		Birthday: w.Manager.Birthday(),
+		Seed:     seed,
+	}
+
+	manager, err := w.Manager.FetchScopedKeyManager(waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keys are exported after iterating over the addresses, because exporting needs the manager's lock, which is
+	// held while iterating.
+	var maddrs []waddrmgr.ManagedAddress
+	collect := func(maddr waddrmgr.ManagedAddress) error {
+		maddrs = append(maddrs, maddr)
+		return nil
+	}
+
+	// addKey adds the private key or script of an address to the backup
+	addKey := func(maddr waddrmgr.ManagedAddress) error {
+		switch a := maddr.(type) {
+		case waddrmgr.ManagedPubKeyAddress:
+			wif, err := a.ExportPrivKey()
+			if err != nil {
+				return fmt.Errorf("failed to export private key of address %s: %s", a.Address(), err)
+			}
+			b.Keys = append(b.Keys, wif.String())
+		case waddrmgr.ManagedScriptAddress:
+			script, err := a.Script()
+			if err != nil {
+				return fmt.Errorf("failed to export script of address %s: %s", a.Address(), err)
+			}
+			b.Scripts = append(b.Scripts, script)
+		}
+		return nil
+	}
+
+	err = walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
+
+		last, err := manager.LastAccount(addrmgrNs)
+		if err != nil {
+			return err
+		}
+
+		for account := uint32(0); account <= last; account++ {
+			if seed == nil {
+				// Without the seed the derived addresses can't be rebuilt, so their keys are kept instead
+				err = manager.ForEachAccountAddress(addrmgrNs, account, collect)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			props, err := manager.AccountProperties(addrmgrNs, account)
+			if err != nil {
+				return err
+			}
+
+			b.Accounts = append(b.Accounts, BackupAccount{
+				Number:       account,
+				Name:         props.AccountName,
+				ExternalKeys: props.ExternalKeyCount,
+				InternalKeys: props.InternalKeyCount,
+			})
+		}
+
+		err = manager.ForEachAccountAddress(addrmgrNs, waddrmgr.ImportedAddrAccount, collect)
+		if err != nil {
+			return err
+		}
+
+		for _, maddr := range maddrs {
+			err = addKey(maddr)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, name := range backupNamespaceKeys {
+			ns := tx.ReadBucket(name)
+			if ns == nil {
+				continue
+			}
+
+			bucket := BackupBucket{Name: string(name)}
+			err = ns.ForEach(func(k, v []byte) error {
+				item := BackupItem{
+					Key:   append([]byte{}, k...),
+					Value: append([]byte{}, v...),
+				}
+				bucket.Items = append(bucket.Items, item)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			b.Buckets = append(b.Buckets, bucket)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to back up wallet: %s", err)
+	}
+
+	return &b, nil
+}
+
+// WriteFile writes the Backup to the named file, encrypted with the backup passphrase
+func (b *Backup) WriteFile(name, backupPass string) error {
+	if len(backupPass) == 0 {
+		return fmt.Errorf("a backup passphrase is required")
+	}
+
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	pass := []byte(backupPass)
+	key, err := snacl.NewSecretKey(&pass, snacl.DefaultN, snacl.DefaultR, snacl.DefaultP)
+	if err != nil {
+		return err
+	}
+	defer key.Zero()
+
+	data, err := key.Encrypt(plain)
+	if err != nil {
+		return err
+	}
+
+	f := backupFile{
+		Version: backupVersion,
+		Key:     key.Marshal(),
+		Data:    data,
+	}
+	content, err := json.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, content, 0600)
+}
+
+// ReadBackup reads a Backup from the named file, decrypting it with the backup passphrase
+func ReadBackup(name, backupPass string) (*Backup, error) {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var f backupFile
+	err = json.Unmarshal(content, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup from %s: %s", name, err)
+	}
+	if f.Version != backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d in %s", f.Version, name)
+	}
+
+	var key snacl.SecretKey
+	err = key.Unmarshal(f.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup key from %s: %s", name, err)
+	}
+	defer key.Zero()
+
+	pass := []byte(backupPass)
+	err = key.DeriveKey(&pass)
+	if err == snacl.ErrInvalidPassword {
+		return nil, fmt.Errorf("wrong backup passphrase for %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := key.Decrypt(f.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup %s: %s", name, err)
+	}
+
+	var b Backup
+	err = json.Unmarshal(plain, &b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup from %s: %s", name, err)
+	}
+
+	return &b, nil
+}
+
+// RestoreWallet creates a new wallet file from the backup, and returns the opened wallet. The wallet's seed,
+// accounts, addresses, imported keys and scripts are restored, along with the data these tools store in it. If the
+// backup has no seed, a new seed is used and the backed up keys are imported.
+func RestoreWallet(name, privPass, pubPass string, b *Backup) (*wallet.Wallet, error) {
+	params, err := NetworkParams(b.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(name)
+	if err == nil {
+		return nil, fmt.Errorf("wallet %s already exists", name)
+	}
+
+	seed := b.Seed
+	if seed == nil {
+		seed, err = hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = createWallet(name, privPass, pubPass, seed, b.Birthday, params)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, fmt.Errorf("Failed to create wallet: %s", err)
+	}
+
+	w, err := OpenWallet(name, pubPass, params)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, err
+	}
+
+	err = restore(w, privPass, b)
+	if err != nil {
+		_ = w.Database().Close()
+		_ = os.Remove(name)
+		return nil, fmt.Errorf("Failed to restore wallet: %s", err)
+	}
+
+	return w, nil
+}
+
+// restore adds the accounts, addresses, keys, scripts and buckets of the backup to the newly-created wallet
+func restore(w *wallet.Wallet, privPass string, b *Backup) error {
+	err := unlock(w, privPass)
+	if err != nil {
+		return err
+	}
+	defer lock(w)
+
+	manager, err := w.Manager.FetchScopedKeyManager(waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		return err
+	}
+
+	// We don't know when imported keys were first used, so the whole dag is considered
+	bs := &waddrmgr.BlockStamp{
+		Hash:   *w.ChainParams().GenesisHash,
+		Height: 0,
+	}
+
+	return walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		var err error
+		for _, a := range b.Accounts {
+			if a.Number == 0 {
+				name, err := manager.AccountName(addrmgrNs, 0)
+				if err != nil {
+					return err
+				}
+				if name != a.Name {
+					err = manager.RenameAccount(addrmgrNs, 0, a.Name)
+					if err != nil {
+						return err
+					}
+				}
+			} else {
+				number, err := manager.NewAccount(addrmgrNs, a.Name)
+				if err != nil {
+					return err
+				}
+				if number != a.Number {
+					return fmt.Errorf("account %s was restored as number %d instead of %d", a.Name, number, a.Number)
+				}
+			}
+
+			if a.ExternalKeys > 0 {
+				_, err := manager.NextExternalAddresses(addrmgrNs, a.Number, a.ExternalKeys)
+				if err != nil {
+					return err
+				}
+			}
+			if a.InternalKeys > 0 {
+				_, err := manager.NextInternalAddresses(addrmgrNs, a.Number, a.InternalKeys)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, key := range b.Keys {
+			wif, err := soterutil.DecodeWIF(key)
+			if err != nil {
+				return fmt.Errorf("failed to decode private key: %s", err)
+			}
+
+			_, err = manager.ImportPrivateKey(addrmgrNs, wif, bs)
+			if err != nil && !waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+				return err
+			}
+		}
+
+		for _, script := range b.Scripts {
+			_, err := manager.ImportScript(addrmgrNs, script, bs)
+			if err != nil && !waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+				return err
+			}
+		}
+
+		for _, bucket := range b.Buckets {
+			if !isBackupNamespace(bucket.Name) {
+				return fmt.Errorf("unknown bucket %s in backup", bucket.Name)
+			}
+
+			ns := tx.ReadWriteBucket([]byte(bucket.Name))
+			if ns == nil {
+				ns, err = tx.CreateTopLevelBucket([]byte(bucket.Name))
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, item := range bucket.Items {
+				err = ns.Put(item.Key, item.Value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// RescanAddresses looks through the dag for outputs paying to addresses derived from the wallet's seed, and adds the
+// addresses to their accounts. For each account, addresses are checked until gap addresses in a row haven't been
+// used. The number of used addresses found is returned.
+func RescanAddresses(client *rpcclient.Client, w *wallet.Wallet, gap uint32) (int, error) {
+	params := w.ChainParams()
+
+	transactions, err := AllTransactions(client)
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[string]bool)
+	for _, info := range transactions {
+		for _, txOut := range info.Tx.TxOut {
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+			if err != nil {
+				continue
+			}
+
+			for _, addr := range addrs {
+				used[addr.EncodeAddress()] = true
+			}
+		}
+	}
+
+	manager, err := w.Manager.FetchScopedKeyManager(waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		return 0, err
+	}
+
+	found := 0
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		last, err := manager.LastAccount(addrmgrNs)
+		if err != nil {
+			return err
+		}
+
+		for account := uint32(0); account <= last; account++ {
+			for _, branch := range []uint32{waddrmgr.ExternalBranch, waddrmgr.InternalBranch} {
+				usedAddrs := make([]soterutil.Address, 0)
+				lastUsed := int64(-1)
+
+				for index := uint32(0); int64(index) <= lastUsed+int64(gap); index++ {
+					path := waddrmgr.DerivationPath{Account: account, Branch: branch, Index: index}
+					maddr, err := manager.DeriveFromKeyPath(addrmgrNs, path)
+					if err != nil {
+						return err
+					}
+
+					if used[maddr.Address().EncodeAddress()] {
+						lastUsed = int64(index)
+						usedAddrs = append(usedAddrs, maddr.Address())
+					}
+				}
+
+				if lastUsed < 0 {
+					continue
+				}
+
+				if branch == waddrmgr.ExternalBranch {
+					err = manager.ExtendExternalAddresses(addrmgrNs, account, uint32(lastUsed))
+				} else {
+					err = manager.ExtendInternalAddresses(addrmgrNs, account, uint32(lastUsed))
+				}
+				if err != nil {
+					return err
+				}
+
+				for _, addr := range usedAddrs {
+					err = manager.MarkUsed(addrmgrNs, addr)
+					if err != nil {
+						return err
+					}
+				}
+				found += len(usedAddrs)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to rescan wallet addresses: %s", err)
+	}
+
+	return found, nil
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/soterec"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"os"
+	"sort"
+	"testing"
+)
+
+// sortedAddresses returns the encoded addresses of the wallet, sorted
+func sortedAddresses(t *testing.T, w *wallet.Wallet) []string {
+	addresses, err := WalletAddresses(w)
+	if err != nil {
+		t.Fatalf("failed to get wallet addresses: %s", err)
+	}
+
+	encoded := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		encoded = append(encoded, addr.EncodeAddress())
+	}
+	sort.Strings(encoded)
+
+	return encoded
+}
+
+// testBackupRestore backs up the wallet, restores it to a new file, and checks that the restored wallet has the same
+// addresses, keys and time-locked addresses.
+func testBackupRestore(t *testing.T, w *wallet.Wallet, name string, imported soterutil.Address, wif string) {
+	b, err := NewBackup(w, "priv")
+	if err != nil {
+		t.Fatalf("failed to back up wallet: %s", err)
+	}
+
+	backupName := name + ".backup"
+	defer os.Remove(backupName)
+	err = b.WriteFile(backupName, "secret")
+	if err != nil {
+		t.Fatalf("failed to write backup: %s", err)
+	}
+
+	_, err = ReadBackup(backupName, "wrong")
+	if err == nil {
+		t.Errorf("read a backup with the wrong passphrase")
+	}
+
+	b, err = ReadBackup(backupName, "secret")
+	if err != nil {
+		t.Fatalf("failed to read backup: %s", err)
+	}
+
+	_, err = RestoreWallet(name, "priv", "pub", b)
+	if err == nil {
+		t.Errorf("restored a backup over an existing wallet")
+	}
+
+	restoredName := name + ".restored"
+	restored, err := RestoreWallet(restoredName, "newpriv", "newpub", b)
+	if err != nil {
+		t.Fatalf("failed to restore wallet: %s", err)
+	}
+	defer func() {
+		_ = restored.Database().Close()
+		_ = os.Remove(restoredName)
+	}()
+
+	want := sortedAddresses(t, w)
+	got := sortedAddresses(t, restored)
+	if len(got) != len(want) {
+		t.Fatalf("wrong addresses in restored wallet; got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("wrong addresses in restored wallet; got %v, want %v", got, want)
+		}
+	}
+
+	restoredWIF, err := ExportPrivateKey(restored, "newpriv", imported)
+	if err != nil {
+		t.Fatalf("failed to export private key from restored wallet: %s", err)
+	}
+	if restoredWIF != wif {
+		t.Errorf("wrong private key of %s in restored wallet", imported)
+	}
+
+	locks, err := TimeLockAddresses(restored)
+	if err != nil {
+		t.Fatalf("failed to get time-locked addresses: %s", err)
+	}
+	if len(locks) != 1 {
+		t.Errorf("wrong number of time-locked addresses in restored wallet; got %d, want 1", len(locks))
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	w, name, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	var owner soterutil.Address
+	for i := 0; i < 3; i++ {
+		addr, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+		if err != nil {
+			t.Fatalf("failed to create address: %s", err)
+		}
+		owner = addr
+	}
+
+	_, err := NewTimeLockAddress(w, owner, 1000)
+	if err != nil {
+		t.Fatalf("failed to create time-locked address: %s", err)
+	}
+
+	privKey, err := soterec.NewPrivateKey(soterec.S256())
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+	wif, err := soterutil.NewWIF(privKey, w.ChainParams(), true)
+	if err != nil {
+		t.Fatalf("failed to encode private key: %s", err)
+	}
+	imported, err := ImportPrivateKey(w, "priv", wif.String())
+	if err != nil {
+		t.Fatalf("failed to import private key: %s", err)
+	}
+
+	b, err := NewBackup(w, "priv")
+	if err != nil {
+		t.Fatalf("failed to back up wallet: %s", err)
+	}
+	if b.Seed == nil {
+		t.Errorf("no seed in backup of a new wallet")
+	}
+
+	testBackupRestore(t, w, name, imported, wif.String())
+
+	// Wallets created before seeds were kept are backed up with the private keys of their addresses
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket(seedNamespaceKey)
+	})
+	if err != nil {
+		t.Fatalf("failed to remove wallet seed: %s", err)
+	}
+
+	testBackupRestore(t, w, name, imported, wif.String())
+}
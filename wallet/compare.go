@@ -0,0 +1,116 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"sort"
+)
+
+// DAGView is a node's view of the dag: its tips, and the blocks at each height up to them
+type DAGView struct {
+	Tips      []string
+	MaxHeight int32
+	// Blocks are the heights of the node's blocks, by hash
+	Blocks map[chainhash.Hash]int32
+}
+
+// MissingBlock is a block that some nodes have, and others don't
+type MissingBlock struct {
+	Hash   chainhash.Hash
+	Height int32
+	// MissingFrom are the indexes of the views that don't have the block
+	MissingFrom []int
+}
+
+// GetDAGView returns the node's view of the dag
+func GetDAGView(client *rpcclient.Client) (*DAGView, error) {
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, err
+	}
+
+	v := DAGView{
+		Tips:      tips.Tips,
+		MaxHeight: tips.MaxHeight,
+		Blocks:    make(map[chainhash.Hash]int32),
+	}
+	sort.Strings(v.Tips)
+
+	for height := int32(0); height <= tips.MaxHeight; height++ {
+		hashes, err := client.GetBlockHash(int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hash := range hashes {
+			v.Blocks[*hash] = height
+		}
+	}
+
+	return &v, nil
+}
+
+// SameTips returns true if the views have the same tips
+func SameTips(views []*DAGView) bool {
+	for _, v := range views[1:] {
+		if v.MaxHeight != views[0].MaxHeight || len(v.Tips) != len(views[0].Tips) {
+			return false
+		}
+		for i := range v.Tips {
+			if v.Tips[i] != views[0].Tips[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// MissingBlocks returns the blocks that are in some of the views, but not in others, sorted by height. Only blocks up
+// to the lowest tips of the views are compared, because nodes that are behind are shown by their tips.
+func MissingBlocks(views []*DAGView) []MissingBlock {
+	if len(views) == 0 {
+		return nil
+	}
+
+	maxHeight := views[0].MaxHeight
+	for _, v := range views[1:] {
+		if v.MaxHeight < maxHeight {
+			maxHeight = v.MaxHeight
+		}
+	}
+
+	seen := make(map[chainhash.Hash]bool)
+	missing := make([]MissingBlock, 0)
+	for _, v := range views {
+		for hash, height := range v.Blocks {
+			if height > maxHeight || seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			m := MissingBlock{Hash: hash, Height: height}
+			for i, other := range views {
+				if _, ok := other.Blocks[hash]; !ok {
+					m.MissingFrom = append(m.MissingFrom, i)
+				}
+			}
+			if len(m.MissingFrom) > 0 {
+				missing = append(missing, m)
+			}
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Height != missing[j].Height {
+			return missing[i].Height < missing[j].Height
+		}
+		return missing[i].Hash.String() < missing[j].Hash.String()
+	})
+
+	return missing
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"testing"
+)
+
+// testHash returns a distinct hash for the number
+func testHash(n byte) chainhash.Hash {
+	var hash chainhash.Hash
+	hash[0] = n
+	return hash
+}
+
+func TestMissingBlocks(t *testing.T) {
+	a := &DAGView{
+		Tips:      []string{testHash(3).String()},
+		MaxHeight: 2,
+		Blocks:    map[chainhash.Hash]int32{testHash(1): 0, testHash(2): 1, testHash(3): 2},
+	}
+	// b doesn't have block 2, and has block 4 at the same height instead
+	b := &DAGView{
+		Tips:      []string{testHash(3).String()},
+		MaxHeight: 2,
+		Blocks:    map[chainhash.Hash]int32{testHash(1): 0, testHash(4): 1, testHash(3): 2},
+	}
+	// c is ahead of the others, so its block 5 isn't compared
+	c := &DAGView{
+		Tips:      []string{testHash(5).String()},
+		MaxHeight: 3,
+		Blocks:    map[chainhash.Hash]int32{testHash(1): 0, testHash(2): 1, testHash(3): 2, testHash(5): 3},
+	}
+
+	if !SameTips([]*DAGView{a, b}) {
+		t.Errorf("views with the same tips have different tips")
+	}
+	if SameTips([]*DAGView{a, b, c}) {
+		t.Errorf("views with different tips have the same tips")
+	}
+
+	if len(MissingBlocks([]*DAGView{a, a})) != 0 {
+		t.Errorf("blocks missing from identical views")
+	}
+
+	missing := MissingBlocks([]*DAGView{a, b, c})
+	if len(missing) != 2 {
+		t.Fatalf("wrong number of missing blocks; got %d, want 2", len(missing))
+	}
+	// Block 2 is missing from b, and block 4 is missing from a and c
+	if missing[0].Hash != testHash(2) || len(missing[0].MissingFrom) != 1 || missing[0].MissingFrom[0] != 1 {
+		t.Errorf("wrong missing block; got %s missing from %v, want %s missing from [1]",
+			missing[0].Hash, missing[0].MissingFrom, testHash(2))
+	}
+	if missing[1].Hash != testHash(4) || len(missing[1].MissingFrom) != 2 {
+		t.Errorf("wrong missing block; got %s missing from %v, want %s missing from [0 2]",
+			missing[1].Hash, missing[1].MissingFrom, testHash(4))
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"io"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DataOutput is the data carried by a null-data (OP_RETURN) output of a transaction
+type DataOutput struct {
+	// The index of the output in the transaction
+	VIndex int
+	Data   []byte
+}
+
+// String returns the data as a hex string
+func (d DataOutput) String() string {
+	return hex.EncodeToString(d.Data)
+}
+
+// Text returns the data as a string, and true if the data is printable UTF-8 text
+func (d DataOutput) Text() (string, bool) {
+	if len(d.Data) == 0 || !utf8.Valid(d.Data) {
+		return "", false
+	}
+
+	s := string(d.Data)
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+
+	return s, true
+}
+
+// NullDataOutput returns a transaction output with no value, that carries the data in a null-data (OP_RETURN) script.
+// soterd's policy only relays transactions with data of up to txscript.MaxDataCarrierSize bytes in a single null-data output.
+func NullDataOutput(data []byte) (*wire.TxOut, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data for null-data output")
+	}
+	if len(data) > txscript.MaxDataCarrierSize {
+		return nil, fmt.Errorf("data is %d bytes, more than the %d bytes allowed in a null-data output",
+			len(data), txscript.MaxDataCarrierSize)
+	}
+
+	script, err := txscript.NullDataScript(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return wire.NewTxOut(0, script), nil
+}
+
+// ExtractNullData returns the data carried by the script, and true if it's a null-data script
+func ExtractNullData(pkScript []byte) ([]byte, bool) {
+	if txscript.GetScriptClass(pkScript) != txscript.NullDataTy {
+		return nil, false
+	}
+
+	pushes, err := txscript.PushedData(pkScript)
+	if err != nil {
+		return nil, false
+	}
+
+	data := make([]byte, 0)
+	for _, p := range pushes {
+		data = append(data, p...)
+	}
+
+	return data, true
+}
+
+// TxDataOutputs returns the data carried by null-data outputs of the transaction
+func TxDataOutputs(tx *wire.MsgTx) []DataOutput {
+	outputs := make([]DataOutput, 0)
+	for i, txOut := range tx.TxOut {
+		data, ok := ExtractNullData(txOut.PkScript)
+		if !ok {
+			continue
+		}
+
+		outputs = append(outputs, DataOutput{VIndex: i, Data: data})
+	}
+
+	return outputs
+}
+
+// FileDigest returns the SHA-256 digest of the file's contents, which can be used to anchor the file in a null-data output
+func FileDigest(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", name, err)
+	}
+
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNullDataOutput(t *testing.T) {
+	tests := []struct {
+		size  int
+		valid bool
+	}{
+		{0, false},
+		{1, true},
+		{32, true},
+		{txscript.MaxDataCarrierSize, true},
+		{txscript.MaxDataCarrierSize + 1, false},
+	}
+
+	for _, test := range tests {
+		data := bytes.Repeat([]byte{0xab}, test.size)
+		txOut, err := NullDataOutput(data)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("created null-data output with %d bytes of data", test.size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed to create null-data output with %d bytes of data: %s", test.size, err)
+			continue
+		}
+
+		if txOut.Value != 0 {
+			t.Errorf("wrong null-data output value; got %d, want 0", txOut.Value)
+		}
+
+		got, ok := ExtractNullData(txOut.PkScript)
+		if !ok {
+			t.Errorf("output script with %d bytes of data isn't a null-data script", test.size)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("wrong data extracted from null-data script; got %x, want %x", got, data)
+		}
+	}
+}
+
+func TestTxDataOutputs(t *testing.T) {
+	digest := sha256.Sum256([]byte("document"))
+	dataOut, err := NullDataOutput(digest[:])
+	if err != nil {
+		t.Fatalf("failed to create null-data output: %s", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+	tx.AddTxOut(dataOut)
+
+	outputs := TxDataOutputs(tx)
+	if len(outputs) != 1 {
+		t.Fatalf("wrong number of data outputs; got %d, want 1", len(outputs))
+	}
+	if outputs[0].VIndex != 1 || !bytes.Equal(outputs[0].Data, digest[:]) {
+		t.Errorf("wrong data output; got index %d data %s", outputs[0].VIndex, outputs[0])
+	}
+	if _, ok := outputs[0].Text(); ok {
+		t.Errorf("binary data reported as text")
+	}
+
+	text := DataOutput{Data: []byte("hello soter")}
+	s, ok := text.Text()
+	if !ok || s != "hello soter" {
+		t.Errorf("wrong text for data output; got %q, %v", s, ok)
+	}
+}
+
+func TestFileDigest(t *testing.T) {
+	contents := []byte("anchor this file in the dag")
+	f, err := ioutil.TempFile("", "sotertools_digest-*")
+	if err != nil {
+		t.Fatalf("failed to create file: %s", err)
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = f.Write(contents)
+	_ = f.Close()
+	if err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	digest, err := FileDigest(f.Name())
+	if err != nil {
+		t.Fatalf("failed to get file digest: %s", err)
+	}
+
+	want := sha256.Sum256(contents)
+	if !bytes.Equal(digest, want[:]) {
+		t.Errorf("wrong file digest; got %x, want %x", digest, want)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterjson"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+)
+
+// The errors of failures that callers can react to, which can be checked for with errors.Is. Some of them are returned
+// as one of the error types below, which has more details and can be found with errors.As.
+var (
+	// ErrInsufficientFunds means there isn't enough spendable coin for the amount and fee of a transaction
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrWrongPassphrase means the wallet couldn't be opened or unlocked, because the passphrase is wrong
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrWalletLocked means the wallet was locked, when its private keys were needed
+	ErrWalletLocked = errors.New("wallet is locked")
+	// ErrMissingPrevTx means a transaction in the dag spends an output of a transaction that isn't found
+	ErrMissingPrevTx = errors.New("missing previous transaction")
+	// ErrRPCUnavailable means the soterd node couldn't be reached, or didn't answer the request
+	ErrRPCUnavailable = errors.New("soterd RPC server is unavailable")
+	// ErrRejected means the soterd node rejected a transaction
+	ErrRejected = errors.New("transaction rejected")
+	// ErrNetworkMismatch means a wallet or key is for another network than the one in use
+	ErrNetworkMismatch = errors.New("network mismatch")
+	// ErrNotFound means a block or transaction isn't in the dag, or the soterd node's mempool
+	ErrNotFound = errors.New("not found")
+)
+
+// InsufficientFundsError is an ErrInsufficientFunds, with the amount needed and the amount that can be spent
+type InsufficientFundsError struct {
+	Needed    soterutil.Amount
+	Available soterutil.Amount
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%s; %s needed, %s spendable", ErrInsufficientFunds, e.Needed, e.Available)
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// MissingPrevTxError is an ErrMissingPrevTx, with the input that spends the missing transaction's output
+type MissingPrevTxError struct {
+	PrevTx chainhash.Hash
+	Tx     chainhash.Hash
+	Input  int
+}
+
+func (e *MissingPrevTxError) Error() string {
+	return fmt.Sprintf("%s %s for transaction %s input %d", ErrMissingPrevTx, e.PrevTx, e.Tx, e.Input)
+}
+
+func (e *MissingPrevTxError) Is(target error) bool {
+	return target == ErrMissingPrevTx
+}
+
+// RejectedError is an ErrRejected, with the reason the node gave
+type RejectedError struct {
+	Tx     chainhash.Hash
+	Code   soterjson.RPCErrorCode
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("transaction %s rejected by node: %s", e.Tx, e.Reason)
+}
+
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// NetworkMismatchError is an ErrNetworkMismatch. Of is what has the wrong network, such as a wallet or private key,
+// and Network is its network, if it's known.
+type NetworkMismatchError struct {
+	Of      string
+	Network string
+	Want    string
+}
+
+func (e *NetworkMismatchError) Error() string {
+	if len(e.Network) == 0 {
+		return fmt.Sprintf("%s isn't for the %s network", e.Of, e.Want)
+	}
+	return fmt.Sprintf("%s is for the %s network, not %s", e.Of, e.Network, e.Want)
+}
+
+func (e *NetworkMismatchError) Is(target error) bool {
+	return target == ErrNetworkMismatch
+}
+
+// rpcError returns the error of an RPC request. An error that isn't the node's answer to the request means the node
+// couldn't be reached, and is returned as an ErrRPCUnavailable.
+func rpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var answer *soterjson.RPCError
+	if errors.As(err, &answer) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", ErrRPCUnavailable, err)
+}
+
+// sendError returns the error of sending the transaction to the node. The node's answer is the reason it rejected
+// the transaction.
+func sendError(txHash chainhash.Hash, err error) error {
+	var answer *soterjson.RPCError
+	if errors.As(err, &answer) {
+		return &RejectedError{Tx: txHash, Code: answer.Code, Reason: answer.Message}
+	}
+
+	return rpcError(err)
+}
+
+// managerError returns the error of the wallet's address manager, as ErrWrongPassphrase or ErrWalletLocked if it's
+// one of them
+func managerError(err error) error {
+	switch {
+	case waddrmgr.IsError(err, waddrmgr.ErrWrongPassphrase):
+		return ErrWrongPassphrase
+	case waddrmgr.IsError(err, waddrmgr.ErrLocked):
+		return ErrWalletLocked
+	default:
+		return err
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterjson"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"testing"
+)
+
+func TestErrorTypes(t *testing.T) {
+	tests := []struct {
+		err    error
+		target error
+	}{
+		{&InsufficientFundsError{Needed: 2, Available: 1}, ErrInsufficientFunds},
+		{&MissingPrevTxError{Input: 1}, ErrMissingPrevTx},
+		{&RejectedError{Reason: "already have transaction"}, ErrRejected},
+		{&NetworkMismatchError{Of: "wallet", Network: "testnet", Want: "simnet"}, ErrNetworkMismatch},
+	}
+	for _, test := range tests {
+		// The errors can be found when they're wrapped
+		err := fmt.Errorf("failed: %w", test.err)
+		if !errors.Is(err, test.target) {
+			t.Errorf("error %q is not %q", err, test.target)
+		}
+		if errors.Is(err, ErrRPCUnavailable) {
+			t.Errorf("error %q is %q", err, ErrRPCUnavailable)
+		}
+	}
+
+	var rejected *RejectedError
+	err := fmt.Errorf("failed: %w", &RejectedError{Reason: "already have transaction"})
+	if !errors.As(err, &rejected) || rejected.Reason != "already have transaction" {
+		t.Errorf("failed to get RejectedError with its reason from %q", err)
+	}
+}
+
+func TestRPCError(t *testing.T) {
+	if rpcError(nil) != nil {
+		t.Errorf("rpcError of nil error isn't nil")
+	}
+
+	// An error that isn't the node's answer means the node is unavailable
+	err := rpcError(errors.New("connection refused"))
+	if !errors.Is(err, ErrRPCUnavailable) {
+		t.Errorf("error %q is not %q", err, ErrRPCUnavailable)
+	}
+
+	// The node's answer is kept
+	answer := &soterjson.RPCError{Code: soterjson.ErrRPCInvalidParameter, Message: "invalid parameter"}
+	err = rpcError(answer)
+	if err != answer {
+		t.Errorf("wrong error of the node's answer; got %q, want %q", err, answer)
+	}
+
+	// The node's answer to a send is the reason the transaction was rejected
+	var hash chainhash.Hash
+	hash[0] = 0x01
+	answer = &soterjson.RPCError{Code: soterjson.ErrRPCVerify, Message: "transaction already exists"}
+	err = sendError(hash, answer)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("error %q is not a RejectedError", err)
+	}
+	if rejected.Tx != hash || rejected.Code != answer.Code || rejected.Reason != answer.Message {
+		t.Errorf("wrong RejectedError; got %+v, want tx %s code %d reason %q", rejected, hash, answer.Code, answer.Message)
+	}
+
+	err = sendError(hash, errors.New("connection refused"))
+	if !errors.Is(err, ErrRPCUnavailable) || errors.Is(err, ErrRejected) {
+		t.Errorf("wrong error of send to an unavailable node: %q", err)
+	}
+}
+
+func TestWalletErrors(t *testing.T) {
+	w, name, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	addr, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	_, err = ExportPrivateKey(w, "wrong", addr)
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("error of exporting a key with the wrong passphrase is %q, not %q", err, ErrWrongPassphrase)
+	}
+
+	_, err = SignMessage(w, "wrong", addr, "message")
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("error of signing with the wrong passphrase is %q, not %q", err, ErrWrongPassphrase)
+	}
+	_ = w.Database().Close()
+
+	_, err = OpenWallet(name, "wrong", nil)
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("error of opening the wallet with the wrong passphrase is %q, not %q", err, ErrWrongPassphrase)
+	}
+
+	_, err = OpenWallet(name, "pub", &chaincfg.TestNet1Params)
+	var mismatch *NetworkMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("error of opening the wallet with another network is %q, not a NetworkMismatchError", err)
+	}
+	if mismatch.Want != chaincfg.TestNet1Params.Name {
+		t.Errorf("wrong network wanted in %q; got %s, want %s", err, mismatch.Want, chaincfg.TestNet1Params.Name)
+	}
+}
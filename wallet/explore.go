@@ -0,0 +1,251 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterjson"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+)
+
+// BlockDetail describes a block of the dag: where it is, the blocks it builds on, and its transactions
+type BlockDetail struct {
+	Block  *wire.MsgBlock
+	Height int32
+	// How many blocks deep the block is, counting the block itself
+	Confirmations int32
+	Parents       []chainhash.Hash
+}
+
+// TxInput is an input of a transaction, resolved to the output it spends
+type TxInput struct {
+	PrevOut wire.OutPoint
+	// A coinbase input doesn't spend an output, so it doesn't have addresses or an amount
+	Coinbase  bool
+	Addresses []soterutil.Address
+	Amount    soterutil.Amount
+}
+
+// TxOutput is an output of a transaction, with the addresses it pays to
+type TxOutput struct {
+	VIndex    int
+	Amount    soterutil.Amount
+	Class     txscript.ScriptClass
+	Addresses []soterutil.Address
+	// The data of a null-data output, which is nil for other outputs
+	Data []byte
+}
+
+// TxDetail describes a transaction: its inputs resolved to the outputs they spend, its outputs, and its fee
+type TxDetail struct {
+	Tx *wire.MsgTx
+	// Where the transaction is in the dag, which is nil for a transaction in the mempool
+	Info     *TxInfo
+	Inputs   []TxInput
+	Outputs  []TxOutput
+	Coinbase bool
+	// What the inputs spend that the outputs don't send to anyone. A coinbase transaction doesn't pay a fee.
+	Fee soterutil.Amount
+}
+
+// isCoinbaseInput returns true if the input is the input of a coinbase transaction, which doesn't spend an output
+func isCoinbaseInput(txIn *wire.TxIn) bool {
+	prev := txIn.PreviousOutPoint
+	return prev.Index == wire.MaxPrevOutIndex && prev.Hash.IsEqual(&zeroHash)
+}
+
+// notFound returns ErrNotFound if the node's answer to an RPC request is that the block or transaction wasn't found,
+// or the error of the request otherwise
+func notFound(err error, format string, args ...interface{}) error {
+	var answer *soterjson.RPCError
+	if errors.As(err, &answer) && answer.Code == soterjson.ErrRPCBlockNotFound {
+		return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ErrNotFound)
+	}
+
+	return rpcError(err)
+}
+
+// GetBlockDetail returns the details of the block with the hash, or ErrNotFound if the node doesn't have it
+func GetBlockDetail(client *rpcclient.Client, hash *chainhash.Hash) (*BlockDetail, error) {
+	block, err := client.GetBlock(hash)
+	if err != nil {
+		return nil, notFound(err, "block %s", hash)
+	}
+	verbose, err := client.GetBlockVerbose(hash)
+	if err != nil {
+		return nil, notFound(err, "block %s", hash)
+	}
+
+	detail := BlockDetail{
+		Block:         block,
+		Height:        int32(verbose.Height),
+		Confirmations: int32(verbose.Confirmations),
+	}
+	for _, p := range block.Parents.Parents {
+		detail.Parents = append(detail.Parents, p.Hash)
+	}
+
+	return &detail, nil
+}
+
+// BlocksAtHeight returns the blocks of the dag at the height, which are parallel to each other. It returns ErrNotFound
+// if the height is above the dag's tips.
+func BlocksAtHeight(client *rpcclient.Client, height int32) ([]*wire.MsgBlock, error) {
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if height < 0 || height > tips.MaxHeight {
+		return nil, fmt.Errorf("height %d, with tips at height %d: %w", height, tips.MaxHeight, ErrNotFound)
+	}
+
+	hashes, err := client.GetBlockHash(int64(height))
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	blocks := make([]*wire.MsgBlock, 0, len(hashes))
+	for _, hash := range hashes {
+		block, err := client.GetBlock(hash)
+		if err != nil {
+			return nil, rpcError(err)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// DescribeTx returns the details of the transaction, resolving its inputs with the transactions they spend from in
+// prevs. An input that spends from a transaction that isn't in prevs is a MissingPrevTxError.
+func DescribeTx(tx *wire.MsgTx, prevs map[chainhash.Hash]*wire.MsgTx, params *chaincfg.Params) (*TxDetail, error) {
+	detail := TxDetail{
+		Tx:      tx,
+		Inputs:  make([]TxInput, 0, len(tx.TxIn)),
+		Outputs: make([]TxOutput, 0, len(tx.TxOut)),
+	}
+
+	var in, out soterutil.Amount
+	for i, txIn := range tx.TxIn {
+		input := TxInput{PrevOut: txIn.PreviousOutPoint}
+		if isCoinbaseInput(txIn) {
+			input.Coinbase = true
+			detail.Coinbase = true
+			detail.Inputs = append(detail.Inputs, input)
+			continue
+		}
+
+		prev, ok := prevs[txIn.PreviousOutPoint.Hash]
+		if !ok || int(txIn.PreviousOutPoint.Index) >= len(prev.TxOut) {
+			return nil, &MissingPrevTxError{PrevTx: txIn.PreviousOutPoint.Hash, Tx: tx.TxHash(), Input: i}
+		}
+		prevOut := prev.TxOut[txIn.PreviousOutPoint.Index]
+		input.Amount = soterutil.Amount(prevOut.Value)
+		// Outputs with scripts that aren't standard don't have addresses
+		_, input.Addresses, _, _ = txscript.ExtractPkScriptAddrs(prevOut.PkScript, params)
+
+		in += input.Amount
+		detail.Inputs = append(detail.Inputs, input)
+	}
+
+	for i, txOut := range tx.TxOut {
+		output := TxOutput{VIndex: i, Amount: soterutil.Amount(txOut.Value)}
+		output.Class, output.Addresses, _, _ = txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+		output.Data, _ = ExtractNullData(txOut.PkScript)
+
+		out += output.Amount
+		detail.Outputs = append(detail.Outputs, output)
+	}
+
+	if !detail.Coinbase {
+		detail.Fee = in - out
+	}
+
+	return &detail, nil
+}
+
+// GetTxDetail returns the details of the transaction with the status, which is found with GetTxStatus. The
+// transactions that its inputs spend from are looked for in the dag once, and the scan stops when all of them are
+// found. It returns ErrNotFound if the transaction is neither in a block nor in the node's mempool.
+func GetTxDetail(client *rpcclient.Client, status *TxStatus, params *chaincfg.Params) (*TxDetail, error) {
+	var info *TxInfo
+	var tx *wire.MsgTx
+	switch status.State {
+	case TxInBlock:
+		for i, blockTx := range status.Block.Transactions {
+			h := blockTx.TxHash()
+			if h.IsEqual(&status.Hash) {
+				info = &TxInfo{Tx: blockTx, Block: status.Block, Index: i, BlockHeight: status.BlockHeight}
+				tx = blockTx
+				break
+			}
+		}
+		if tx == nil {
+			return nil, fmt.Errorf("transaction %s in block %s: %w", status.Hash, status.Block.BlockHash(),
+				ErrNotFound)
+		}
+	case TxInMempool:
+		mempoolTx, err := client.GetRawTransaction(&status.Hash)
+		if err != nil {
+			return nil, notFound(err, "transaction %s", status.Hash)
+		}
+		tx = mempoolTx.MsgTx()
+	default:
+		return nil, fmt.Errorf("transaction %s: %w", status.Hash, ErrNotFound)
+	}
+
+	// A transaction in the mempool can spend from another transaction that's still in the mempool
+	pending := make(map[chainhash.Hash]bool)
+	if info == nil {
+		mempool, err := client.GetRawMempool()
+		if err != nil {
+			return nil, rpcError(err)
+		}
+		for _, h := range mempool {
+			pending[*h] = true
+		}
+	}
+
+	prevs := make(map[chainhash.Hash]*wire.MsgTx, len(tx.TxIn))
+	hashes := make([]chainhash.Hash, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		h := txIn.PreviousOutPoint.Hash
+		if _, ok := prevs[h]; ok || isCoinbaseInput(txIn) {
+			continue
+		}
+		if pending[h] {
+			prev, err := client.GetRawTransaction(&h)
+			if err == nil {
+				prevs[h] = prev.MsgTx()
+				continue
+			}
+		}
+
+		hashes = append(hashes, h)
+	}
+
+	found, err := FindTxs(client, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for h, prev := range found {
+		prevs[h] = prev.Tx
+	}
+
+	detail, err := DescribeTx(tx, prevs, params)
+	if err != nil {
+		return nil, err
+	}
+	detail.Info = info
+
+	return detail, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"testing"
+)
+
+func TestDescribeTx(t *testing.T) {
+	params := &chaincfg.SimNetParams
+	addr, err := soterutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20), params)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("failed to create output script: %s", err)
+	}
+	dataOut, err := NullDataOutput([]byte("memo"))
+	if err != nil {
+		t.Fatalf("failed to create null-data output: %s", err)
+	}
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), nil, nil))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, script))
+	coinbaseHash := coinbase.TxHash()
+
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&coinbaseHash, 0), nil, nil))
+	spend.AddTxOut(wire.NewTxOut(3000000000, script))
+	spend.AddTxOut(wire.NewTxOut(1999000000, script))
+	spend.AddTxOut(dataOut)
+
+	prevs := map[chainhash.Hash]*wire.MsgTx{coinbaseHash: coinbase}
+
+	detail, err := DescribeTx(coinbase, prevs, params)
+	if err != nil {
+		t.Fatalf("failed to describe coinbase transaction: %s", err)
+	}
+	if !detail.Coinbase || !detail.Inputs[0].Coinbase || detail.Fee != 0 {
+		t.Errorf("wrong coinbase details; got coinbase %v, input coinbase %v, fee %d",
+			detail.Coinbase, detail.Inputs[0].Coinbase, detail.Fee)
+	}
+
+	detail, err = DescribeTx(spend, prevs, params)
+	if err != nil {
+		t.Fatalf("failed to describe transaction: %s", err)
+	}
+	if detail.Coinbase {
+		t.Errorf("transaction is described as a coinbase")
+	}
+	if detail.Fee != 1000000 {
+		t.Errorf("wrong fee; got %d, want %d", detail.Fee, 1000000)
+	}
+	in := detail.Inputs[0]
+	if in.Amount != 5000000000 || len(in.Addresses) != 1 || in.Addresses[0].EncodeAddress() != addr.EncodeAddress() {
+		t.Errorf("wrong input; got %d from %v, want %d from %s", in.Amount, in.Addresses, 5000000000, addr)
+	}
+	if len(detail.Outputs) != 3 {
+		t.Fatalf("wrong number of outputs; got %d, want 3", len(detail.Outputs))
+	}
+	if detail.Outputs[1].Class != txscript.PubKeyHashTy || detail.Outputs[1].Amount != 1999000000 {
+		t.Errorf("wrong output; got %s of %d", detail.Outputs[1].Class, detail.Outputs[1].Amount)
+	}
+	if detail.Outputs[2].Class != txscript.NullDataTy || string(detail.Outputs[2].Data) != "memo" {
+		t.Errorf("wrong null-data output; got %s with data %q", detail.Outputs[2].Class, detail.Outputs[2].Data)
+	}
+
+	_, err = DescribeTx(spend, map[chainhash.Hash]*wire.MsgTx{}, params)
+	if !errors.Is(err, ErrMissingPrevTx) {
+		t.Errorf("wrong error of missing previous transaction; got %v, want %s", err, ErrMissingPrevTx)
+	}
+}
+
+func TestGetTxDetailFromStatus(t *testing.T) {
+	params := &chaincfg.SimNetParams
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), nil, nil))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, nil))
+	block := wire.MsgBlock{Transactions: []*wire.MsgTx{coinbase}}
+
+	// The transaction is taken from the block of its status, and a coinbase has no inputs to look for, so the node
+	// isn't asked for anything
+	status := TxStatus{Hash: coinbase.TxHash(), State: TxInBlock, Block: &block, BlockHeight: 7}
+	detail, err := GetTxDetail(nil, &status, params)
+	if err != nil {
+		t.Fatalf("failed to get details of transaction in block: %s", err)
+	}
+	if detail.Info == nil || detail.Info.Block != &block || detail.Info.BlockHeight != 7 || detail.Info.Index != 0 {
+		t.Errorf("wrong block info; got %+v, want index 0 of block at height 7", detail.Info)
+	}
+
+	tests := []TxStatus{
+		{Hash: chainhash.Hash{0x01}, State: TxUnknown},
+		// The block of the status doesn't hold the transaction
+		{Hash: chainhash.Hash{0x01}, State: TxInBlock, Block: &block, BlockHeight: 7},
+	}
+	for i, test := range tests {
+		_, err := GetTxDetail(nil, &test, params)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("test %d: wrong error; got %v, want %s", i, err, ErrNotFound)
+		}
+	}
+}
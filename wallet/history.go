@@ -0,0 +1,125 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+)
+
+// HistoryEntry describes a transaction in the dag that sends coin to, or spends coin from, some addresses
+type HistoryEntry struct {
+	Info *TxInfo
+
+	// Coin sent to the addresses by the transaction's outputs
+	Received soterutil.Amount
+	// Coin spent from the addresses by the transaction's inputs
+	Sent soterutil.Amount
+
+	// Data carried by the transaction's null-data outputs
+	Data []DataOutput
+}
+
+// Net returns the change in balance of the addresses from the transaction
+func (e *HistoryEntry) Net() soterutil.Amount {
+	return e.Received - e.Sent
+}
+
+// outputAmount returns the value of the output if it pays to one of the addresses, or 0 otherwise
+func outputAmount(txOut *wire.TxOut, addresses []soterutil.Address, params *chaincfg.Params) (soterutil.Amount, error) {
+	_, outAddresses, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, address := range outAddresses {
+		if IsAddressIn(address, addresses) {
+			return soterutil.Amount(txOut.Value), nil
+		}
+	}
+
+	return 0, nil
+}
+
+// History returns the transactions in the dag that send coin to, or spend coin from, the given addresses, in dag order
+func History(client *rpcclient.Client, addresses []soterutil.Address, params *chaincfg.Params) ([]HistoryEntry, error) {
+	var history = make([]HistoryEntry, 0)
+	var txIndex = make(map[chainhash.Hash]TxInfo)
+
+	transactions, err := AllTransactions(client)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range transactions {
+		txIndex[info.Tx.TxHash()] = info
+	}
+
+	for _, info := range transactions {
+		// Locally bind info to a local variable, to keep the Info field pointing at the correct
+		// TxInfo struct as the loop continues.
+		entryInfo := info
+		entry := HistoryEntry{
+			Info: &entryInfo,
+		}
+
+		for i, txIn := range info.Tx.TxIn {
+			if txIn.PreviousOutPoint.Hash.IsEqual(&zeroHash) {
+				continue
+			}
+
+			prev, ok := txIndex[txIn.PreviousOutPoint.Hash]
+			if !ok {
+				return nil, fmt.Errorf("missing previous transaction %s for transaction %s input %d",
+					txIn.PreviousOutPoint.Hash, info.Tx.TxHash(), i)
+			}
+
+			amount, err := outputAmount(prev.Tx.TxOut[txIn.PreviousOutPoint.Index], addresses, params)
+			if err != nil {
+				return nil, err
+			}
+			entry.Sent += amount
+		}
+
+		for _, txOut := range info.Tx.TxOut {
+			amount, err := outputAmount(txOut, addresses, params)
+			if err != nil {
+				return nil, err
+			}
+			entry.Received += amount
+		}
+
+		if entry.Received == 0 && entry.Sent == 0 {
+			continue
+		}
+
+		entry.Data = TxDataOutputs(info.Tx)
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// FindDataOutputs returns the transactions in the dag that involve the given addresses and carry data in null-data outputs
+func FindDataOutputs(client *rpcclient.Client, addresses []soterutil.Address, params *chaincfg.Params) ([]HistoryEntry, error) {
+	history, err := History(client, addresses, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0)
+	for _, e := range history {
+		if len(e.Data) > 0 {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"sync"
+	"time"
+)
+
+// The longest time between checks of whether the wallet is unlocked, in AutoLock
+const maxLockCheckInterval = time.Second
+
+var (
+	// unlockTimes holds when the address managers were unlocked by unlock, until lock locks them again, so that
+	// AutoLock times the unlock that's in effect rather than an earlier one
+	unlockTimes    = make(map[*waddrmgr.Manager]time.Time)
+	unlockTimesMtx sync.Mutex
+)
+
+// setUnlocked records that the manager was unlocked now
+func setUnlocked(m *waddrmgr.Manager) {
+	unlockTimesMtx.Lock()
+	defer unlockTimesMtx.Unlock()
+
+	unlockTimes[m] = time.Now()
+}
+
+// setLocked removes the record of the manager being unlocked
+func setLocked(m *waddrmgr.Manager) {
+	unlockTimesMtx.Lock()
+	defer unlockTimesMtx.Unlock()
+
+	delete(unlockTimes, m)
+}
+
+// unlockedAt returns when the manager was unlocked. A manager that was unlocked outside of this package is recorded
+// as unlocked now, the first time it's seen.
+func unlockedAt(m *waddrmgr.Manager, now time.Time) time.Time {
+	unlockTimesMtx.Lock()
+	defer unlockTimesMtx.Unlock()
+
+	at, ok := unlockTimes[m]
+	if !ok {
+		at = now
+		unlockTimes[m] = at
+	}
+
+	return at
+}
+
+// AutoLock locks the wallet's address manager whenever it has stayed unlocked for longer than the timeout, until quit
+// is closed. It blocks, so it's meant to be run in its own goroutine. mtx is held while the manager is checked and
+// locked, so that it isn't locked while the holder of mtx is using its private keys.
+//
+// Functions in this package lock the manager again once they're done with its private keys, so AutoLock is a
+// safeguard for long-running processes like walletweb, against the manager being left unlocked.
+func AutoLock(w *wallet.Wallet, timeout time.Duration, mtx sync.Locker, quit <-chan struct{}) {
+	interval := timeout / 10
+	if interval > maxLockCheckInterval {
+		interval = maxLockCheckInterval
+	}
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			mtx.Lock()
+			now := time.Now()
+			if !w.Manager.IsLocked() && now.Sub(unlockedAt(w.Manager, now)) >= timeout {
+				lock(w)
+			}
+			mtx.Unlock()
+		}
+	}
+}
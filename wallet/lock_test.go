@@ -0,0 +1,56 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAutoLock(t *testing.T) {
+	w, _, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	addr, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	// Functions that use the private keys lock the wallet again when they're done
+	_, err = SignMessage(w, "priv", addr, "hello")
+	if err != nil {
+		t.Fatalf("failed to sign message: %s", err)
+	}
+	if !w.Manager.IsLocked() {
+		t.Errorf("wallet left unlocked after signing a message")
+	}
+
+	var mtx sync.Mutex
+	quit := make(chan struct{})
+	defer close(quit)
+	go AutoLock(w, 100*time.Millisecond, &mtx, quit)
+
+	// The wallet isn't locked while mtx is held by a user of its private keys, even after the timeout
+	mtx.Lock()
+	err = unlock(w, "priv")
+	if err != nil {
+		t.Fatalf("failed to unlock wallet: %s", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if w.Manager.IsLocked() {
+		t.Errorf("wallet was locked while mtx was held")
+	}
+	mtx.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !w.Manager.IsLocked() {
+		if time.Now().After(deadline) {
+			t.Fatalf("wallet wasn't locked after its unlock timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
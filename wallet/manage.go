@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/soteria-dag/soterd/chaincfg"
 	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/soterutil/hdkeychain"
 	"github.com/soteria-dag/soterwallet/waddrmgr"
 	"github.com/soteria-dag/soterwallet/wallet"
 	"github.com/soteria-dag/soterwallet/walletdb"
@@ -56,6 +57,17 @@ func newWalletAddress(w *wallet.Wallet, addrmgrNs walletdb.ReadWriteBucket, acco
 // CreateWallet creates a wallet
 // NOTE(cedric): Based on github.com/soteria-dag/soterwallet/walletsetup.go createSimulationWallet function
 func CreateWallet(name, privPass, pubPass string, netParams *chaincfg.Params) error {
+	// The seed is generated here instead of by wallet.Create, so that it can be kept for backups
+	seed, err := hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
+	if err != nil {
+		return err
+	}
+
+	return createWallet(name, privPass, pubPass, seed, time.Now(), netParams)
+}
+
+// createWallet creates a wallet from the seed, and stores the seed encrypted in it
+func createWallet(name, privPass, pubPass string, seed []byte, birthday time.Time, netParams *chaincfg.Params) error {
 	priv := []byte(privPass)
 	pub := []byte(pubPass)
 
@@ -73,18 +85,32 @@ func CreateWallet(name, privPass, pubPass string, netParams *chaincfg.Params) er
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	// Initialize wallet db, creating the wallet
-	err = wallet.Create(db, pub, priv, nil, netParams, time.Now())
+	// Initialize wallet db, creating the wallet. Its network and seed are recorded in one db transaction, so that
+	// neither is left out of it.
+	err = wallet.Create(db, pub, priv, seed, netParams, birthday)
+	if err == nil {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			err := putNetwork(tx, netParams)
+			if err != nil {
+				return err
+			}
+
+			return putSeed(tx, pub, priv, seed, netParams)
+		})
+	}
+	_ = db.Close()
 	if err != nil {
+		// A wallet that's missing its network or seed can't be backed up, so it isn't kept
+		_ = os.Remove(name)
 		return err
 	}
 
 	return nil
 }
 
-// OpenWallet opens a wallet db, then wallet from it
+// OpenWallet opens a wallet db, then wallet from it. The wallet must be for the network of the params, or if params is
+// nil, the wallet's own network is used.
 func OpenWallet(name, pubPass string, params *chaincfg.Params) (*wallet.Wallet, error) {
 	// Open wallet db
 	db, err := walletdb.Open(walletDbType, name)
@@ -92,10 +118,28 @@ func OpenWallet(name, pubPass string, params *chaincfg.Params) (*wallet.Wallet,
 		return nil, fmt.Errorf("Failed to open wallet db %s: %s", name, err)
 	}
 
+	// Check the wallet's network, and record it for wallets created before it was recorded
+	var record bool
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		params, record, err = checkNetwork(tx, params)
+		return err
+	})
+	if err == nil && record {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			return putNetwork(tx, params)
+		})
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("Failed to open wallet %s: %w", name, err)
+	}
+
 	// Open wallet
 	w, err := wallet.Open(db, []byte(pubPass), nil, params, recoveryWindow)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to open wallet: %s", err)
+		// The db is closed, so that its file lock doesn't keep the wallet from being opened again
+		_ = db.Close()
+		return nil, fmt.Errorf("Failed to open wallet: %w", managerError(err))
 	}
 
 	return w, nil
@@ -142,4 +186,105 @@ func WalletAddresses(w *wallet.Wallet) ([]soterutil.Address, error) {
 	}
 
 	return addresses, nil
-}
\ No newline at end of file
+}
+
+// ImportPrivateKey imports a WIF-encoded private key into the wallet's imported account, so that coin sent to its
+// address is found through WalletAddresses and can be spent. It returns the address of the key.
+func ImportPrivateKey(w *wallet.Wallet, privPass, wifKey string) (soterutil.Address, error) {
+	wif, err := soterutil.DecodeWIF(wifKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode private key: %s", err)
+	}
+	if !wif.IsForNet(w.ChainParams()) {
+		return nil, &NetworkMismatchError{Of: "private key", Want: w.ChainParams().Name}
+	}
+
+	// The private key is stored encrypted, so the wallet needs to be unlocked
+	err = unlock(w, privPass)
+	if err != nil {
+		return nil, err
+	}
+	defer lock(w)
+
+	var addr soterutil.Address
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		manager, err := w.Manager.FetchScopedKeyManager(waddrmgr.KeyScopeBIP0044)
+		if err != nil {
+			return err
+		}
+
+		// We don't know when the key was first used, so the whole dag is considered
+		bs := &waddrmgr.BlockStamp{
+			Hash:   *w.ChainParams().GenesisHash,
+			Height: 0,
+		}
+
+		maddr, err := manager.ImportPrivateKey(addrmgrNs, wif, bs)
+		if err != nil {
+			return err
+		}
+
+		addr = maddr.Address()
+		return nil
+	})
+	if waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+		return nil, fmt.Errorf("Private key is already in the wallet")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to import private key: %s", err)
+	}
+
+	return addr, nil
+}
+
+// ExportPrivateKey returns the WIF-encoded private key of the wallet address
+func ExportPrivateKey(w *wallet.Wallet, privPass string, addr soterutil.Address) (string, error) {
+	err := unlock(w, privPass)
+	if err != nil {
+		return "", err
+	}
+	defer lock(w)
+
+	wif, err := w.DumpWIFPrivateKey(addr)
+	if err != nil {
+		return "", fmt.Errorf("Failed to export private key of address %s: %w", addr, managerError(err))
+	}
+
+	return wif, nil
+}
+
+// PassphraseChange is a change of the wallet's public or private passphrase
+type PassphraseChange struct {
+	Old     string
+	New     string
+	Private bool
+}
+
+// ChangePassphrases checks the old passphrases and changes them to the new ones. All of the changes are saved in a
+// single wallet db transaction, so if any of them fails or the program is interrupted, none of them are saved. After a
+// failure the wallet should be closed and reopened, because its in-memory state may not match the db.
+func ChangePassphrases(w *wallet.Wallet, changes ...PassphraseChange) error {
+	err := walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		for _, c := range changes {
+			err := w.Manager.ChangePassphrase(addrmgrNs, []byte(c.Old), []byte(c.New), c.Private,
+				&waddrmgr.DefaultScryptOptions)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if waddrmgr.IsError(err, waddrmgr.ErrWrongPassphrase) {
+		return ErrWrongPassphrase
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to change passphrase: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterec"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportExportPrivateKey(t *testing.T) {
+	w1, _, cleanup1 := createTestWallet(t, "priv1", "pub1")
+	defer cleanup1()
+	w2, _, cleanup2 := createTestWallet(t, "priv2", "pub2")
+	defer cleanup2()
+
+	addr, err := NewAddress(w1, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	_, err = ExportPrivateKey(w1, "wrong", addr)
+	if err == nil {
+		t.Errorf("exported a private key with the wrong private passphrase")
+	}
+
+	wif, err := ExportPrivateKey(w1, "priv1", addr)
+	if err != nil {
+		t.Fatalf("failed to export private key: %s", err)
+	}
+
+	imported, err := ImportPrivateKey(w2, "priv2", wif)
+	if err != nil {
+		t.Fatalf("failed to import private key: %s", err)
+	}
+	if imported.EncodeAddress() != addr.EncodeAddress() {
+		t.Errorf("wrong address of imported key; got %s, want %s", imported, addr)
+	}
+
+	addresses, err := WalletAddresses(w2)
+	if err != nil {
+		t.Fatalf("failed to get wallet addresses: %s", err)
+	}
+	if !IsAddressIn(addr, addresses) {
+		t.Errorf("imported address %s isn't in the wallet addresses %v", addr, addresses)
+	}
+
+	_, err = ImportPrivateKey(w2, "priv2", wif)
+	if err == nil {
+		t.Errorf("imported the same private key twice")
+	}
+
+	// Keys for other networks can't be imported
+	privKey, err := soterec.NewPrivateKey(soterec.S256())
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+	mainnetWIF, err := soterutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatalf("failed to encode private key: %s", err)
+	}
+	_, err = ImportPrivateKey(w2, "priv2", mainnetWIF.String())
+	if err == nil {
+		t.Errorf("imported a private key for another network")
+	}
+}
+
+func TestChangePassphrases(t *testing.T) {
+	w, name, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	// A wrong old passphrase fails all of the changes
+	err := ChangePassphrases(w,
+		PassphraseChange{Old: "pub", New: "newpub"},
+		PassphraseChange{Old: "wrong", New: "newpriv", Private: true},
+	)
+	if err == nil {
+		t.Fatalf("changed passphrases with the wrong old private passphrase")
+	}
+
+	_ = w.Database().Close()
+	w, err = OpenWallet(name, "pub", &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to open wallet with unchanged public passphrase: %s", err)
+	}
+
+	err = ChangePassphrases(w, PassphraseChange{Old: "priv", New: "newpriv", Private: true})
+	if err != nil {
+		t.Fatalf("failed to change private passphrase: %s", err)
+	}
+	err = ChangePassphrases(w, PassphraseChange{Old: "pub", New: "newpub"})
+	if err != nil {
+		t.Fatalf("failed to change public passphrase: %s", err)
+	}
+
+	_ = w.Database().Close()
+	_, err = OpenWallet(name, "pub", &chaincfg.SimNetParams)
+	if err == nil {
+		t.Errorf("opened wallet with the old public passphrase")
+	}
+	w, err = OpenWallet(name, "newpub", &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to open wallet with the new public passphrase: %s", err)
+	}
+
+	err = unlock(w, "priv")
+	if err == nil {
+		t.Errorf("unlocked wallet with the old private passphrase")
+	}
+	err = unlock(w, "newpriv")
+	if err != nil {
+		t.Fatalf("failed to unlock wallet with the new private passphrase: %s", err)
+	}
+
+	// The seed is encrypted with the wallet's private key, which doesn't change with the passphrase
+	seed, err := fetchSeed(w)
+	if err != nil || seed == nil {
+		t.Errorf("failed to read wallet seed after changing passphrases: %v", err)
+	}
+}
+
+func TestCreateWalletFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sotertools_wallet")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "wallet.db")
+
+	// A seed that's too short fails to create the wallet, and the wallet file isn't left behind
+	err = createWallet(name, "priv", "pub", []byte{1, 2, 3}, time.Now(), &chaincfg.SimNetParams)
+	if err == nil {
+		t.Fatalf("created wallet from a seed that's too short")
+	}
+	_, err = os.Stat(name)
+	if !os.IsNotExist(err) {
+		t.Errorf("wallet file left behind after failing to create wallet: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterec"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/wallet"
+)
+
+// messageHash returns the hash that's signed for the message. It matches the hash used by the signmessage and
+// verifymessage RPCs of soterd and soterwallet, so that signatures can be checked by either.
+func messageHash(message string) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, "Soter Signed Message:\n")
+	_ = wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// SignMessage returns a base64-encoded compact signature of the message, made with the private key of the wallet
+// address. The address must be a pay-to-pubkey-hash address.
+func SignMessage(w *wallet.Wallet, privPass string, addr soterutil.Address, message string) (string, error) {
+	if _, ok := addr.(*soterutil.AddressPubKeyHash); !ok {
+		return "", fmt.Errorf("address %s is not a pay-to-pubkey-hash address", addr)
+	}
+
+	err := unlock(w, privPass)
+	if err != nil {
+		return "", err
+	}
+	defer lock(w)
+
+	privKey, err := w.PrivKeyForAddress(addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to get private key of address %s: %w", addr, managerError(err))
+	}
+
+	sig, err := soterec.SignCompact(soterec.S256(), privKey, messageHash(message), true)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyMessage returns true if the base64-encoded signature of the message was made with the private key of the
+// address. An error is returned if the address or signature can't be decoded; a signature that can be decoded but
+// doesn't match returns false.
+func VerifyMessage(addr soterutil.Address, signature, message string, params *chaincfg.Params) (bool, error) {
+	if _, ok := addr.(*soterutil.AddressPubKeyHash); !ok {
+		return false, fmt.Errorf("address %s is not a pay-to-pubkey-hash address", addr)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("malformed base64 encoding of signature: %s", err)
+	}
+
+	pubKey, wasCompressed, err := soterec.RecoverCompact(soterec.S256(), sig, messageHash(message))
+	if err != nil {
+		// The signature isn't valid for any key
+		return false, nil
+	}
+
+	var serialized []byte
+	if wasCompressed {
+		serialized = pubKey.SerializeCompressed()
+	} else {
+		serialized = pubKey.SerializeUncompressed()
+	}
+
+	recovered, err := soterutil.NewAddressPubKey(serialized, params)
+	if err != nil {
+		return false, nil
+	}
+
+	return recovered.EncodeAddress() == addr.EncodeAddress(), nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"testing"
+)
+
+func TestSignMessage(t *testing.T) {
+	w, _, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+	params := &chaincfg.SimNetParams
+
+	addr, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	other, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	_, err = SignMessage(w, "wrong", addr, "hello")
+	if err == nil {
+		t.Errorf("signed a message with the wrong private passphrase")
+	}
+
+	sig, err := SignMessage(w, "priv", addr, "hello")
+	if err != nil {
+		t.Fatalf("failed to sign message: %s", err)
+	}
+
+	tests := []struct {
+		addr    soterutil.Address
+		message string
+		valid   bool
+	}{
+		{addr, "hello", true},
+		{addr, "hello!", false},
+		{other, "hello", false},
+	}
+
+	for _, test := range tests {
+		valid, err := VerifyMessage(test.addr, sig, test.message, params)
+		if err != nil {
+			t.Errorf("failed to verify message %q for %s: %s", test.message, test.addr, err)
+			continue
+		}
+		if valid != test.valid {
+			t.Errorf("wrong verification of message %q for %s; got %v, want %v", test.message, test.addr, valid, test.valid)
+		}
+	}
+
+	_, err = VerifyMessage(addr, "not base64!", "hello", params)
+	if err == nil {
+		t.Errorf("verified a malformed signature")
+	}
+}
@@ -0,0 +1,536 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"io/ioutil"
+)
+
+// The version of the partial transaction file format written by PartialTx.WriteFile
+const partialTxVersion = 1
+
+// ParseMultisigKey parses a key for a multisig address. The key can be a hex-encoded public key (for example from
+// another party), or a pay-to-pubkey-hash address from the wallet.
+func ParseMultisigKey(key string, params *chaincfg.Params) (soterutil.Address, error) {
+	serialized, err := hex.DecodeString(key)
+	if err == nil {
+		return soterutil.NewAddressPubKey(serialized, params)
+	}
+
+	return soterutil.DecodeAddress(key, params)
+}
+
+// importScript registers the redeem script in the wallet, as a pay-to-script-hash address of the imported account.
+// The wallet must be unlocked, because the script is stored encrypted. It's not an error if the script is already
+// registered.
+func importScript(w *wallet.Wallet, script []byte) (*soterutil.AddressScriptHash, error) {
+	params := w.ChainParams()
+	addr, err := soterutil.NewAddressScriptHash(script, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		manager, err := w.Manager.FetchScopedKeyManager(waddrmgr.KeyScopeBIP0044)
+		if err != nil {
+			return err
+		}
+
+		bs := &waddrmgr.BlockStamp{
+			Hash:   *params.GenesisHash,
+			Height: 0,
+		}
+
+		_, err = manager.ImportScript(addrmgrNs, script, bs)
+		if waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addr, nil
+}
+
+// NewMultisigAddress creates a P2SH address that requires nRequired signatures from the keys to spend, and registers
+// its redeem script in the wallet. Keys are parsed with ParseMultisigKey, so a mixture of our own addresses and other
+// parties' public keys can be used. It returns the address and its redeem script.
+func NewMultisigAddress(w *wallet.Wallet, privPass string, keys []string, nRequired int) (*soterutil.AddressScriptHash, []byte, error) {
+	if nRequired < 1 || nRequired > len(keys) {
+		return nil, nil, fmt.Errorf("required signatures must be between 1 and %d, got %d", len(keys), nRequired)
+	}
+
+	addrs := make([]soterutil.Address, len(keys))
+	for i, key := range keys {
+		addr, err := ParseMultisigKey(key, w.ChainParams())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse key %s: %s", key, err)
+		}
+
+		addrs[i] = addr
+	}
+
+	script, err := w.MakeMultiSigScript(addrs, nRequired)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = unlock(w, privPass)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lock(w)
+
+	addr, err := importScript(w, script)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to register redeem script: %s", err)
+	}
+
+	return addr, script, nil
+}
+
+// MultisigAddresses returns the pay-to-script-hash addresses registered in the wallet
+func MultisigAddresses(w *wallet.Wallet) ([]soterutil.Address, error) {
+	multisig := make([]soterutil.Address, 0)
+
+	addrs, err := w.AccountAddresses(waddrmgr.ImportedAddrAccount)
+	if err != nil {
+		return multisig, err
+	}
+
+	for _, addr := range addrs {
+		if _, ok := addr.(*soterutil.AddressScriptHash); ok {
+			multisig = append(multisig, addr)
+		}
+	}
+
+	return multisig, nil
+}
+
+// payToScriptHashScript returns the output script paying to the hash of the redeem script
+func payToScriptHashScript(redeemScript []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(soterutil.Hash160(redeemScript)).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
+
+// PartialTx is a transaction spending multisig outputs, which is passed between cosigners to collect signatures until
+// enough of them are present to spend the outputs.
+type PartialTx struct {
+	Tx *wire.MsgTx
+
+	// The scripts of the outputs spent by the transaction's inputs
+	PrevScripts map[wire.OutPoint][]byte
+
+	// The redeem scripts of the multisig addresses whose outputs are spent
+	RedeemScripts [][]byte
+}
+
+// partialTxPrevScript is the serialized form of a PartialTx previous output script
+type partialTxPrevScript struct {
+	Txid     string `json:"txid"`
+	Vout     uint32 `json:"vout"`
+	PkScript string `json:"pkScript"`
+}
+
+// partialTxFile is the serialized form of a PartialTx
+type partialTxFile struct {
+	Version       int                   `json:"version"`
+	Tx            string                `json:"tx"`
+	PrevScripts   []partialTxPrevScript `json:"prevScripts"`
+	RedeemScripts []string              `json:"redeemScripts"`
+}
+
+// MarshalJSON returns the serialized form of the PartialTx
+func (p *PartialTx) MarshalJSON() ([]byte, error) {
+	var raw bytes.Buffer
+	err := p.Tx.Serialize(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	f := partialTxFile{
+		Version: partialTxVersion,
+		Tx:      hex.EncodeToString(raw.Bytes()),
+	}
+
+	for _, txIn := range p.Tx.TxIn {
+		op := txIn.PreviousOutPoint
+		f.PrevScripts = append(f.PrevScripts, partialTxPrevScript{
+			Txid:     op.Hash.String(),
+			Vout:     op.Index,
+			PkScript: hex.EncodeToString(p.PrevScripts[op]),
+		})
+	}
+
+	for _, script := range p.RedeemScripts {
+		f.RedeemScripts = append(f.RedeemScripts, hex.EncodeToString(script))
+	}
+
+	return json.MarshalIndent(&f, "", "\t")
+}
+
+// UnmarshalJSON sets the PartialTx from its serialized form
+func (p *PartialTx) UnmarshalJSON(b []byte) error {
+	var f partialTxFile
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return err
+	}
+
+	if f.Version != partialTxVersion {
+		return fmt.Errorf("unsupported partial transaction version %d", f.Version)
+	}
+
+	raw, err := hex.DecodeString(f.Tx)
+	if err != nil {
+		return err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	prevScripts := make(map[wire.OutPoint][]byte)
+	for _, ps := range f.PrevScripts {
+		hash, err := chainhash.NewHashFromStr(ps.Txid)
+		if err != nil {
+			return err
+		}
+
+		script, err := hex.DecodeString(ps.PkScript)
+		if err != nil {
+			return err
+		}
+
+		prevScripts[*wire.NewOutPoint(hash, ps.Vout)] = script
+	}
+
+	redeemScripts := make([][]byte, 0, len(f.RedeemScripts))
+	for _, rs := range f.RedeemScripts {
+		script, err := hex.DecodeString(rs)
+		if err != nil {
+			return err
+		}
+
+		redeemScripts = append(redeemScripts, script)
+	}
+
+	p.Tx = tx
+	p.PrevScripts = prevScripts
+	p.RedeemScripts = redeemScripts
+	return nil
+}
+
+// WriteFile writes the PartialTx to the named file
+func (p *PartialTx) WriteFile(name string) error {
+	b, err := p.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, b, 0600)
+}
+
+// ReadPartialTx reads a PartialTx from the named file
+func ReadPartialTx(name string) (*PartialTx, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var p PartialTx
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partial transaction from %s: %s", name, err)
+	}
+
+	return &p, nil
+}
+
+// Signatures returns the lowest number of signatures present on any of the transaction's inputs, and the number of
+// signatures required by the redeem script of that input.
+func (p *PartialTx) Signatures() (int, int, error) {
+	have, required := -1, 0
+
+	for _, txIn := range p.Tx.TxIn {
+		n := 0
+		if len(txIn.SignatureScript) > 0 {
+			pushed, err := txscript.PushedData(txIn.SignatureScript)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			// The signature script is made of a dummy OP_0, the signatures, and the redeem script
+			if len(pushed) > 2 {
+				n = len(pushed) - 2
+			}
+		}
+
+		var redeemScript []byte
+		pkScript := p.PrevScripts[txIn.PreviousOutPoint]
+		for _, script := range p.RedeemScripts {
+			p2sh, err := payToScriptHashScript(script)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if bytes.Equal(pkScript, p2sh) {
+				redeemScript = script
+				break
+			}
+		}
+		if redeemScript == nil {
+			return 0, 0, fmt.Errorf("no redeem script for input %s", txIn.PreviousOutPoint)
+		}
+
+		_, nRequired, err := txscript.CalcMultiSigStats(redeemScript)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if have == -1 || n < have {
+			have = n
+			required = nRequired
+		}
+	}
+
+	if have == -1 {
+		have = 0
+	}
+
+	return have, required, nil
+}
+
+// IsComplete returns true if all of the transaction's inputs have enough valid signatures to be spent
+func (p *PartialTx) IsComplete() bool {
+	for i, txIn := range p.Tx.TxIn {
+		prevScript, ok := p.PrevScripts[txIn.PreviousOutPoint]
+		if !ok {
+			return false
+		}
+
+		vm, err := txscript.NewEngine(prevScript, p.Tx, i, txscript.StandardVerifyFlags, nil, nil, 0)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewMultisigSpend creates an unsigned transaction that spends multisig outputs from the matches to the destination.
+// Change is returned to the multisig address. The matches must be for multisig addresses registered in the wallet.
+func NewMultisigSpend(client *rpcclient.Client, w *wallet.Wallet, privPass string, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount) (*PartialTx, error) {
+	selected := SelectInputs(matches, amount, fee)
+
+	tx, err := newTransaction(client, selected, dest, amount, fee, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p := PartialTx{
+		Tx:          tx,
+		PrevScripts: makePrevScripts(selected),
+	}
+
+	// Look up the redeem scripts of the addresses being spent from, so that cosigners can sign without having
+	// registered the multisig address themselves.
+	err = unlock(w, privPass)
+	if err != nil {
+		return nil, err
+	}
+	defer lock(w)
+
+	seen := make(map[string]bool)
+	for _, m := range selected {
+		if seen[m.Address] {
+			continue
+		}
+		seen[m.Address] = true
+
+		addr, err := soterutil.DecodeAddress(m.Address, w.ChainParams())
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := w.AddressInfo(addr)
+		if err != nil {
+			return nil, fmt.Errorf("address %s isn't in the wallet: %s", m.Address, err)
+		}
+
+		sa, ok := info.(waddrmgr.ManagedScriptAddress)
+		if !ok {
+			return nil, fmt.Errorf("address %s isn't a multisig address", m.Address)
+		}
+
+		script, err := sa.Script()
+		if err != nil {
+			return nil, err
+		}
+
+		p.RedeemScripts = append(p.RedeemScripts, script)
+	}
+
+	return &p, nil
+}
+
+// hasMultisigKey returns true if the redeem script is a multisig script, with one of the wallet's keys
+func hasMultisigKey(w *wallet.Wallet, script []byte) (bool, error) {
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(script, w.ChainParams())
+	if err != nil {
+		return false, err
+	}
+	if class != txscript.MultiSigTy {
+		return false, nil
+	}
+
+	for _, addr := range addrs {
+		pubKey, ok := addr.(*soterutil.AddressPubKey)
+		if !ok {
+			continue
+		}
+
+		have, err := w.HaveAddress(pubKey.AddressPubKeyHash())
+		if err != nil {
+			return false, err
+		}
+		if have {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// multisigPrevScripts returns the previous output scripts of the transaction's inputs, after checking that every
+// input spends from a multisig address of the wallet, with one of the redeem scripts. The wallet signs any input
+// whose previous output script pays to one of its keys, so without the check a cosigner could add inputs that spend
+// the wallet's other coin.
+func multisigPrevScripts(w *wallet.Wallet, p *PartialTx, redeemScripts [][]byte) (map[wire.OutPoint][]byte, error) {
+	prevScripts := make(map[wire.OutPoint][]byte, len(p.Tx.TxIn))
+	for _, txIn := range p.Tx.TxIn {
+		op := txIn.PreviousOutPoint
+		pkScript, ok := p.PrevScripts[op]
+		if !ok {
+			return nil, fmt.Errorf("no previous output script for input %s", op)
+		}
+
+		var redeemScript []byte
+		for _, script := range redeemScripts {
+			p2sh, err := payToScriptHashScript(script)
+			if err != nil {
+				return nil, err
+			}
+
+			if bytes.Equal(pkScript, p2sh) {
+				redeemScript = script
+				break
+			}
+		}
+		if redeemScript == nil {
+			return nil, fmt.Errorf("input %s doesn't spend from a multisig address with one of the wallet's keys", op)
+		}
+
+		addr, err := soterutil.NewAddressScriptHash(redeemScript, w.ChainParams())
+		if err != nil {
+			return nil, err
+		}
+		have, err := w.HaveAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		if !have {
+			return nil, fmt.Errorf("input %s spends from multisig address %s, which isn't in the wallet", op, addr)
+		}
+
+		prevScripts[op] = pkScript
+	}
+
+	return prevScripts, nil
+}
+
+// SignPartialTx adds signatures from the wallet's keys to the transaction. Redeem scripts from the transaction that
+// have one of the wallet's keys, and aren't registered in the wallet yet, are registered first. Every input has to
+// spend from one of the wallet's multisig addresses, or nothing is signed. It returns true if the transaction has
+// enough signatures to be sent.
+func SignPartialTx(w *wallet.Wallet, privPass string, p *PartialTx) (bool, error) {
+	err := unlock(w, privPass)
+	if err != nil {
+		return false, err
+	}
+	defer lock(w)
+
+	// Scripts without any of the wallet's keys aren't registered, so that a file can't add addresses to the wallet
+	// that it has nothing to do with
+	redeemScripts := make([][]byte, 0, len(p.RedeemScripts))
+	for _, script := range p.RedeemScripts {
+		ours, err := hasMultisigKey(w, script)
+		if err != nil {
+			return false, fmt.Errorf("failed to check redeem script: %s", err)
+		}
+		if !ours {
+			continue
+		}
+
+		_, err = importScript(w, script)
+		if err != nil {
+			return false, fmt.Errorf("failed to register redeem script: %s", err)
+		}
+		redeemScripts = append(redeemScripts, script)
+	}
+
+	prevScripts, err := multisigPrevScripts(w, p, redeemScripts)
+	if err != nil {
+		return false, fmt.Errorf("refusing to sign transaction: %s", err)
+	}
+
+	// Inputs without enough signatures are reported as signature errors, which is expected until the last cosigner
+	// has signed.
+	_, err = w.SignTransaction(p.Tx, txscript.SigHashAll, prevScripts, nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("Failed to sign transaction: %s", err)
+	}
+
+	return p.IsComplete(), nil
+}
+
+// SendPartialTx sends a fully-signed multisig transaction to the network
+func SendPartialTx(client *rpcclient.Client, w *wallet.Wallet, p *PartialTx) (*chainhash.Hash, error) {
+	if !p.IsComplete() {
+		have, required, err := p.Signatures()
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("transaction doesn't have enough signatures yet; have %d, need %d", have, required)
+	}
+
+	return broadcast(client, w, p.Tx, 0, nil)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"testing"
+)
+
+// testPubKey creates a new address in the wallet, and returns its hex-encoded public key
+func testPubKey(t *testing.T, w *wallet.Wallet) string {
+	addr, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	pubKey, err := w.PubKeyForAddress(addr)
+	if err != nil {
+		t.Fatalf("failed to get public key of address %s: %s", addr, err)
+	}
+
+	return hex.EncodeToString(pubKey.SerializeCompressed())
+}
+
+func TestMultisig(t *testing.T) {
+	w1, _, cleanup1 := createTestWallet(t, "priv1", "pub1")
+	defer cleanup1()
+	w2, _, cleanup2 := createTestWallet(t, "priv2", "pub2")
+	defer cleanup2()
+
+	keys := []string{testPubKey(t, w1), testPubKey(t, w2)}
+
+	_, _, err := NewMultisigAddress(w1, "priv1", keys, 3)
+	if err == nil {
+		t.Errorf("created a multisig address requiring more signatures than keys")
+	}
+
+	addr, redeemScript, err := NewMultisigAddress(w1, "priv1", keys, 2)
+	if err != nil {
+		t.Fatalf("failed to create multisig address: %s", err)
+	}
+
+	multisig, err := MultisigAddresses(w1)
+	if err != nil {
+		t.Fatalf("failed to list multisig addresses: %s", err)
+	}
+	if len(multisig) != 1 || multisig[0].EncodeAddress() != addr.EncodeAddress() {
+		t.Fatalf("wrong multisig addresses; got %v, want [%s]", multisig, addr)
+	}
+
+	// Spend an output paying to the multisig address
+	pkScript, err := payToScriptHashScript(redeemScript)
+	if err != nil {
+		t.Fatalf("failed to create p2sh script: %s", err)
+	}
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxOut(wire.NewTxOut(5000, pkScript))
+	fundingHash := funding.TxHash()
+	op := wire.NewOutPoint(&fundingHash, 0)
+
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(op, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(4000, pkScript))
+
+	p := &PartialTx{
+		Tx:            spend,
+		PrevScripts:   map[wire.OutPoint][]byte{*op: pkScript},
+		RedeemScripts: [][]byte{redeemScript},
+	}
+
+	complete, err := SignPartialTx(w1, "priv1", p)
+	if err != nil {
+		t.Fatalf("failed to sign partial transaction: %s", err)
+	}
+	if complete {
+		t.Fatalf("partial transaction complete after one of two signatures")
+	}
+
+	have, required, err := p.Signatures()
+	if err != nil {
+		t.Fatalf("failed to count signatures: %s", err)
+	}
+	if have != 1 || required != 2 {
+		t.Errorf("wrong signature count; got %d of %d, want 1 of 2", have, required)
+	}
+
+	// Pass the transaction to the other cosigner, who hasn't registered the multisig address yet
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to serialize partial transaction: %s", err)
+	}
+	var received PartialTx
+	err = json.Unmarshal(b, &received)
+	if err != nil {
+		t.Fatalf("failed to deserialize partial transaction: %s", err)
+	}
+
+	complete, err = SignPartialTx(w2, "priv2", &received)
+	if err != nil {
+		t.Fatalf("failed to sign partial transaction: %s", err)
+	}
+	if !complete {
+		have, required, _ := received.Signatures()
+		t.Fatalf("partial transaction incomplete after both signatures; have %d of %d", have, required)
+	}
+
+	multisig, err = MultisigAddresses(w2)
+	if err != nil {
+		t.Fatalf("failed to list multisig addresses: %s", err)
+	}
+	if len(multisig) != 1 {
+		t.Errorf("multisig address wasn't registered by cosigner")
+	}
+}
+
+func TestSignPartialTxRejects(t *testing.T) {
+	w1, _, cleanup1 := createTestWallet(t, "priv1", "pub1")
+	defer cleanup1()
+	w2, _, cleanup2 := createTestWallet(t, "priv2", "pub2")
+	defer cleanup2()
+	w3, _, cleanup3 := createTestWallet(t, "priv3", "pub3")
+	defer cleanup3()
+
+	keys := []string{testPubKey(t, w1), testPubKey(t, w2)}
+	_, redeemScript, err := NewMultisigAddress(w1, "priv1", keys, 2)
+	if err != nil {
+		t.Fatalf("failed to create multisig address: %s", err)
+	}
+	pkScript, err := payToScriptHashScript(redeemScript)
+	if err != nil {
+		t.Fatalf("failed to create p2sh script: %s", err)
+	}
+
+	// An ordinary output of the second cosigner, which isn't part of the multisig address
+	own, err := NewAddress(w2, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	ownScript, err := txscript.PayToAddrScript(own)
+	if err != nil {
+		t.Fatalf("failed to create p2pkh script: %s", err)
+	}
+
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxOut(wire.NewTxOut(5000, pkScript))
+	funding.AddTxOut(wire.NewTxOut(9000, ownScript))
+	fundingHash := funding.TxHash()
+	multisigOp := wire.NewOutPoint(&fundingHash, 0)
+	ownOp := wire.NewOutPoint(&fundingHash, 1)
+
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(multisigOp, nil, nil))
+	spend.AddTxIn(wire.NewTxIn(ownOp, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(13000, pkScript))
+
+	p := &PartialTx{
+		Tx:            spend,
+		PrevScripts:   map[wire.OutPoint][]byte{*multisigOp: pkScript, *ownOp: ownScript},
+		RedeemScripts: [][]byte{redeemScript},
+	}
+	_, err = SignPartialTx(w2, "priv2", p)
+	if err == nil {
+		t.Errorf("signed a partial transaction spending an output that isn't multisig")
+	}
+	for i, txIn := range p.Tx.TxIn {
+		if len(txIn.SignatureScript) > 0 {
+			t.Errorf("input %d of a rejected transaction was signed", i)
+		}
+	}
+
+	// A wallet without any of the multisig keys doesn't register the address, or sign
+	spend = wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(multisigOp, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(4000, pkScript))
+	p = &PartialTx{
+		Tx:            spend,
+		PrevScripts:   map[wire.OutPoint][]byte{*multisigOp: pkScript},
+		RedeemScripts: [][]byte{redeemScript},
+	}
+	_, err = SignPartialTx(w3, "priv3", p)
+	if err == nil {
+		t.Errorf("signed a partial transaction with a wallet that has none of the multisig keys")
+	}
+	multisig, err := MultisigAddresses(w3)
+	if err != nil {
+		t.Fatalf("failed to list multisig addresses: %s", err)
+	}
+	if len(multisig) != 0 {
+		t.Errorf("redeem script without any of the wallet's keys was registered; got %v", multisig)
+	}
+}
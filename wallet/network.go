@@ -0,0 +1,178 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"sync"
+)
+
+// ErrUnknownWalletNetwork is returned when a wallet doesn't record its network, and the network can't be detected
+var ErrUnknownWalletNetwork = errors.New("the wallet doesn't record its network, and it can't be detected; choose its network")
+
+var (
+	// The wallet db bucket that the name and magic of the wallet's network are stored in
+	networkNamespaceKey = []byte("sotertoolsnetwork")
+	networkNameKey      = []byte("name")
+	networkNetKey       = []byte("net")
+
+	// networks are the params of the networks that wallets can be for. Custom networks are added by RegisterNetwork.
+	networks = []*chaincfg.Params{
+		&chaincfg.MainNetParams,
+		&chaincfg.TestNet1Params,
+		&chaincfg.SimNetParams,
+		&chaincfg.RegressionNetParams,
+	}
+	networksMtx sync.Mutex
+)
+
+// RegisterNetwork registers the params of a custom network with chaincfg, so that its addresses and keys can be
+// decoded, and so that NetworkParams can find it by name.
+func RegisterNetwork(params *chaincfg.Params) error {
+	networksMtx.Lock()
+	defer networksMtx.Unlock()
+
+	for _, n := range networks {
+		if n.Name == params.Name {
+			return fmt.Errorf("network %s is already registered", params.Name)
+		}
+	}
+
+	err := chaincfg.Register(params)
+	if err == chaincfg.ErrDuplicateNet {
+		return fmt.Errorf("network %s uses the magic %s of another registered network", params.Name, params.Net)
+	}
+	if err != nil {
+		return err
+	}
+
+	networks = append(networks, params)
+	return nil
+}
+
+// NetworkParams returns the params of the named network
+func NetworkParams(name string) (*chaincfg.Params, error) {
+	networksMtx.Lock()
+	defer networksMtx.Unlock()
+
+	for _, params := range networks {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown network %s", name)
+}
+
+// putNetwork records the wallet's network in the wallet db
+func putNetwork(tx walletdb.ReadWriteTx, params *chaincfg.Params) error {
+	ns, err := tx.CreateTopLevelBucket(networkNamespaceKey)
+	if err != nil {
+		return err
+	}
+
+	err = ns.Put(networkNameKey, []byte(params.Name))
+	if err != nil {
+		return err
+	}
+
+	net := make([]byte, 4)
+	binary.LittleEndian.PutUint32(net, uint32(params.Net))
+	return ns.Put(networkNetKey, net)
+}
+
+// genesisNetworks returns the networks whose genesis block has the hash
+func genesisNetworks(hash []byte) []*chaincfg.Params {
+	networksMtx.Lock()
+	defer networksMtx.Unlock()
+
+	matches := make([]*chaincfg.Params, 0)
+	for _, params := range networks {
+		if params.GenesisHash != nil && string(params.GenesisHash[:]) == string(hash) {
+			matches = append(matches, params)
+		}
+	}
+
+	return matches
+}
+
+// checkNetwork returns the network of the wallet db, and true if it isn't recorded yet. If want isn't nil, it's
+// checked to be the wallet's network.
+//
+// Wallets created before their network was recorded are detected by the genesis block that their address manager
+// started from. If several networks have that genesis block, the wanted network is trusted to be one of them.
+func checkNetwork(tx walletdb.ReadTx, want *chaincfg.Params) (*chaincfg.Params, bool, error) {
+	ns := tx.ReadBucket(networkNamespaceKey)
+	if ns != nil {
+		name := string(ns.Get(networkNameKey))
+		netBytes := ns.Get(networkNetKey)
+		if len(name) == 0 || len(netBytes) != 4 {
+			// The record can be incomplete if creating the wallet was interrupted
+			return nil, false, fmt.Errorf("wallet's network record is corrupt; name %q, magic %x", name, netBytes)
+		}
+		net := wire.SoterNet(binary.LittleEndian.Uint32(netBytes))
+		if want != nil && (want.Name != name || want.Net != net) {
+			return nil, false, &NetworkMismatchError{Of: "wallet", Network: name, Want: want.Name}
+		}
+
+		params, err := NetworkParams(name)
+		if err != nil {
+			return nil, false, fmt.Errorf("wallet is for the %s network, which isn't loaded; give its network file", name)
+		}
+		if params.Net != net {
+			return nil, false, fmt.Errorf("wallet is for a %s network with magic %s, but the loaded one has magic %s",
+				name, net, params.Net)
+		}
+
+		return params, false, nil
+	}
+
+	start, err := waddrmgr.FetchStartBlock(tx.ReadBucket(waddrmgrNamespaceKey))
+	if err != nil {
+		return nil, false, err
+	}
+	matches := genesisNetworks(start.Hash[:])
+
+	if want != nil {
+		for _, params := range matches {
+			if params == want {
+				return want, true, nil
+			}
+		}
+		if len(matches) == 1 {
+			return nil, false, &NetworkMismatchError{Of: "wallet", Network: matches[0].Name, Want: want.Name}
+		}
+		return nil, false, &NetworkMismatchError{Of: "wallet", Want: want.Name}
+	}
+
+	if len(matches) != 1 {
+		return nil, false, ErrUnknownWalletNetwork
+	}
+
+	return matches[0], true, nil
+}
+
+// WalletNetwork returns the params of the network that the named wallet is for
+func WalletNetwork(name string) (*chaincfg.Params, error) {
+	db, err := walletdb.Open(walletDbType, name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open wallet db %s: %s", name, err)
+	}
+	defer db.Close()
+
+	var params *chaincfg.Params
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		params, _, err = checkNetwork(tx, nil)
+		return err
+	})
+
+	return params, err
+}
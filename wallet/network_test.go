@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"strings"
+	"testing"
+)
+
+func TestWalletNetwork(t *testing.T) {
+	w, name, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+	_ = w.Database().Close()
+
+	params, err := WalletNetwork(name)
+	if err != nil {
+		t.Fatalf("failed to get network of wallet: %s", err)
+	}
+	if params != &chaincfg.SimNetParams {
+		t.Errorf("wrong network of wallet; got %s, want %s", params.Name, chaincfg.SimNetParams.Name)
+	}
+
+	// Opening the wallet with the params of another network is an error
+	_, err = OpenWallet(name, "pub", &chaincfg.TestNet1Params)
+	if err == nil {
+		t.Errorf("opened %s wallet with %s params", chaincfg.SimNetParams.Name, chaincfg.TestNet1Params.Name)
+	}
+
+	// Without params, the wallet's network is used
+	w, err = OpenWallet(name, "pub", nil)
+	if err != nil {
+		t.Fatalf("failed to open wallet without params: %s", err)
+	}
+	if w.ChainParams() != &chaincfg.SimNetParams {
+		t.Errorf("wrong params of wallet; got %s, want %s", w.ChainParams().Name, chaincfg.SimNetParams.Name)
+	}
+
+	// The network of a wallet created before it was recorded is detected, and recorded when the wallet is opened
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket(networkNamespaceKey)
+	})
+	if err != nil {
+		t.Fatalf("failed to delete network of wallet: %s", err)
+	}
+	_ = w.Database().Close()
+
+	params, err = WalletNetwork(name)
+	if err != nil {
+		t.Fatalf("failed to detect network of wallet: %s", err)
+	}
+	if params != &chaincfg.SimNetParams {
+		t.Errorf("wrong detected network of wallet; got %s, want %s", params.Name, chaincfg.SimNetParams.Name)
+	}
+
+	_, err = OpenWallet(name, "pub", &chaincfg.MainNetParams)
+	if err == nil {
+		t.Errorf("opened %s wallet with %s params", chaincfg.SimNetParams.Name, chaincfg.MainNetParams.Name)
+	}
+
+	w, err = OpenWallet(name, "pub", &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to open wallet: %s", err)
+	}
+	err = walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		if tx.ReadBucket(networkNamespaceKey) == nil {
+			t.Errorf("network of wallet not recorded when opened")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("failed to read wallet: %s", err)
+	}
+
+	// An incomplete network record is an error, rather than a panic
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket(networkNamespaceKey).Delete(networkNetKey)
+	})
+	if err != nil {
+		t.Fatalf("failed to delete network magic of wallet: %s", err)
+	}
+	_ = w.Database().Close()
+
+	_, err = WalletNetwork(name)
+	if err == nil || !strings.Contains(err.Error(), "network record is corrupt") {
+		t.Errorf("wrong error of wallet with corrupt network record: %v", err)
+	}
+}
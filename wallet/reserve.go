@@ -0,0 +1,124 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"fmt"
+	"github.com/soteria-dag/soterd/wire"
+	"sync"
+	"time"
+)
+
+// Reserver tracks outputs that have been selected as inputs for in-flight transactions, so that concurrent sends
+// from the same wallet don't pick the same outputs and double-spend each other.
+//
+// A reservation is held until it is released (for example because the transaction was rejected), or until its
+// timeout expires. Reservations for accepted transactions are expected to be left to expire, so that the outputs
+// aren't selected again while the transaction is waiting to be included in a block.
+type Reserver struct {
+	mtx      sync.Mutex
+	timeout  time.Duration
+	reserved map[wire.OutPoint]time.Time
+}
+
+// NewReserver returns a Reserver whose reservations expire after the given timeout
+func NewReserver(timeout time.Duration) *Reserver {
+	return &Reserver{
+		timeout:  timeout,
+		reserved: make(map[wire.OutPoint]time.Time),
+	}
+}
+
+// matchOutPoint returns the outpoint of the output referenced by the match
+func matchOutPoint(m TxMatch) wire.OutPoint {
+	return wire.OutPoint{
+		Hash:  m.Info.Tx.TxHash(),
+		Index: uint32(m.VIndex),
+	}
+}
+
+// isReserved returns true if the outpoint has a reservation that hasn't expired yet.
+// Expired reservations are removed. The caller must hold the mutex.
+func (r *Reserver) isReserved(op wire.OutPoint, now time.Time) bool {
+	expires, ok := r.reserved[op]
+	if !ok {
+		return false
+	}
+
+	if now.After(expires) {
+		delete(r.reserved, op)
+		return false
+	}
+
+	return true
+}
+
+// IsReserved returns true if the outpoint is currently reserved
+func (r *Reserver) IsReserved(op wire.OutPoint) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.isReserved(op, time.Now())
+}
+
+// Available returns the matches whose outputs aren't currently reserved
+func (r *Reserver) Available(matches []TxMatch) []TxMatch {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := time.Now()
+	available := make([]TxMatch, 0, len(matches))
+	for _, m := range matches {
+		if r.isReserved(matchOutPoint(m), now) {
+			continue
+		}
+
+		available = append(available, m)
+	}
+
+	return available
+}
+
+// Reserve reserves the outputs of all of the matches. If any of the outputs are already reserved, none of them are
+// reserved and an error is returned.
+func (r *Reserver) Reserve(matches []TxMatch) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := time.Now()
+	for _, m := range matches {
+		op := matchOutPoint(m)
+		if r.isReserved(op, now) {
+			return fmt.Errorf("output %s is reserved by another transaction", op)
+		}
+	}
+
+	expires := now.Add(r.timeout)
+	for _, m := range matches {
+		r.reserved[matchOutPoint(m)] = expires
+	}
+
+	return nil
+}
+
+// Release removes reservations for the outputs of the matches, making them available for other transactions
+func (r *Reserver) Release(matches []TxMatch) {
+	ops := make([]wire.OutPoint, len(matches))
+	for i, m := range matches {
+		ops[i] = matchOutPoint(m)
+	}
+
+	r.ReleaseOutPoints(ops)
+}
+
+// ReleaseOutPoints removes reservations for the outpoints, making them available for other transactions
+func (r *Reserver) ReleaseOutPoints(ops []wire.OutPoint) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, op := range ops {
+		delete(r.reserved, op)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/wire"
+	"sync"
+	"testing"
+	"time"
+)
+
+// makeMatches returns matches for outputs of a single transaction, with the given amounts
+func makeMatches(amounts ...soterutil.Amount) []TxMatch {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, amt := range amounts {
+		tx.AddTxOut(wire.NewTxOut(int64(amt), []byte{}))
+	}
+
+	info := &TxInfo{Tx: tx}
+	matches := make([]TxMatch, len(amounts))
+	for i, amt := range amounts {
+		matches[i] = TxMatch{
+			Amount: amt,
+			VIndex: i,
+			Info:   info,
+		}
+	}
+
+	return matches
+}
+
+func TestReserver(t *testing.T) {
+	matches := makeMatches(10, 20, 30)
+	r := NewReserver(time.Hour)
+
+	err := r.Reserve(matches[:2])
+	if err != nil {
+		t.Fatalf("failed to reserve outputs: %s", err)
+	}
+
+	available := r.Available(matches)
+	if len(available) != 1 || available[0].VIndex != 2 {
+		t.Fatalf("wrong available outputs; got %d, want 1 (index 2)", len(available))
+	}
+
+	// Reserving an overlapping set should fail, and not reserve anything
+	err = r.Reserve(matches[1:])
+	if err == nil {
+		t.Fatalf("reserved an output that was already reserved")
+	}
+	if r.IsReserved(matchOutPoint(matches[2])) {
+		t.Errorf("output was reserved by a failed reservation")
+	}
+
+	r.Release(matches[:1])
+	available = r.Available(matches)
+	if len(available) != 2 {
+		t.Errorf("wrong number of available outputs after release; got %d, want 2", len(available))
+	}
+}
+
+func TestReserverTimeout(t *testing.T) {
+	matches := makeMatches(10)
+	r := NewReserver(time.Millisecond)
+
+	err := r.Reserve(matches)
+	if err != nil {
+		t.Fatalf("failed to reserve outputs: %s", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if r.IsReserved(matchOutPoint(matches[0])) {
+		t.Errorf("output still reserved after timeout")
+	}
+	err = r.Reserve(matches)
+	if err != nil {
+		t.Errorf("failed to reserve outputs after timeout: %s", err)
+	}
+}
+
+func TestReserverConcurrent(t *testing.T) {
+	matches := makeMatches(10, 20, 30, 40)
+	r := NewReserver(time.Hour)
+
+	// Only one of the goroutines reserving the same outputs should succeed
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- r.Reserve(matches)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("wrong number of successful reservations; got %d, want 1", succeeded)
+	}
+}
+
+func TestSelectInputs(t *testing.T) {
+	matches := makeMatches(10, 20, 30)
+
+	selected := SelectInputs(matches, 15, 5)
+	if len(selected) != 2 {
+		t.Errorf("wrong number of selected inputs; got %d, want 2", len(selected))
+	}
+
+	selected = SelectInputs(matches, 10, 0)
+	if len(selected) != 1 {
+		t.Errorf("wrong number of selected inputs; got %d, want 1", len(selected))
+	}
+}
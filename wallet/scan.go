@@ -5,7 +5,6 @@
 package wallet
 
 import (
-	"fmt"
 	"github.com/soteria-dag/soterd/chaincfg"
 	"github.com/soteria-dag/soterd/chaincfg/chainhash"
 	"github.com/soteria-dag/soterd/rpcclient"
@@ -43,19 +42,19 @@ func AllTransactions(client *rpcclient.Client) ([]TxInfo, error) {
 
 	tips, err := client.GetDAGTips()
 	if err != nil {
-		return transactions, err
+		return transactions, rpcError(err)
 	}
 
 	for height := int32(0); height <= tips.MaxHeight; height++ {
 		hashes, err := client.GetBlockHash(int64(height))
 		if err != nil {
-			return transactions, err
+			return transactions, rpcError(err)
 		}
 
 		for _, hash := range hashes {
 			block, err := client.GetBlock(hash)
 			if err != nil {
-				return transactions, err
+				return transactions, rpcError(err)
 			}
 
 			for i, tx := range block.Transactions {
@@ -74,6 +73,91 @@ func AllTransactions(client *rpcclient.Client) ([]TxInfo, error) {
 	return transactions, nil
 }
 
+// FindTxs looks for the transactions in blocks from the dag tips down, and stops once all of them are found. It
+// returns the transactions that were found, by hash.
+func FindTxs(client *rpcclient.Client, hashes []chainhash.Hash) (map[chainhash.Hash]TxInfo, error) {
+	found := make(map[chainhash.Hash]TxInfo, len(hashes))
+	wanted := make(map[chainhash.Hash]bool, len(hashes))
+	for _, h := range hashes {
+		wanted[h] = true
+	}
+	if len(wanted) == 0 {
+		return found, nil
+	}
+
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return found, rpcError(err)
+	}
+
+	for height := tips.MaxHeight; height >= 0; height-- {
+		blockHashes, err := client.GetBlockHash(int64(height))
+		if err != nil {
+			return found, rpcError(err)
+		}
+
+		for _, hash := range blockHashes {
+			block, err := client.GetBlock(hash)
+			if err != nil {
+				return found, rpcError(err)
+			}
+
+			for i, tx := range block.Transactions {
+				h := tx.TxHash()
+				if !wanted[h] {
+					continue
+				}
+
+				found[h] = TxInfo{
+					Tx:          tx,
+					Block:       block,
+					Index:       i,
+					BlockHeight: height,
+				}
+				delete(wanted, h)
+			}
+		}
+
+		if len(wanted) == 0 {
+			break
+		}
+	}
+
+	return found, nil
+}
+
+// SpentOutputs returns the outputs spent by the transaction's inputs, by looking for them in the dag. An input that
+// spends an output that isn't found is a MissingPrevTxError. A coinbase input doesn't spend an output.
+func SpentOutputs(client *rpcclient.Client, tx *wire.MsgTx) (map[wire.OutPoint]*wire.TxOut, error) {
+	hashes := make([]chainhash.Hash, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		if !isCoinbaseInput(txIn) {
+			hashes = append(hashes, txIn.PreviousOutPoint.Hash)
+		}
+	}
+
+	prevs, err := FindTxs(client, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[wire.OutPoint]*wire.TxOut, len(hashes))
+	for i, txIn := range tx.TxIn {
+		if isCoinbaseInput(txIn) {
+			continue
+		}
+
+		op := txIn.PreviousOutPoint
+		prev, ok := prevs[op.Hash]
+		if !ok || int(op.Index) >= len(prev.Tx.TxOut) {
+			return nil, &MissingPrevTxError{PrevTx: op.Hash, Tx: tx.TxHash(), Input: i}
+		}
+		spent[op] = prev.Tx.TxOut[op.Index]
+	}
+
+	return spent, nil
+}
+
 // IsAddressIn returns true if the given address is in the set of addresses
 func IsAddressIn(address soterutil.Address, set []soterutil.Address) bool {
 	for _, member := range set {
@@ -126,8 +210,7 @@ func GetBalance(client *rpcclient.Client, addresses []soterutil.Address, params
 
 			prev, ok := txIndex[txIn.PreviousOutPoint.Hash]
 			if !ok {
-				err := fmt.Errorf("missing previous transaction %s for transaction %s input %d",
-					txIn.PreviousOutPoint.Hash, info.Tx.TxHash(), i)
+				err := &MissingPrevTxError{PrevTx: txIn.PreviousOutPoint.Hash, Tx: info.Tx.TxHash(), Input: i}
 				return balance, spendableBalance, err
 			}
 
@@ -193,7 +276,7 @@ func SpendableTxOuts(client *rpcclient.Client, addresses []soterutil.Address, pa
 
 	tips, err := client.GetDAGTips()
 	if err != nil {
-		return nil, err
+		return nil, rpcError(err)
 	}
 
 	transactions, err = AllTransactions(client)
@@ -14,6 +14,7 @@ import (
 	"github.com/soteria-dag/soterd/wire"
 	"github.com/soteria-dag/soterwallet/wallet"
 	"github.com/soteria-dag/soterwallet/walletdb"
+	"strings"
 )
 
 var (
@@ -30,6 +31,23 @@ func absAmount(amt soterutil.Amount) soterutil.Amount {
 	}
 }
 
+// SelectInputs returns the matches that would be used as inputs for a transaction sending the desired amount plus fee.
+// Matches are used in the order they're given, until their total amount covers the desired amount and fee.
+func SelectInputs(matches []TxMatch, desired, fee soterutil.Amount) []TxMatch {
+	selected := make([]TxMatch, 0)
+
+	remaining := desired + fee
+	for _, m := range matches {
+		selected = append(selected, m)
+		remaining -= m.Amount
+		if remaining <= soterutil.Amount(0) {
+			break
+		}
+	}
+
+	return selected
+}
+
 // makeTxInputs creates some transaction inputs that can be used in the createrawtransaction RPC call
 func makeTxInputs(matches []TxMatch, desired, fee soterutil.Amount) []soterjson.TransactionInput {
 	txIns := make([]soterjson.TransactionInput, 0)
@@ -104,49 +122,176 @@ func makePrevScripts(matches []TxMatch) map[wire.OutPoint][]byte {
 	return prevScripts
 }
 
+// SendOptions holds optional settings for transactions created by SendWithOptions
+type SendOptions struct {
+	// Data to embed in a null-data (OP_RETURN) output of the transaction, if set
+	Data []byte
+
+	// LockTime is the earliest dag height (below txscript.LockTimeThreshold) or unix timestamp at which the
+	// transaction can be included in a block, if set. Use LockTimeFromHeight or LockTimeFromTime to create it.
+	LockTime uint32
+	// RelativeLock is the number of confirmations each input's output needs before the transaction can be included
+	// in a block (a BIP0068 sequence lock), if set.
+	RelativeLock uint32
+}
+
 // newTransaction returns a raw transaction that can be signed and sent to the soter network
-func newTransaction(client *rpcclient.Client, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount) (*wire.MsgTx, error) {
+func newTransaction(client *rpcclient.Client, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount, opts *SendOptions) (*wire.MsgTx, error) {
+	var dataOut *wire.TxOut
+	if opts != nil && len(opts.Data) > 0 {
+		// Check the data before asking the node to create the transaction
+		var err error
+		dataOut, err = NullDataOutput(opts.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	total := soterutil.Amount(0)
+	for _, m := range matches {
+		total += m.Amount
+	}
+	if amount+fee > total {
+		return nil, &InsufficientFundsError{Needed: amount + fee, Available: total}
+	}
+
 	txIns := makeTxInputs(matches, amount, fee)
 	txAmts := makeTxAmts(matches, dest, amount, fee)
 	// Have the soterd node translate our inputs and amounts into a raw transaction
-	return client.CreateRawTransaction(txIns, txAmts, nil)
-}
+	tx, err := client.CreateRawTransaction(txIns, txAmts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("createrawtransaction RPC call failed: %w", rpcError(err))
+	}
 
-// Send creates a new transaction to send coin to the given address, signs it, and sends it to the network via the rpc client.
-func Send(client *rpcclient.Client, w *wallet.Wallet, privPass string, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount) (*chainhash.Hash, error) {
-	// Create a new transaction
-	tx, err := newTransaction(client, matches, dest, amount, fee)
+	// createrawtransaction only creates outputs paying to addresses, so we add the null-data output ourselves.
+	// This happens before signing, so that the signatures commit to it.
+	if dataOut != nil {
+		tx.AddTxOut(dataOut)
+	}
+
+	err = applyLocks(tx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("createrawtransaction RPC call failed: %s", err)
+		return nil, err
 	}
 
-	// Build a map of scripts from the outputs that are used as inputs in the new transaction.
-	// This is so that the w.SignTransaction method won't attempt to look up this information in its own records,
-	// which it won't have because we aren't running a full soterwallet node.
-	prevScripts := makePrevScripts(matches)
+	return tx, nil
+}
 
-	err = walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+// unlock unlocks the wallet's address manager, so that private keys and scripts can be used
+func unlock(w *wallet.Wallet, privPass string) error {
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
 		addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
 		return w.Manager.Unlock(addrmgrNs, []byte(privPass))
 	})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to unlock wallet: %s", err)
+		return fmt.Errorf("Failed to unlock wallet: %w", managerError(err))
 	}
+	setUnlocked(w.Manager)
+
+	return nil
+}
+
+// lock locks the wallet's address manager again, clearing its private keys from memory
+func lock(w *wallet.Wallet) {
+	// The manager may already be locked, which isn't a problem
+	_ = w.Manager.Lock()
+	setLocked(w.Manager)
+}
+
+// signTx unlocks the wallet and signs the transaction's inputs, locking the wallet again afterwards. prevScripts must
+// contain the scripts of the outputs that are used as inputs in the transaction. It fails if any input couldn't be
+// signed, naming the inputs.
+func signTx(w *wallet.Wallet, privPass string, tx *wire.MsgTx, prevScripts map[wire.OutPoint][]byte) error {
+	err := unlock(w, privPass)
+	if err != nil {
+		return err
+	}
+	defer lock(w)
 
 	// Sign the transaction
 	invalidSigs, err := w.SignTransaction(tx, txscript.SigHashAll, prevScripts, nil, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to sign transaction: %s", err)
+		return fmt.Errorf("Failed to sign transaction: %w", managerError(err))
+	}
+
+	if len(invalidSigs) > 0 {
+		// The node would reject a transaction with unsigned inputs, so it fails here instead of being sent
+		unsigned := make([]string, 0, len(invalidSigs))
+		for _, e := range invalidSigs {
+			unsigned = append(unsigned, fmt.Sprintf("%d (%s)", e.InputIndex, e.Error))
+		}
+		return fmt.Errorf("Failed to sign transaction inputs %s", strings.Join(unsigned, ", "))
 	}
 
-	for _, e := range invalidSigs {
-		fmt.Printf("Unsigned input at index: %d\n", e.InputIndex)
+	return nil
+}
+
+// broadcast records the signed transaction in the wallet's store of sent transactions, and sends it to the network.
+// If the node doesn't accept the transaction, it's removed from the store again.
+func broadcast(client *rpcclient.Client, w *wallet.Wallet, tx *wire.MsgTx, minHeight int32, parent *chainhash.Hash) (*chainhash.Hash, error) {
+	height := minHeight
+	tips, err := client.GetDAGTips()
+	if err == nil {
+		height = tips.MaxHeight
+	}
+
+	sent, err := RecordSentTx(w, tx, height, parent)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to record transaction: %s", err)
 	}
 
 	txHash, err := client.SendRawTransaction(tx, false)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to send transaction to network: %s", err)
+		_ = deleteSentTx(w, &sent.Hash)
+		return nil, fmt.Errorf("Failed to send transaction to network: %w", sendError(sent.Hash, err))
 	}
 
 	return txHash, nil
-}
\ No newline at end of file
+}
+
+// Send creates a new transaction to send coin to the given address, signs it, and sends it to the network via the rpc client.
+// The transaction is recorded in the wallet's store of sent transactions, so that it can be rebroadcast or abandoned later.
+func Send(client *rpcclient.Client, w *wallet.Wallet, privPass string, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount) (*chainhash.Hash, error) {
+	return SendWithOptions(client, w, privPass, matches, dest, amount, fee, nil)
+}
+
+// SendWithOptions is like Send, but applies the optional settings in opts to the new transaction. opts may be nil.
+//
+// If opts has a lock time that hasn't passed yet, the node won't accept the transaction. It's only recorded in the
+// wallet's store of sent transactions, with a zero LastBroadcast, and can be broadcast with Rebroadcast once the lock
+// time passes.
+func SendWithOptions(client *rpcclient.Client, w *wallet.Wallet, privPass string, matches []TxMatch, dest soterutil.Address, amount, fee soterutil.Amount, opts *SendOptions) (*chainhash.Hash, error) {
+	// Create a new transaction
+	tx, err := newTransaction(client, matches, dest, amount, fee, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build a map of scripts from the outputs that are used as inputs in the new transaction.
+	// This is so that the w.SignTransaction method won't attempt to look up this information in its own records,
+	// which it won't have because we aren't running a full soterwallet node.
+	prevScripts := makePrevScripts(matches)
+
+	err = signTx(w, privPass, tx, prevScripts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.LockTime > 0 {
+		tips, err := client.GetDAGTips()
+		if err != nil {
+			return nil, rpcError(err)
+		}
+
+		now, err := MedianTime(client)
+		if err != nil {
+			return nil, err
+		}
+
+		if !TxLockTimeMet(tx, tips.MaxHeight+1, now) {
+			return holdTx(client, w, tx)
+		}
+	}
+
+	return broadcast(client, w, tx, 0, nil)
+}
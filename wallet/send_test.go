@@ -5,77 +5,24 @@
 package wallet
 
 import (
+	"context"
 	"fmt"
 	"github.com/soteria-dag/soterd/chaincfg"
 	"github.com/soteria-dag/soterd/chaincfg/chainhash"
 	"github.com/soteria-dag/soterd/integration/rpctest"
-	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterec"
 	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
 	"github.com/soteria-dag/soterd/wire"
 	"github.com/soteria-dag/soterwallet/waddrmgr"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
 
-// waitForTx waits for the given transaction hash to appear in a block. It returns:
-// - Whether the transaction was found within wait time (bool)
-// - The block the transaction was found in
-// - The height of the first block the transaction was found in
-// - An error, if an error was encountered during the search
-func waitForTx(c *rpcclient.Client, txHash *chainhash.Hash, startHeight int32, wait time.Duration) (bool, *wire.MsgBlock, int32, error) {
-	pollInterval := time.Duration(time.Second * 4)
-	waitThreshold := time.Now().Add(wait)
-
-	pollCount := 0
-	for {
-		if pollCount > 0 {
-			if time.Now().Before(waitThreshold) {
-				time.Sleep(pollInterval)
-			} else {
-				timeout := fmt.Errorf("Timeout while waiting for transaction")
-				return false, nil, -1, timeout
-			}
-		}
-		pollCount++
-
-		tips, err := c.GetDAGTips()
-		if err != nil {
-			continue
-		}
-
-		// Look at blocks from starting height to tips for transaction
-	AFTERNAP:
-		for height := tips.MaxHeight; height >= 0; height-- {
-			hashes, err := c.GetBlockHash(int64(height))
-			if err != nil {
-				continue AFTERNAP
-			}
-
-			for _, hash := range hashes {
-				block, err := c.GetBlock(hash)
-				if err != nil {
-					continue AFTERNAP
-				}
-
-				txs := make([]string, 0)
-				for _, tx := range block.Transactions {
-					txs = append(txs, tx.TxHash().String())
-				}
-
-				for _, tx := range block.Transactions {
-					h := tx.TxHash()
-					if h.IsEqual(txHash) {
-						return true, block, height, nil
-					}
-				}
-			}
-		}
-	}
-}
-
 func TestSend(t *testing.T) {
 	var activeNet = &chaincfg.SimNetParams
 	// Number of miners to spawn
@@ -245,11 +192,11 @@ func TestSend(t *testing.T) {
 		t.Fatalf("failed to generate blocks: %s", err)
 	}
 
-	found, _, _, err := waitForTx(miners[0].Node, txHash, 0, wait)
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	_, err = WaitForTx(ctx, miners[0].Node, txHash, 1, time.Second * 4, nil)
 	if err != nil {
 		t.Fatalf("failed to find transaction %s in dag: %s", txHash, err)
-	} else if !found {
-		t.Fatalf("failed to find transaction %s in dag", txHash)
 	}
 
 	// Check balance of dest address
@@ -264,4 +211,32 @@ func TestSend(t *testing.T) {
 	}
 
 	t.Logf("sent %s coin (fee %s) to %s", balance, feeAmount, dest)
-}
\ No newline at end of file
+}
+func TestSignTxUnsignedInputs(t *testing.T) {
+	w, _, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	// An output paying to a key that isn't in the wallet can't be signed for
+	privKey, err := soterec.NewPrivateKey(soterec.S256())
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+	addr, err := soterutil.NewAddressPubKeyHash(soterutil.Hash160(privKey.PubKey().SerializeCompressed()), &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("failed to create script: %s", err)
+	}
+
+	op := wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}
+	tx := spendingTx(op)
+	err = signTx(w, "priv", tx, map[wire.OutPoint][]byte{op: pkScript})
+	if err == nil {
+		t.Fatalf("signTx signed an input of a key that isn't in the wallet")
+	}
+	if !strings.Contains(err.Error(), "inputs 0") {
+		t.Errorf("signTx error %q doesn't name the unsigned input", err)
+	}
+}
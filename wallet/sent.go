@@ -0,0 +1,444 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// The wallet db bucket that transactions we've broadcast are stored in. It's kept separate from the buckets
+	// soterwallet manages, so that it doesn't interfere with them.
+	sentTxNamespaceKey = []byte("sotertoolssenttx")
+)
+
+// SentTxStatus is the status of a transaction we've broadcast
+type SentTxStatus string
+
+const (
+	// SentTxPending means the transaction was broadcast, but hasn't been seen in a block yet
+	SentTxPending SentTxStatus = "pending"
+	// SentTxConfirmed means the transaction was seen in a block
+	SentTxConfirmed SentTxStatus = "confirmed"
+	// SentTxAbandoned means we gave up on the transaction, and its inputs can be used by other transactions
+	SentTxAbandoned SentTxStatus = "abandoned"
+)
+
+// SentTx is a transaction that was broadcast to the network by these tools
+type SentTx struct {
+	Hash   chainhash.Hash
+	Tx     *wire.MsgTx
+	Inputs []wire.OutPoint
+	Status SentTxStatus
+
+	// The dag tips height when the transaction was first broadcast. The transaction can't be in any block below it.
+	BroadcastHeight int32
+	Created         time.Time
+	LastBroadcast   time.Time
+
+	// The transaction this one is spending the change of, if it was created to bump the parent's fee
+	Parent *chainhash.Hash
+}
+
+// sentTxRecord is the serialized form of a SentTx, as stored in the wallet db
+type sentTxRecord struct {
+	Raw             []byte       `json:"raw"`
+	Status          SentTxStatus `json:"status"`
+	BroadcastHeight int32        `json:"broadcastHeight"`
+	Created         time.Time    `json:"created"`
+	LastBroadcast   time.Time    `json:"lastBroadcast"`
+	Parent          string       `json:"parent,omitempty"`
+}
+
+// encodeSentTx returns the serialized form of the SentTx
+func encodeSentTx(s *SentTx) ([]byte, error) {
+	var raw bytes.Buffer
+	err := s.Tx.Serialize(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	r := sentTxRecord{
+		Raw:             raw.Bytes(),
+		Status:          s.Status,
+		BroadcastHeight: s.BroadcastHeight,
+		Created:         s.Created,
+		LastBroadcast:   s.LastBroadcast,
+	}
+	if s.Parent != nil {
+		r.Parent = s.Parent.String()
+	}
+
+	return json.Marshal(&r)
+}
+
+// decodeSentTx returns a SentTx from its serialized form
+func decodeSentTx(b []byte) (*SentTx, error) {
+	var r sentTxRecord
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	err = tx.Deserialize(bytes.NewReader(r.Raw))
+	if err != nil {
+		return nil, err
+	}
+
+	s := SentTx{
+		Hash:            tx.TxHash(),
+		Tx:              tx,
+		Status:          r.Status,
+		BroadcastHeight: r.BroadcastHeight,
+		Created:         r.Created,
+		LastBroadcast:   r.LastBroadcast,
+	}
+
+	for _, txIn := range tx.TxIn {
+		s.Inputs = append(s.Inputs, txIn.PreviousOutPoint)
+	}
+
+	if len(r.Parent) > 0 {
+		s.Parent, err = chainhash.NewHashFromStr(r.Parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+// putSentTx stores the SentTx in the wallet db
+func putSentTx(w *wallet.Wallet, s *SentTx) error {
+	value, err := encodeSentTx(s)
+	if err != nil {
+		return err
+	}
+
+	return walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(sentTxNamespaceKey)
+		if ns == nil {
+			ns, err = tx.CreateTopLevelBucket(sentTxNamespaceKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		return ns.Put(s.Hash[:], value)
+	})
+}
+
+// deleteSentTx removes the transaction from the wallet db
+func deleteSentTx(w *wallet.Wallet, hash *chainhash.Hash) error {
+	return walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(sentTxNamespaceKey)
+		if ns == nil {
+			return nil
+		}
+
+		return ns.Delete(hash[:])
+	})
+}
+
+// RecordSentTx stores the transaction in the wallet db, with a pending status
+func RecordSentTx(w *wallet.Wallet, tx *wire.MsgTx, broadcastHeight int32, parent *chainhash.Hash) (*SentTx, error) {
+	now := time.Now()
+	s := SentTx{
+		Hash:            tx.TxHash(),
+		Tx:              tx,
+		Status:          SentTxPending,
+		BroadcastHeight: broadcastHeight,
+		Created:         now,
+		LastBroadcast:   now,
+		Parent:          parent,
+	}
+
+	for _, txIn := range tx.TxIn {
+		s.Inputs = append(s.Inputs, txIn.PreviousOutPoint)
+	}
+
+	err := putSentTx(w, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GetSentTx returns the stored transaction with the given hash
+func GetSentTx(w *wallet.Wallet, hash *chainhash.Hash) (*SentTx, error) {
+	var value []byte
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(sentTxNamespaceKey)
+		if ns == nil {
+			return nil
+		}
+
+		v := ns.Get(hash[:])
+		if v != nil {
+			// Values are only valid for the life of the db transaction
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return nil, fmt.Errorf("no sent transaction %s", hash)
+	}
+
+	return decodeSentTx(value)
+}
+
+// SentTxs returns all stored transactions, oldest first
+func SentTxs(w *wallet.Wallet) ([]*SentTx, error) {
+	sent := make([]*SentTx, 0)
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(sentTxNamespaceKey)
+		if ns == nil {
+			return nil
+		}
+
+		return ns.ForEach(func(k, v []byte) error {
+			s, err := decodeSentTx(v)
+			if err != nil {
+				return err
+			}
+
+			sent = append(sent, s)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sent, func(i, j int) bool {
+		return sent[i].Created.Before(sent[j].Created)
+	})
+
+	return sent, nil
+}
+
+// PendingTxs returns stored transactions that haven't been seen in a block yet, and haven't been abandoned
+func PendingTxs(w *wallet.Wallet) ([]*SentTx, error) {
+	sent, err := SentTxs(w)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]*SentTx, 0)
+	for _, s := range sent {
+		if s.Status == SentTxPending {
+			pending = append(pending, s)
+		}
+	}
+
+	return pending, nil
+}
+
+// ExcludePendingInputs returns the matches whose outputs aren't used as inputs by pending transactions
+func ExcludePendingInputs(w *wallet.Wallet, matches []TxMatch) ([]TxMatch, error) {
+	pending, err := PendingTxs(w)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[wire.OutPoint]bool)
+	for _, s := range pending {
+		for _, op := range s.Inputs {
+			used[op] = true
+		}
+	}
+
+	unused := make([]TxMatch, 0, len(matches))
+	for _, m := range matches {
+		if used[matchOutPoint(m)] {
+			continue
+		}
+
+		unused = append(unused, m)
+	}
+
+	return unused, nil
+}
+
+// UpdateSentTxs checks with the node whether pending transactions have been included in a block, and marks those that
+// have as confirmed. It returns the pending transactions along with their status.
+func UpdateSentTxs(client *rpcclient.Client, w *wallet.Wallet) ([]*SentTx, []*TxStatus, error) {
+	pending, err := PendingTxs(w)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statuses := make([]*TxStatus, len(pending))
+	for i, s := range pending {
+		status, err := GetTxStatus(client, &s.Hash, s.BroadcastHeight)
+		if err != nil {
+			return nil, nil, err
+		}
+		statuses[i] = status
+
+		if status.State == TxInBlock {
+			s.Status = SentTxConfirmed
+			err = putSentTx(w, s)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return pending, statuses, nil
+}
+
+// Rebroadcast sends the stored transaction to the network again
+func Rebroadcast(client *rpcclient.Client, w *wallet.Wallet, hash *chainhash.Hash) error {
+	s, err := GetSentTx(w, hash)
+	if err != nil {
+		return err
+	}
+
+	if s.Status != SentTxPending {
+		return fmt.Errorf("transaction %s is %s, not %s", hash, s.Status, SentTxPending)
+	}
+
+	_, err = client.SendRawTransaction(s.Tx, false)
+	if err != nil && !strings.Contains(err.Error(), "already have transaction") {
+		return fmt.Errorf("Failed to send transaction to network: %w", sendError(*hash, err))
+	}
+
+	s.LastBroadcast = time.Now()
+	return putSentTx(w, s)
+}
+
+// Abandon marks the stored transaction as abandoned, so that its inputs can be used by other transactions.
+// Pending transactions spending the abandoned transaction's outputs are abandoned too, because they can't be valid
+// without it. It returns the transactions that were abandoned.
+//
+// Abandoning a transaction doesn't remove it from the mempools of nodes that have already seen it, so it could still
+// be included in a block.
+func Abandon(w *wallet.Wallet, hash *chainhash.Hash) ([]*SentTx, error) {
+	s, err := GetSentTx(w, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Status != SentTxPending {
+		return nil, fmt.Errorf("transaction %s is %s, not %s", hash, s.Status, SentTxPending)
+	}
+
+	pending, err := PendingTxs(w)
+	if err != nil {
+		return nil, err
+	}
+
+	abandoned := make([]*SentTx, 0)
+	queue := []*SentTx{s}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		s.Status = SentTxAbandoned
+		err = putSentTx(w, s)
+		if err != nil {
+			return abandoned, err
+		}
+		abandoned = append(abandoned, s)
+
+		for _, child := range pending {
+			if child.Status != SentTxPending {
+				continue
+			}
+
+			for _, op := range child.Inputs {
+				if op.Hash.IsEqual(&s.Hash) {
+					queue = append(queue, child)
+					break
+				}
+			}
+		}
+	}
+
+	return abandoned, nil
+}
+
+// BumpFee creates a child transaction that spends the change of a pending transaction back to the wallet, paying the
+// given fee. If the parent's fee was too low for it to be mined, miners including the child will need to include the
+// parent too (child-pays-for-parent). The parent's change is identified as the outputs paying to addresses in the
+// wallet.
+func BumpFee(client *rpcclient.Client, w *wallet.Wallet, privPass string, hash *chainhash.Hash, fee soterutil.Amount) (*chainhash.Hash, error) {
+	parent, err := GetSentTx(w, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if parent.Status != SentTxPending {
+		return nil, fmt.Errorf("transaction %s is %s, not %s", hash, parent.Status, SentTxPending)
+	}
+
+	params := w.ChainParams()
+	child := wire.NewMsgTx(wire.TxVersion)
+	prevScripts := make(map[wire.OutPoint][]byte)
+	var changeAddr soterutil.Address
+	total := soterutil.Amount(0)
+
+	for i, txOut := range parent.Tx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+		if err != nil || len(addrs) != 1 {
+			continue
+		}
+
+		ours, err := w.HaveAddress(addrs[0])
+		if err != nil || !ours {
+			continue
+		}
+
+		if changeAddr == nil {
+			changeAddr = addrs[0]
+		}
+
+		op := wire.NewOutPoint(hash, uint32(i))
+		child.AddTxIn(wire.NewTxIn(op, nil, nil))
+		prevScripts[*op] = txOut.PkScript
+		total += soterutil.Amount(txOut.Value)
+	}
+
+	if changeAddr == nil {
+		return nil, fmt.Errorf("transaction %s has no change output paying to the wallet", hash)
+	}
+
+	if total <= fee {
+		return nil, fmt.Errorf("change of transaction %s isn't enough to pay the fee: %w", hash,
+			&InsufficientFundsError{Needed: fee, Available: total})
+	}
+
+	pkScript, err := txscript.PayToAddrScript(changeAddr)
+	if err != nil {
+		return nil, err
+	}
+	child.AddTxOut(wire.NewTxOut(int64(total-fee), pkScript))
+
+	err = signTx(w, privPass, child, prevScripts)
+	if err != nil {
+		return nil, err
+	}
+
+	return broadcast(client, w, child, parent.BroadcastHeight, hash)
+}
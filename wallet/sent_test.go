@@ -0,0 +1,129 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// createTestWallet creates and opens a wallet in a temporary file. The returned function closes and removes it.
+func createTestWallet(t *testing.T, privPass, pubPass string) (*wallet.Wallet, string, func()) {
+	tmpfile, err := ioutil.TempFile("", "sotertools_wallet-*.db")
+	if err != nil {
+		t.Fatalf("failed to create wallet file: %s", err)
+	}
+	name := tmpfile.Name()
+	_ = tmpfile.Close()
+	_ = os.Remove(name)
+
+	err = CreateWallet(name, privPass, pubPass, &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to create wallet %s: %s", name, err)
+	}
+
+	w, err := OpenWallet(name, pubPass, &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to open wallet %s: %s", name, err)
+	}
+
+	cleanup := func() {
+		_ = w.Database().Close()
+		_ = os.Remove(name)
+	}
+
+	return w, name, cleanup
+}
+
+// spendingTx returns a transaction spending the given outputs
+func spendingTx(ops ...wire.OutPoint) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := range ops {
+		tx.AddTxIn(wire.NewTxIn(&ops[i], nil, nil))
+	}
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{}))
+
+	return tx
+}
+
+func TestSentTxs(t *testing.T) {
+	w, _, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	matches := makeMatches(10, 20, 30)
+	parent := spendingTx(matchOutPoint(matches[0]), matchOutPoint(matches[1]))
+	parentHash := parent.TxHash()
+	child := spendingTx(*wire.NewOutPoint(&parentHash, 0))
+	childHash := child.TxHash()
+
+	_, err := RecordSentTx(w, parent, 5, nil)
+	if err != nil {
+		t.Fatalf("failed to record transaction: %s", err)
+	}
+	_, err = RecordSentTx(w, child, 5, &parentHash)
+	if err != nil {
+		t.Fatalf("failed to record transaction: %s", err)
+	}
+
+	s, err := GetSentTx(w, &childHash)
+	if err != nil {
+		t.Fatalf("failed to get sent transaction: %s", err)
+	}
+	if s.Parent == nil || !s.Parent.IsEqual(&parentHash) {
+		t.Errorf("wrong parent of sent transaction; got %v, want %s", s.Parent, parentHash)
+	}
+	if s.BroadcastHeight != 5 || s.Status != SentTxPending {
+		t.Errorf("wrong sent transaction details; got height %d status %s", s.BroadcastHeight, s.Status)
+	}
+
+	pending, err := PendingTxs(w)
+	if err != nil {
+		t.Fatalf("failed to get pending transactions: %s", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("wrong number of pending transactions; got %d, want 2", len(pending))
+	}
+
+	unused, err := ExcludePendingInputs(w, matches)
+	if err != nil {
+		t.Fatalf("failed to exclude pending inputs: %s", err)
+	}
+	if len(unused) != 1 || unused[0].VIndex != 2 {
+		t.Errorf("wrong unused outputs; got %d, want 1 (index 2)", len(unused))
+	}
+
+	// Abandoning the parent should abandon the child too, and free the parent's inputs
+	abandoned, err := Abandon(w, &parentHash)
+	if err != nil {
+		t.Fatalf("failed to abandon transaction: %s", err)
+	}
+	if len(abandoned) != 2 {
+		t.Errorf("wrong number of abandoned transactions; got %d, want 2", len(abandoned))
+	}
+
+	unused, err = ExcludePendingInputs(w, matches)
+	if err != nil {
+		t.Fatalf("failed to exclude pending inputs: %s", err)
+	}
+	if len(unused) != len(matches) {
+		t.Errorf("wrong number of unused outputs after abandon; got %d, want %d", len(unused), len(matches))
+	}
+
+	_, err = Abandon(w, &childHash)
+	if err == nil {
+		t.Errorf("abandoned a transaction that was already abandoned")
+	}
+
+	var missing chainhash.Hash
+	_, err = GetSentTx(w, &missing)
+	if err == nil {
+		t.Errorf("got a sent transaction that was never recorded")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/wallet"
+)
+
+// SweepKey decodes a WIF-encoded private key, and returns it along with its pay-to-pubkey-hash address
+func SweepKey(wifKey string, params *chaincfg.Params) (*soterutil.WIF, *soterutil.AddressPubKeyHash, error) {
+	wif, err := soterutil.DecodeWIF(wifKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode private key: %s", err)
+	}
+	if !wif.IsForNet(params) {
+		return nil, nil, &NetworkMismatchError{Of: "private key", Want: params.Name}
+	}
+
+	addr, err := soterutil.NewAddressPubKeyHash(soterutil.Hash160(wif.SerializePubKey()), params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wif, addr, nil
+}
+
+// signWithKey signs the transaction's inputs, which must spend pay-to-pubkey-hash outputs of the key's address.
+// prevScripts must contain the scripts of the outputs that are used as inputs in the transaction.
+func signWithKey(tx *wire.MsgTx, wif *soterutil.WIF, prevScripts map[wire.OutPoint][]byte) error {
+	for i, txIn := range tx.TxIn {
+		pkScript, ok := prevScripts[txIn.PreviousOutPoint]
+		if !ok {
+			return fmt.Errorf("missing script of output %s for input %d", txIn.PreviousOutPoint, i)
+		}
+
+		sigScript, err := txscript.SignatureScript(tx, i, pkScript, txscript.SigHashAll, wif.PrivKey, wif.CompressPubKey)
+		if err != nil {
+			return fmt.Errorf("Failed to sign input %d: %s", i, err)
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+
+	return nil
+}
+
+// Sweep sends all of the coin in the matches, minus the fee, to the destination. The inputs are signed with the
+// private key directly, so the key doesn't need to be in a wallet. The transaction is recorded in the wallet's store of
+// sent transactions.
+func Sweep(client *rpcclient.Client, w *wallet.Wallet, wif *soterutil.WIF, matches []TxMatch, dest soterutil.Address, fee soterutil.Amount) (*chainhash.Hash, soterutil.Amount, error) {
+	total := soterutil.Amount(0)
+	for _, m := range matches {
+		total += m.Amount
+	}
+
+	amount := total - fee
+	if amount <= 0 {
+		return nil, 0, fmt.Errorf("not enough coin to sweep: %w", &InsufficientFundsError{Needed: fee, Available: total})
+	}
+
+	tx, err := newTransaction(client, matches, dest, amount, fee, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = signWithKey(tx, wif, makePrevScripts(matches))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	txHash, err := broadcast(client, w, tx, 0, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return txHash, amount, nil
+}
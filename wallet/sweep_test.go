@@ -0,0 +1,65 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterec"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"testing"
+)
+
+func TestSignWithKey(t *testing.T) {
+	params := &chaincfg.SimNetParams
+
+	privKey, err := soterec.NewPrivateKey(soterec.S256())
+	if err != nil {
+		t.Fatalf("failed to create private key: %s", err)
+	}
+
+	for _, compress := range []bool{true, false} {
+		wif, err := soterutil.NewWIF(privKey, params, compress)
+		if err != nil {
+			t.Fatalf("failed to encode private key: %s", err)
+		}
+
+		decoded, addr, err := SweepKey(wif.String(), params)
+		if err != nil {
+			t.Fatalf("failed to decode sweep key: %s", err)
+		}
+
+		_, _, err = SweepKey(wif.String(), &chaincfg.MainNetParams)
+		if err == nil {
+			t.Errorf("decoded a sweep key for the wrong network")
+		}
+
+		// Spend an output paying to the key's address
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			t.Fatalf("failed to create output script: %s", err)
+		}
+		funding := wire.NewMsgTx(wire.TxVersion)
+		funding.AddTxOut(wire.NewTxOut(5000, pkScript))
+		fundingHash := funding.TxHash()
+		op := wire.NewOutPoint(&fundingHash, 0)
+
+		spend := spendingTx(*op)
+		err = signWithKey(spend, decoded, map[wire.OutPoint][]byte{*op: pkScript})
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %s", err)
+		}
+
+		vm, err := txscript.NewEngine(pkScript, spend, 0, txscript.StandardVerifyFlags, nil, nil, 5000)
+		if err != nil {
+			t.Fatalf("failed to create script engine: %s", err)
+		}
+		err = vm.Execute()
+		if err != nil {
+			t.Errorf("invalid signature with compressed key %v: %s", compress, err)
+		}
+	}
+}
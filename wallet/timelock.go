@@ -0,0 +1,473 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/txscript"
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"github.com/soteria-dag/soterwallet/wallet"
+	"github.com/soteria-dag/soterwallet/walletdb"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	// The wallet db bucket that time-locked addresses are stored in. Their scripts aren't standard, so we keep them
+	// here instead of registering them with the address manager.
+	timeLockNamespaceKey = []byte("sotertoolstimelock")
+)
+
+// LockTimeFromHeight returns a lock time that passes once the dag reaches the height
+func LockTimeFromHeight(height int32) (uint32, error) {
+	if height <= 0 || height >= txscript.LockTimeThreshold {
+		return 0, fmt.Errorf("lock height must be between 1 and %d, got %d", int32(txscript.LockTimeThreshold-1), height)
+	}
+
+	return uint32(height), nil
+}
+
+// LockTimeFromTime returns a lock time that passes at the given time
+func LockTimeFromTime(t time.Time) (uint32, error) {
+	if t.Unix() < txscript.LockTimeThreshold || t.Unix() > math.MaxUint32 {
+		return 0, fmt.Errorf("lock time %s is out of range", t.UTC().Format(time.RFC3339))
+	}
+
+	return uint32(t.Unix()), nil
+}
+
+// FormatLockTime returns a description of when the lock time passes
+func FormatLockTime(lockTime uint32) string {
+	if lockTime < txscript.LockTimeThreshold {
+		return fmt.Sprintf("height %d", lockTime)
+	}
+
+	return time.Unix(int64(lockTime), 0).UTC().Format(time.RFC3339)
+}
+
+// LockTimeMet returns true if a transaction with the lock time can be included in a block at the given height and time.
+// The lock time is a dag height if it's below txscript.LockTimeThreshold, or a unix timestamp otherwise. The node
+// checks a time lock against the median time of the dag's recent blocks rather than its clock, so now should be the
+// time from MedianTime.
+func LockTimeMet(lockTime uint32, height int32, now time.Time) bool {
+	if lockTime == 0 {
+		return true
+	}
+
+	if lockTime < txscript.LockTimeThreshold {
+		return int64(lockTime) < int64(height)
+	}
+
+	return int64(lockTime) < now.Unix()
+}
+
+// TxLockTimeMet returns true if the transaction's lock time doesn't prevent it from being included in a block at the
+// given height and time.
+func TxLockTimeMet(tx *wire.MsgTx, height int32, now time.Time) bool {
+	if LockTimeMet(tx.LockTime, height, now) {
+		return true
+	}
+
+	// The lock time is ignored if all inputs have the maximum sequence number
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence != wire.MaxTxInSequenceNum {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MedianTime returns the median time of the dag's recent blocks, as reported by the node. It lags behind the clock, and
+// is the time that time locks are checked against when a transaction is accepted.
+func MedianTime(client *rpcclient.Client) (time.Time, error) {
+	info, err := client.GetBlockChainInfo()
+	if err != nil {
+		return time.Time{}, rpcError(err)
+	}
+
+	return time.Unix(info.MedianTime, 0), nil
+}
+
+// ConfirmedTxOuts returns the matches whose outputs have at least the given number of confirmations, when the dag
+// tips are at maxHeight.
+func ConfirmedTxOuts(matches []TxMatch, maxHeight int32, confirmations int32) []TxMatch {
+	confirmed := make([]TxMatch, 0, len(matches))
+	for _, m := range matches {
+		if maxHeight-m.Info.BlockHeight+1 >= confirmations {
+			confirmed = append(confirmed, m)
+		}
+	}
+
+	return confirmed
+}
+
+// applyLocks sets the lock time and input sequence numbers of the transaction, according to the options
+func applyLocks(tx *wire.MsgTx, opts *SendOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.RelativeLock > 0 {
+		if opts.RelativeLock > wire.SequenceLockTimeMask {
+			return fmt.Errorf("relative lock of %d blocks is more than the maximum of %d",
+				opts.RelativeLock, wire.SequenceLockTimeMask)
+		}
+
+		// Sequence locks only apply to version 2 transactions (BIP0068)
+		tx.Version = 2
+		for _, txIn := range tx.TxIn {
+			txIn.Sequence = opts.RelativeLock
+		}
+	}
+
+	if opts.LockTime > 0 {
+		tx.LockTime = opts.LockTime
+
+		// The lock time is only enforced if an input doesn't have the maximum sequence number
+		for _, txIn := range tx.TxIn {
+			if txIn.Sequence == wire.MaxTxInSequenceNum {
+				txIn.Sequence = wire.MaxTxInSequenceNum - 1
+			}
+		}
+	}
+
+	return nil
+}
+
+// holdTx records the signed transaction in the wallet's store of sent transactions without broadcasting it, because
+// the node won't accept it until its lock time passes. It can be broadcast later with Rebroadcast.
+func holdTx(client *rpcclient.Client, w *wallet.Wallet, tx *wire.MsgTx) (*chainhash.Hash, error) {
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := RecordSentTx(w, tx, tips.MaxHeight, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to record transaction: %s", err)
+	}
+
+	// A zero LastBroadcast marks the transaction as never broadcast
+	s.LastBroadcast = time.Time{}
+	err = putSentTx(w, s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to record transaction: %s", err)
+	}
+
+	return &s.Hash, nil
+}
+
+// TimeLockAddress is a pay-to-script-hash address whose coin can only be spent by the key of a wallet address, once
+// the lock time has passed. The redeem script uses OP_CHECKLOCKTIMEVERIFY to enforce the lock.
+type TimeLockAddress struct {
+	Address *soterutil.AddressScriptHash
+	// The wallet address whose key can spend from the address
+	Owner    soterutil.Address
+	LockTime uint32
+	Script   []byte
+}
+
+// timeLockRecord is the serialized form of a TimeLockAddress, as stored in the wallet db
+type timeLockRecord struct {
+	Owner    string `json:"owner"`
+	LockTime uint32 `json:"lockTime"`
+	Script   []byte `json:"script"`
+}
+
+// timeLockScript returns a redeem script that can be spent with a signature from the public key, once the lock time
+// has passed.
+func timeLockScript(lockTime uint32, pubKey []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddInt64(int64(lockTime)).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddData(pubKey).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// NewTimeLockAddress creates a pay-to-script-hash address whose coin can be spent by the owner's key once the lock
+// time has passed, and stores it in the wallet. The owner must be a pay-to-pubkey-hash address of the wallet.
+func NewTimeLockAddress(w *wallet.Wallet, owner soterutil.Address, lockTime uint32) (*TimeLockAddress, error) {
+	if lockTime == 0 {
+		return nil, fmt.Errorf("no lock time given")
+	}
+
+	pubKey, err := w.PubKeyForAddress(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key of address %s: %s", owner, err)
+	}
+
+	script, err := timeLockScript(lockTime, pubKey.SerializeCompressed())
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := soterutil.NewAddressScriptHash(script, w.ChainParams())
+	if err != nil {
+		return nil, err
+	}
+
+	r := timeLockRecord{
+		Owner:    owner.EncodeAddress(),
+		LockTime: lockTime,
+		Script:   script,
+	}
+	value, err := json.Marshal(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walletdb.Update(w.Database(), func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(timeLockNamespaceKey)
+		if ns == nil {
+			ns, err = tx.CreateTopLevelBucket(timeLockNamespaceKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		return ns.Put(addr.ScriptAddress(), value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := TimeLockAddress{
+		Address:  addr,
+		Owner:    owner,
+		LockTime: lockTime,
+		Script:   script,
+	}
+
+	return &t, nil
+}
+
+// TimeLockAddresses returns the time-locked addresses stored in the wallet, ordered by lock time
+func TimeLockAddresses(w *wallet.Wallet) ([]*TimeLockAddress, error) {
+	params := w.ChainParams()
+	addrs := make([]*TimeLockAddress, 0)
+
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(timeLockNamespaceKey)
+		if ns == nil {
+			return nil
+		}
+
+		return ns.ForEach(func(k, v []byte) error {
+			var r timeLockRecord
+			err := json.Unmarshal(v, &r)
+			if err != nil {
+				return err
+			}
+
+			owner, err := soterutil.DecodeAddress(r.Owner, params)
+			if err != nil {
+				return err
+			}
+
+			addr, err := soterutil.NewAddressScriptHash(r.Script, params)
+			if err != nil {
+				return err
+			}
+
+			t := TimeLockAddress{
+				Address:  addr,
+				Owner:    owner,
+				LockTime: r.LockTime,
+				Script:   r.Script,
+			}
+			addrs = append(addrs, &t)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].LockTime < addrs[j].LockTime
+	})
+
+	return addrs, nil
+}
+
+// TimeLockedTxOut is an output paying to a time-locked address of the wallet
+type TimeLockedTxOut struct {
+	TxMatch
+	Lock *TimeLockAddress
+
+	// Whether the lock time has passed, so that the output can be spent
+	Unlocked bool
+}
+
+// TimeLockedTxOuts returns the outputs in the dag that pay to the wallet's time-locked addresses, and whether they're
+// unlocked yet, by the dag's height and median time.
+func TimeLockedTxOuts(client *rpcclient.Client, w *wallet.Wallet, params *chaincfg.Params) ([]TimeLockedTxOut, error) {
+	locks, err := TimeLockAddresses(w)
+	if err != nil {
+		return nil, err
+	}
+
+	outs := make([]TimeLockedTxOut, 0)
+	if len(locks) == 0 {
+		return outs, nil
+	}
+
+	byAddress := make(map[string]*TimeLockAddress)
+	addresses := make([]soterutil.Address, len(locks))
+	for i, l := range locks {
+		byAddress[l.Address.EncodeAddress()] = l
+		addresses[i] = l.Address
+	}
+
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := AllTransactions(client)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := MedianTime(client)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range transactions {
+		for i, txOut := range info.Tx.TxOut {
+			amount, err := outputAmount(txOut, addresses, params)
+			if err != nil {
+				return nil, err
+			}
+			if amount == 0 {
+				continue
+			}
+
+			_, outAddresses, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, params)
+			if err != nil {
+				return nil, err
+			}
+			lock := byAddress[outAddresses[0].EncodeAddress()]
+
+			matchInfo := info
+			out := TimeLockedTxOut{
+				TxMatch: TxMatch{
+					Address: lock.Address.EncodeAddress(),
+					Amount:  amount,
+					VIndex:  i,
+					Info:    &matchInfo,
+				},
+				Lock:     lock,
+				Unlocked: LockTimeMet(lock.LockTime, tips.MaxHeight+1, now),
+			}
+			outs = append(outs, out)
+		}
+	}
+
+	return outs, nil
+}
+
+// signTimeLocked signs the transaction's inputs, which must spend the given time-locked outputs in order
+func signTimeLocked(w *wallet.Wallet, privPass string, tx *wire.MsgTx, outs []TimeLockedTxOut) error {
+	err := unlock(w, privPass)
+	if err != nil {
+		return err
+	}
+	defer lock(w)
+
+	for i, out := range outs {
+		var maddr waddrmgr.ManagedAddress
+		err := walletdb.View(w.Database(), func(dbtx walletdb.ReadTx) error {
+			addrmgrNs := dbtx.ReadBucket(waddrmgrNamespaceKey)
+			var err error
+			maddr, err = w.Manager.Address(addrmgrNs, out.Lock.Owner)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to look up address %s: %s", out.Lock.Owner, err)
+		}
+
+		pka, ok := maddr.(waddrmgr.ManagedPubKeyAddress)
+		if !ok {
+			return fmt.Errorf("address %s is not a key type", out.Lock.Owner)
+		}
+
+		privKey, err := pka.PrivKey()
+		if err != nil {
+			return err
+		}
+
+		sig, err := txscript.RawTxInSignature(tx, i, out.Lock.Script, txscript.SigHashAll, privKey)
+		if err != nil {
+			return fmt.Errorf("Failed to sign input %d: %s", i, err)
+		}
+
+		sigScript, err := txscript.NewScriptBuilder().AddData(sig).AddData(out.Lock.Script).Script()
+		if err != nil {
+			return err
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+
+		// Check the signature script against the output, so that we don't send a transaction the node will reject
+		pkScript := out.Info.Tx.TxOut[out.VIndex].PkScript
+		vm, err := txscript.NewEngine(pkScript, tx, i, txscript.StandardVerifyFlags, nil, nil, int64(out.Amount))
+		if err != nil {
+			return err
+		}
+		err = vm.Execute()
+		if err != nil {
+			return fmt.Errorf("Failed to verify signature of input %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// SpendTimeLocked creates a transaction that sends coin from the unlocked time-locked outputs to the destination, signs
+// it, and sends it to the network. Change is returned to the time-locked address it came from.
+func SpendTimeLocked(client *rpcclient.Client, w *wallet.Wallet, privPass string, outs []TimeLockedTxOut, dest soterutil.Address, amount, fee soterutil.Amount) (*chainhash.Hash, error) {
+	matches := make([]TxMatch, len(outs))
+	for i, out := range outs {
+		matches[i] = out.TxMatch
+	}
+	selected := outs[:len(SelectInputs(matches, amount, fee))]
+
+	// The transaction's lock time has to be at least the lock time of every input, and of the same kind
+	lockTime := uint32(0)
+	for _, out := range selected {
+		if !out.Unlocked {
+			return nil, fmt.Errorf("output %d of transaction %s is locked until %s",
+				out.VIndex, out.Info.Tx.TxHash(), FormatLockTime(out.Lock.LockTime))
+		}
+		if lockTime > 0 && (lockTime < txscript.LockTimeThreshold) != (out.Lock.LockTime < txscript.LockTimeThreshold) {
+			return nil, fmt.Errorf("can't spend outputs locked by height and by time in the same transaction")
+		}
+		if out.Lock.LockTime > lockTime {
+			lockTime = out.Lock.LockTime
+		}
+	}
+
+	tx, err := newTransaction(client, matches[:len(selected)], dest, amount, fee, &SendOptions{LockTime: lockTime})
+	if err != nil {
+		return nil, err
+	}
+
+	err = signTimeLocked(w, privPass, tx, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	return broadcast(client, w, tx, 0, nil)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/soteria-dag/soterd/wire"
+	"github.com/soteria-dag/soterwallet/waddrmgr"
+	"testing"
+	"time"
+)
+
+func TestLockTimeMet(t *testing.T) {
+	now := time.Unix(1570000000, 0)
+	tests := []struct {
+		lockTime uint32
+		height   int32
+		met      bool
+	}{
+		{0, 1, true},
+		{100, 100, false},
+		{100, 101, true},
+		{uint32(now.Unix()), 1, false},
+		{uint32(now.Unix()) - 1, 1, true},
+	}
+
+	for _, test := range tests {
+		got := LockTimeMet(test.lockTime, test.height, now)
+		if got != test.met {
+			t.Errorf("wrong result for lock time %d at height %d; got %v, want %v",
+				test.lockTime, test.height, got, test.met)
+		}
+	}
+
+	_, err := LockTimeFromTime(time.Unix(1000, 0))
+	if err == nil {
+		t.Errorf("created a lock time from a time that would be read as a height")
+	}
+	_, err = LockTimeFromHeight(0)
+	if err == nil {
+		t.Errorf("created a lock time from height 0")
+	}
+}
+
+func TestApplyLocks(t *testing.T) {
+	matches := makeMatches(10, 20)
+	tx := spendingTx(matchOutPoint(matches[0]), matchOutPoint(matches[1]))
+
+	err := applyLocks(tx, &SendOptions{LockTime: 500})
+	if err != nil {
+		t.Fatalf("failed to apply lock time: %s", err)
+	}
+	if tx.LockTime != 500 {
+		t.Errorf("wrong lock time; got %d, want 500", tx.LockTime)
+	}
+	if TxLockTimeMet(tx, 500, time.Now()) || !TxLockTimeMet(tx, 501, time.Now()) {
+		t.Errorf("lock time of transaction isn't enforced")
+	}
+
+	tx = spendingTx(matchOutPoint(matches[0]))
+	err = applyLocks(tx, &SendOptions{RelativeLock: 10})
+	if err != nil {
+		t.Fatalf("failed to apply relative lock: %s", err)
+	}
+	if tx.Version != 2 || tx.TxIn[0].Sequence != 10 {
+		t.Errorf("wrong relative lock; got version %d sequence %d", tx.Version, tx.TxIn[0].Sequence)
+	}
+
+	err = applyLocks(tx, &SendOptions{RelativeLock: wire.SequenceLockTimeMask + 1})
+	if err == nil {
+		t.Errorf("applied a relative lock larger than the maximum")
+	}
+}
+
+func TestTimeLock(t *testing.T) {
+	w, _, cleanup := createTestWallet(t, "priv", "pub")
+	defer cleanup()
+
+	owner, err := NewAddress(w, 0, waddrmgr.KeyScopeBIP0044)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	lock, err := NewTimeLockAddress(w, owner, 200)
+	if err != nil {
+		t.Fatalf("failed to create time-locked address: %s", err)
+	}
+
+	locks, err := TimeLockAddresses(w)
+	if err != nil {
+		t.Fatalf("failed to list time-locked addresses: %s", err)
+	}
+	if len(locks) != 1 || locks[0].Address.EncodeAddress() != lock.Address.EncodeAddress() || locks[0].LockTime != 200 {
+		t.Fatalf("wrong time-locked addresses; got %v, want [%s]", locks, lock.Address)
+	}
+
+	// Time-locked addresses aren't multisig addresses
+	multisig, err := MultisigAddresses(w)
+	if err != nil {
+		t.Fatalf("failed to list multisig addresses: %s", err)
+	}
+	if len(multisig) != 0 {
+		t.Errorf("time-locked address listed as multisig address")
+	}
+
+	// Spend an output paying to the time-locked address
+	pkScript, err := payToScriptHashScript(lock.Script)
+	if err != nil {
+		t.Fatalf("failed to create p2sh script: %s", err)
+	}
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxOut(wire.NewTxOut(5000, pkScript))
+	fundingHash := funding.TxHash()
+	out := TimeLockedTxOut{
+		TxMatch: TxMatch{
+			Address: lock.Address.EncodeAddress(),
+			Amount:  5000,
+			VIndex:  0,
+			Info:    &TxInfo{Tx: funding},
+		},
+		Lock:     locks[0],
+		Unlocked: true,
+	}
+
+	for _, test := range []struct {
+		lockTime uint32
+		valid    bool
+	}{
+		{199, false},
+		{200, true},
+	} {
+		spend := spendingTx(*wire.NewOutPoint(&fundingHash, 0))
+		err = applyLocks(spend, &SendOptions{LockTime: test.lockTime})
+		if err != nil {
+			t.Fatalf("failed to apply lock time: %s", err)
+		}
+
+		err = signTimeLocked(w, "priv", spend, []TimeLockedTxOut{out})
+		if test.valid && err != nil {
+			t.Errorf("failed to sign spend with lock time %d: %s", test.lockTime, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("signed spend with lock time %d, before the output's lock time", test.lockTime)
+		}
+	}
+}
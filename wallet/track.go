@@ -0,0 +1,235 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterjson"
+	"github.com/soteria-dag/soterd/wire"
+	"time"
+)
+
+// TxState describes where a transaction is on its way to being included in the dag
+type TxState int
+
+const (
+	// TxUnknown means the transaction isn't in the node's mempool or dag
+	TxUnknown TxState = iota
+	// TxInMempool means the transaction is waiting in the node's mempool to be included in a block
+	TxInMempool
+	// TxInBlock means the transaction was included in a block in the dag
+	TxInBlock
+)
+
+// String returns a description of the transaction state
+func (s TxState) String() string {
+	switch s {
+	case TxUnknown:
+		return "unknown"
+	case TxInMempool:
+		return "in mempool"
+	case TxInBlock:
+		return "in block"
+	default:
+		return fmt.Sprintf("TxState(%d)", int(s))
+	}
+}
+
+// TxStatus represents the status of a transaction, as seen by a soterd node
+type TxStatus struct {
+	Hash  chainhash.Hash
+	State TxState
+
+	// The block the transaction was found in, and its height. These are only set when State is TxInBlock.
+	Block       *wire.MsgBlock
+	BlockHeight int32
+
+	// How many blocks deep the block containing the transaction is, counting the block itself.
+	// This is 0 unless State is TxInBlock.
+	Confirmations int32
+
+	// The lowest dag height that was searched for the transaction. When State is TxUnknown, the transaction isn't in
+	// the mempool or in blocks from the tips down to this height, but it can be in a lower block.
+	SearchedHeight int32
+}
+
+// inMempool returns true if the transaction is in the node's mempool
+func inMempool(client *rpcclient.Client, txHash *chainhash.Hash) (bool, error) {
+	hashes, err := client.GetRawMempool()
+	if err != nil {
+		return false, err
+	}
+
+	for _, h := range hashes {
+		if h.IsEqual(txHash) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// TxSearchDepth is how many dag heights below the tips are searched for a transaction, when the height it was sent at
+// isn't known. Searching the whole dag for a transaction that isn't in it would take a request per block.
+const TxSearchDepth = 1000
+
+// searchFloor returns the lowest height to search for a transaction, down to minHeight if it's known, otherwise
+// TxSearchDepth heights below the tips.
+func searchFloor(tips *soterjson.GetDAGTipsResult, minHeight int32) int32 {
+	if minHeight > 0 {
+		return minHeight
+	}
+
+	floor := tips.MaxHeight - TxSearchDepth + 1
+	if floor < 0 {
+		floor = 0
+	}
+	return floor
+}
+
+// FindTx looks for the transaction in blocks from the dag tips down to minHeight, or TxSearchDepth heights below the
+// tips if minHeight is 0. It returns the block the transaction was found in and its height, or a nil block if the
+// transaction wasn't found.
+func FindTx(client *rpcclient.Client, txHash *chainhash.Hash, minHeight int32) (*wire.MsgBlock, int32, error) {
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return findTx(client, txHash, tips.MaxHeight, searchFloor(tips, minHeight))
+}
+
+// findTx looks for the transaction in blocks from maxHeight down to minHeight
+func findTx(client *rpcclient.Client, txHash *chainhash.Hash, maxHeight, minHeight int32) (*wire.MsgBlock, int32, error) {
+	for height := maxHeight; height >= minHeight && height >= 0; height-- {
+		hashes, err := client.GetBlockHash(int64(height))
+		if err != nil {
+			return nil, -1, err
+		}
+
+		for _, hash := range hashes {
+			block, err := client.GetBlock(hash)
+			if err != nil {
+				return nil, -1, err
+			}
+
+			for _, tx := range block.Transactions {
+				h := tx.TxHash()
+				if h.IsEqual(txHash) {
+					return block, height, nil
+				}
+			}
+		}
+	}
+
+	return nil, -1, nil
+}
+
+// GetTxStatus returns the status of the transaction, searching blocks down to minHeight when it isn't in the mempool.
+// If minHeight is 0, blocks are searched down to TxSearchDepth heights below the tips, and a transaction that isn't
+// found is TxUnknown with the SearchedHeight it was searched down to.
+func GetTxStatus(client *rpcclient.Client, txHash *chainhash.Hash, minHeight int32) (*TxStatus, error) {
+	status := TxStatus{
+		Hash:        *txHash,
+		State:       TxUnknown,
+		BlockHeight: -1,
+	}
+
+	pending, err := inMempool(client, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		status.State = TxInMempool
+		return &status, nil
+	}
+
+	tips, err := client.GetDAGTips()
+	if err != nil {
+		return nil, err
+	}
+
+	status.SearchedHeight = searchFloor(tips, minHeight)
+	block, height, err := findTx(client, txHash, tips.MaxHeight, status.SearchedHeight)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return &status, nil
+	}
+
+	status.State = TxInBlock
+	status.Block = block
+	status.BlockHeight = height
+	status.Confirmations = tips.MaxHeight - height + 1
+
+	return &status, nil
+}
+
+// WaitForTx polls the node until the transaction has at least the given number of confirmations.
+// An error is returned if the context is cancelled or its deadline passes first, along with the last status seen.
+//
+// If update is not nil, it's called every time the state or confirmations of the transaction change.
+//
+// The first poll searches TxSearchDepth heights below the tips for the transaction. Later polls only search the blocks
+// above the tips of the poll before them, so a transaction in a lower block isn't found.
+func WaitForTx(ctx context.Context, client *rpcclient.Client, txHash *chainhash.Hash, confirmations int32,
+	pollInterval time.Duration, update func(TxStatus)) (*TxStatus, error) {
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive, not %s", pollInterval)
+	}
+
+	var last *TxStatus
+	minHeight := int32(0)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var status *TxStatus
+		var err error
+		if last != nil && last.State == TxInBlock {
+			// We already know which block the transaction is in, so only the dag height needs to be checked.
+			var tips *soterjson.GetDAGTipsResult
+			tips, err = client.GetDAGTips()
+			if err == nil {
+				s := *last
+				s.Confirmations = tips.MaxHeight - s.BlockHeight + 1
+				status = &s
+			}
+		} else {
+			// The transaction can only be included in blocks above the tips of this poll, so the next poll doesn't
+			// need to search lower blocks if it isn't found
+			var tips *soterjson.GetDAGTipsResult
+			tips, err = client.GetDAGTips()
+			if err == nil {
+				status, err = GetTxStatus(client, txHash, minHeight)
+				if err == nil && status.State != TxInBlock {
+					minHeight = tips.MaxHeight
+				}
+			}
+		}
+
+		if err == nil {
+			if update != nil && (last == nil || last.State != status.State || last.Confirmations != status.Confirmations) {
+				update(*status)
+			}
+			last = status
+
+			if status.State == TxInBlock && status.Confirmations >= confirmations {
+				return status, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, fmt.Errorf("stopped waiting for transaction %s: %s", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"context"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/soterjson"
+	"testing"
+)
+
+func TestSearchFloor(t *testing.T) {
+	tests := []struct {
+		maxHeight int32
+		minHeight int32
+		want      int32
+	}{
+		// The height the transaction was sent at is searched down to
+		{5000, 4990, 4990},
+		// Without it, only TxSearchDepth heights are searched
+		{5000, 0, 5000 - TxSearchDepth + 1},
+		{10, 0, 0},
+	}
+
+	for _, test := range tests {
+		got := searchFloor(&soterjson.GetDAGTipsResult{MaxHeight: test.maxHeight}, test.minHeight)
+		if got != test.want {
+			t.Errorf("searchFloor(%d, %d) = %d, want %d", test.maxHeight, test.minHeight, got, test.want)
+		}
+	}
+}
+
+func TestWaitForTxPollInterval(t *testing.T) {
+	// The poll interval is checked before the node is used
+	_, err := WaitForTx(context.Background(), nil, &chainhash.Hash{}, 1, 0, nil)
+	if err == nil {
+		t.Errorf("WaitForTx accepted a poll interval of 0")
+	}
+}
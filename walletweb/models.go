@@ -0,0 +1,445 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package walletweb
+
+import (
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"github.com/soteria-dag/soterd/wire"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+)
+
+// Represents address balance info that we're interested in rendering
+type balanceInfo struct {
+	Address string
+	Balance string
+	Spendable string
+}
+
+// getBalance returns a balanceInfo, with amounts shown in the style
+func getBalance(c *rpcclient.Client, address string, style amount.Style) (balanceInfo, error) {
+	info := balanceInfo{
+		Address:   address,
+	}
+
+	addr, err := soterutil.DecodeAddress(address, activeNetParams)
+	if err != nil {
+		return info, err
+	}
+
+	balance, spendable, err := wallet.GetBalance(c, []soterutil.Address{addr}, activeNetParams)
+	if err != nil {
+		return info, err
+	}
+
+	info.Balance = style.Format(balance)
+	info.Spendable = style.Format(spendable)
+
+	return info, nil
+}
+
+// Represents transaction status info that we're interested in rendering
+type txStatusInfo struct {
+	Hash string
+	State string
+	// Block info is only set once the transaction is in a block
+	InBlock bool
+	BlockHash string
+	BlockHeight int32
+	Confirmations int32
+}
+
+// getTxStatus returns a txStatusInfo, and the status it's made from, which getTxDetail uses to find the transaction
+// again without looking for it in the dag
+func getTxStatus(c *rpcclient.Client, hash string) (txStatusInfo, *wallet.TxStatus, error) {
+	info := txStatusInfo{
+		Hash: hash,
+	}
+
+	txHash, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return info, nil, err
+	}
+
+	status, err := wallet.GetTxStatus(c, txHash, 0)
+	if err != nil {
+		return info, nil, err
+	}
+
+	info.State = status.State.String()
+	if status.State == wallet.TxUnknown {
+		info.State = fmt.Sprintf("%s (not in the mempool, or in blocks down to height %d)", status.State, status.SearchedHeight)
+	}
+	if status.State == wallet.TxInBlock {
+		info.InBlock = true
+		info.BlockHash = status.Block.BlockHash().String()
+		info.BlockHeight = status.BlockHeight
+		info.Confirmations = status.Confirmations
+	}
+
+	return info, status, nil
+}
+
+// Represents a pending transaction from the wallet's store of sent transactions, that we're interested in rendering
+type pendingTxInfo struct {
+	Hash string
+	Inputs int
+	// The status of the transaction on the soterd node
+	State string
+	LastBroadcast string
+	// The transaction this one is bumping the fee of, if any
+	Parent string
+	// When the transaction's lock time passes, if it has one
+	LockedUntil string
+}
+
+// getPendingTxs returns pendingTxInfo for transactions sent from the wallet that haven't been seen in a block yet
+func getPendingTxs(c *rpcclient.Client, w *soterwallet.Wallet) ([]pendingTxInfo, error) {
+	infos := make([]pendingTxInfo, 0)
+
+	pending, statuses, err := wallet.UpdateSentTxs(c, w)
+	if err != nil {
+		return infos, err
+	}
+
+	for i, s := range pending {
+		status := statuses[i]
+		if status.State == wallet.TxInBlock {
+			continue
+		}
+
+		info := pendingTxInfo{
+			Hash: s.Hash.String(),
+			Inputs: len(s.Inputs),
+			State: status.State.String(),
+			LastBroadcast: s.LastBroadcast.Format("2006-01-02 15:04:05"),
+		}
+		if s.LastBroadcast.IsZero() {
+			info.LastBroadcast = "never"
+		}
+		if s.Parent != nil {
+			info.Parent = s.Parent.String()
+		}
+		if s.Tx.LockTime > 0 {
+			info.LockedUntil = wallet.FormatLockTime(s.Tx.LockTime)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+// Represents data embedded in a null-data output of a transaction, that we're interested in rendering
+type dataInfo struct {
+	Index int
+	Hex string
+	// Text is set if the data is printable text
+	Text string
+}
+
+// Represents a transaction in the history of an address, that we're interested in rendering
+type historyInfo struct {
+	Hash string
+	BlockHash string
+	BlockHeight int32
+	Received string
+	Sent string
+	Data []dataInfo
+}
+
+// Represents the history of an address
+type historyInfos []historyInfo
+
+// getHistory returns historyInfo for transactions in the dag that send coin to, or spend coin from, the address, with
+// amounts shown in the style
+func getHistory(c *rpcclient.Client, address string, style amount.Style) (historyInfos, error) {
+	infos := make(historyInfos, 0)
+
+	addr, err := soterutil.DecodeAddress(address, activeNetParams)
+	if err != nil {
+		return infos, err
+	}
+
+	history, err := wallet.History(c, []soterutil.Address{addr}, activeNetParams)
+	if err != nil {
+		return infos, err
+	}
+
+	for _, e := range history {
+		info := historyInfo{
+			Hash: e.Info.Tx.TxHash().String(),
+			BlockHash: e.Info.Block.BlockHash().String(),
+			BlockHeight: e.Info.BlockHeight,
+			Received: style.Format(e.Received),
+			Sent: style.Format(e.Sent),
+		}
+		for _, d := range e.Data {
+			text, _ := d.Text()
+			info.Data = append(info.Data, dataInfo{Index: d.VIndex, Hex: d.String(), Text: text})
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// How many heights below the dag tips are listed on the tips page
+const recentHeights = 10
+
+// Represents the tips of the dag, that we're interested in rendering
+type tipsInfo struct {
+	MaxHeight int32
+	Tips []string
+	// The heights below the tips, from the highest, to browse the dag from
+	Heights []int32
+}
+
+// getTips returns a tipsInfo
+func getTips(c *rpcclient.Client) (tipsInfo, error) {
+	var info tipsInfo
+
+	tips, err := c.GetDAGTips()
+	if err != nil {
+		return info, err
+	}
+
+	info.MaxHeight = tips.MaxHeight
+	info.Tips = tips.Tips
+	for h := tips.MaxHeight; h >= 0 && h > tips.MaxHeight-recentHeights; h-- {
+		info.Heights = append(info.Heights, h)
+	}
+
+	return info, nil
+}
+
+// Represents a block in a list of blocks, that we're interested in rendering
+type blockSummary struct {
+	Hash string
+	Time string
+	Parents int
+	Txs int
+}
+
+// newBlockSummary returns a blockSummary of the block
+func newBlockSummary(block *wire.MsgBlock) blockSummary {
+	return blockSummary{
+		Hash: block.BlockHash().String(),
+		Time: block.Header.Timestamp.Format("2006-01-02 15:04:05"),
+		Parents: len(block.Parents.Parents),
+		Txs: len(block.Transactions),
+	}
+}
+
+// Represents the blocks at a height of the dag, that we're interested in rendering
+type heightInfo struct {
+	Height int32
+	// Whether there are heights above and below this one, to link to
+	HasPrev bool
+	HasNext bool
+	Blocks []blockSummary
+}
+
+// Prev returns the height below
+func (info heightInfo) Prev() int32 {
+	return info.Height - 1
+}
+
+// Next returns the height above
+func (info heightInfo) Next() int32 {
+	return info.Height + 1
+}
+
+// getHeight returns a heightInfo
+func getHeight(c *rpcclient.Client, height int32) (heightInfo, error) {
+	info := heightInfo{
+		Height: height,
+		HasPrev: height > 0,
+	}
+
+	blocks, err := wallet.BlocksAtHeight(c, height)
+	if err != nil {
+		return info, err
+	}
+	for _, b := range blocks {
+		info.Blocks = append(info.Blocks, newBlockSummary(b))
+	}
+
+	tips, err := c.GetDAGTips()
+	if err != nil {
+		return info, err
+	}
+	info.HasNext = height < tips.MaxHeight
+
+	return info, nil
+}
+
+// Represents a transaction in a block, that we're interested in rendering
+type blockTxInfo struct {
+	Hash string
+	Inputs int
+	Outputs int
+	// The total amount sent by the transaction's outputs
+	Total string
+}
+
+// Represents a block, that we're interested in rendering
+type blockInfo struct {
+	Hash string
+	Height int32
+	Confirmations int32
+	Time string
+	Parents []string
+	// The coinbase transaction's outputs, which are the reward of the block's miner
+	Coinbase string
+	CoinbaseOutputs []txOutputInfo
+	Txs []blockTxInfo
+}
+
+// getBlock returns a blockInfo, with amounts shown in the style
+func getBlock(c *rpcclient.Client, hash string, style amount.Style) (blockInfo, error) {
+	info := blockInfo{
+		Hash: hash,
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return info, requestErrorf("failed to parse block hash %s: %s", hash, err)
+	}
+
+	detail, err := wallet.GetBlockDetail(c, blockHash)
+	if err != nil {
+		return info, err
+	}
+
+	info.Height = detail.Height
+	info.Confirmations = detail.Confirmations
+	info.Time = detail.Block.Header.Timestamp.Format("2006-01-02 15:04:05")
+	for _, p := range detail.Parents {
+		info.Parents = append(info.Parents, p.String())
+	}
+
+	for i, tx := range detail.Block.Transactions {
+		var total soterutil.Amount
+		for _, txOut := range tx.TxOut {
+			total += soterutil.Amount(txOut.Value)
+		}
+		info.Txs = append(info.Txs, blockTxInfo{
+			Hash: tx.TxHash().String(),
+			Inputs: len(tx.TxIn),
+			Outputs: len(tx.TxOut),
+			Total: style.Format(total),
+		})
+
+		// The first transaction of a block is its coinbase
+		if i == 0 {
+			// The coinbase doesn't spend any outputs, so it's described without previous transactions
+			coinbase, err := wallet.DescribeTx(tx, nil, activeNetParams)
+			if err != nil || !coinbase.Coinbase {
+				continue
+			}
+			info.Coinbase = tx.TxHash().String()
+			info.CoinbaseOutputs = newTxOutputInfos(coinbase.Outputs, style)
+		}
+	}
+
+	return info, nil
+}
+
+// Represents an input of a transaction, that we're interested in rendering
+type txInputInfo struct {
+	// The transaction and output that the input spends
+	PrevTx string
+	PrevIndex uint32
+	Coinbase bool
+	Addresses []string
+	Amount string
+}
+
+// Represents an output of a transaction, that we're interested in rendering
+type txOutputInfo struct {
+	Index int
+	Amount string
+	Type string
+	Addresses []string
+	// Data is set for null-data outputs
+	Data *dataInfo
+}
+
+// newTxOutputInfos returns txOutputInfo of the outputs, with amounts shown in the style
+func newTxOutputInfos(outputs []wallet.TxOutput, style amount.Style) []txOutputInfo {
+	infos := make([]txOutputInfo, 0, len(outputs))
+	for _, o := range outputs {
+		info := txOutputInfo{
+			Index: o.VIndex,
+			Amount: style.Format(o.Amount),
+			Type: o.Class.String(),
+		}
+		for _, a := range o.Addresses {
+			info.Addresses = append(info.Addresses, a.EncodeAddress())
+		}
+		if o.Data != nil {
+			d := wallet.DataOutput{VIndex: o.VIndex, Data: o.Data}
+			text, _ := d.Text()
+			info.Data = &dataInfo{Index: d.VIndex, Hex: d.String(), Text: text}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Represents the inputs, outputs and fee of a transaction, that we're interested in rendering
+type txDetailInfo struct {
+	Hash string
+	Coinbase bool
+	// The block and its height are only set once the transaction is in a block
+	BlockHash string
+	BlockHeight int32
+	Inputs []txInputInfo
+	Outputs []txOutputInfo
+	Fee string
+}
+
+// getTxDetail returns a txDetailInfo of the transaction with the status from getTxStatus, with amounts shown in the
+// style
+func getTxDetail(c *rpcclient.Client, status *wallet.TxStatus, style amount.Style) (txDetailInfo, error) {
+	info := txDetailInfo{
+		Hash: status.Hash.String(),
+	}
+
+	detail, err := wallet.GetTxDetail(c, status, activeNetParams)
+	if err != nil {
+		return info, err
+	}
+
+	info.Coinbase = detail.Coinbase
+	info.Fee = style.Format(detail.Fee)
+	if detail.Info != nil {
+		info.BlockHash = detail.Info.Block.BlockHash().String()
+		info.BlockHeight = detail.Info.BlockHeight
+	}
+	for _, in := range detail.Inputs {
+		input := txInputInfo{
+			PrevTx: in.PrevOut.Hash.String(),
+			PrevIndex: in.PrevOut.Index,
+			Coinbase: in.Coinbase,
+			Amount: style.Format(in.Amount),
+		}
+		for _, a := range in.Addresses {
+			input.Addresses = append(input.Addresses, a.EncodeAddress())
+		}
+
+		info.Inputs = append(info.Inputs, input)
+	}
+	info.Outputs = newTxOutputInfos(detail.Outputs, style)
+
+	return info, nil
+}
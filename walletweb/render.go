@@ -0,0 +1,255 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package walletweb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/sotertools/walletweb/templates"
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+// setContentType sets the Content-Type HTTP header of a response
+func setContentType(w http.ResponseWriter, cType string) {
+	w.Header().Set("Content-Type", cType)
+}
+
+// renderHTML renders the given template text into the response, with optional template data
+func renderHTML(w http.ResponseWriter, tmpl string, data interface{}) {
+	t := template.New("htmlElem")
+	t, err := t.Parse(tmpl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = t.Execute(w, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestError is an error in the request, such as an invalid form value
+type requestError struct {
+	msg string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+// requestErrorf returns a requestError with the formatted message
+func requestErrorf(format string, args ...interface{}) error {
+	return &requestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// httpStatus returns the HTTP status of a response that failed with the error
+func httpStatus(err error) int {
+	var re *requestError
+	switch {
+	case errors.As(err, &re), errors.Is(err, wallet.ErrNetworkMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, wallet.ErrWrongPassphrase):
+		return http.StatusForbidden
+	case errors.Is(err, wallet.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, wallet.ErrWalletLocked):
+		return http.StatusLocked
+	case errors.Is(err, wallet.ErrInsufficientFunds), errors.Is(err, wallet.ErrRejected):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, wallet.ErrMissingPrevTx):
+		return http.StatusBadGateway
+	case errors.Is(err, wallet.ErrRPCUnavailable), errors.Is(err, nodes.ErrNoHealthyNode):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// renderHTMLErr renders the error in the response, and sets the HTTP status of the response for it
+func renderHTMLErr(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(httpStatus(err))
+		renderHTML(w, `<div class="alert alert-danger" role="alert">{{ . }}</div>`, err.Error())
+	}
+}
+
+// pageWriter holds a page until it's rendered, so that the HTTP status of an error found partway through rendering
+// it can still be sent. The first status that's set is the one that's sent.
+type pageWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (p *pageWriter) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+func (p *pageWriter) Write(b []byte) (int, error) {
+	return p.buf.Write(b)
+}
+
+// page returns a handler that renders the page of the handler, and responds with it once it's rendered
+func page(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := pageWriter{ResponseWriter: w}
+		handler(&p, r)
+
+		if p.status == 0 {
+			p.status = http.StatusOK
+		}
+		w.WriteHeader(p.status)
+		_, _ = w.Write(p.buf.Bytes())
+	}
+}
+
+// The cookies that hold the display settings chosen by a user
+const (
+	unitCookie   = "unit"
+	localeCookie = "locale"
+)
+
+// settingCookie returns the value of the display setting's cookie, or an empty string if it isn't set
+func settingCookie(r *http.Request, name string) string {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	// Values are escaped, because cookies can't hold unit names such as µSOTER
+	v, err := url.QueryUnescape(c.Value)
+	if err != nil {
+		return ""
+	}
+
+	return v
+}
+
+// displayStyle returns how amounts are shown for the request: the server's style, with the unit and locale that the
+// user chose on the settings page. Settings that aren't valid are ignored.
+func displayStyle(r *http.Request) amount.Style {
+	style := display
+	unit, err := amount.ParseUnit(settingCookie(r, unitCookie))
+	if err == nil {
+		style.Unit = unit
+	}
+	locale := settingCookie(r, localeCookie)
+	if len(locale) > 0 {
+		s, err := amount.NewStyle("", locale)
+		if err == nil {
+			style.Thousands = s.Thousands
+			style.Decimal = s.Decimal
+		}
+	}
+
+	return style
+}
+
+// renderHTMLTmpl renders the template from the file in the response
+func renderHTMLTmpl(w http.ResponseWriter, name string, data interface{}) {
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// renderHTMLOpen renders the <html> element's opening tag in the response
+func renderHTMLOpen(w http.ResponseWriter) {
+	setContentType(w, "text/html")
+	renderHTML(w, "<!DOCTYPE html>", nil)
+	renderHTML(w, "<html>", nil)
+}
+
+// renderHTMLClose renders the </html> element's closing tag in the response
+func renderHTMLClose(w http.ResponseWriter) {
+	renderHTML(w, "</html>", nil)
+}
+
+// renderHTMLBodyOpen renders the <body> element's opening tag in the response
+func renderHTMLBodyOpen(w http.ResponseWriter) {
+	renderHTML(w, "<body>", nil)
+}
+
+// renderHTMLBodyClose renders the <body> element's closing tag in the response
+func renderHTMLBodyClose(w http.ResponseWriter) {
+	renderHTML(w, "</body>", nil)
+}
+
+// renderHTMLHeader renders the header.tmpl template in the response
+func renderHTMLHeader(w http.ResponseWriter, title string) {
+	renderHTMLTmpl(w, "header", title)
+}
+
+// renderHTMLNavbar renders the navbar.tmpl template in the response
+func renderHTMLNavbar(w http.ResponseWriter) {
+	type navbar struct {
+		// The 'brand' name used in the navbar
+		Brand string
+	}
+
+	n := navbar{
+		Brand: "walletweb",
+	}
+
+	renderHTMLTmpl(w, "navbar", n)
+}
+
+// renderHTMLFooter renders the footer.tmpl template in the response
+func renderHTMLFooter(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "footer", nil)
+}
+
+// renderHTMLScript renders the script.tmpl template in the response
+func renderHTMLScript(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "script", nil)
+}
+
+// RenderHTML renders the balanceInfo as a bootstrap card in the response
+func (info *balanceInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "balance", info)
+}
+
+// RenderHTML renders the txStatusInfo as a bootstrap card in the response
+func (info *txStatusInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "txstatus", info)
+}
+
+// RenderHTML renders the pendingTxInfo as a bootstrap card in the response, with forms to act on the transaction
+func (info *pendingTxInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "pendingtx", info)
+}
+
+// RenderHTML renders the historyInfos as a table in the response
+func (infos historyInfos) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "history", infos)
+}
+
+// RenderHTML renders the tipsInfo as a bootstrap card in the response
+func (info *tipsInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "tips", info)
+}
+
+// RenderHTML renders the blocks of the heightInfo as a table in the response, with links to the heights around it
+func (info *heightInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "height", info)
+}
+
+// RenderHTML renders the blockInfo as a bootstrap card in the response, with a table of its transactions
+func (info *blockInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "block", info)
+}
+
+// RenderHTML renders the inputs and outputs of the txDetailInfo as tables in the response
+func (info *txDetailInfo) RenderHTML(w http.ResponseWriter) {
+	renderHTMLTmpl(w, "txdetail", info)
+}
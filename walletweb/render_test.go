@@ -0,0 +1,179 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package walletweb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	"github.com/soteria-dag/soterd/soterutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("failed"), http.StatusInternalServerError},
+		{requestErrorf("no fee specified"), http.StatusBadRequest},
+		{fmt.Errorf("failed to open wallet: %w", &wallet.NetworkMismatchError{Of: "wallet", Want: "simnet"}), http.StatusBadRequest},
+		{fmt.Errorf("failed to send coin: %w", wallet.ErrWrongPassphrase), http.StatusForbidden},
+		{fmt.Errorf("failed to sign message: %w", wallet.ErrWalletLocked), http.StatusLocked},
+		{fmt.Errorf("failed to get block: %w", wallet.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("failed to send coin: %w", &wallet.InsufficientFundsError{Needed: 2, Available: 1}), http.StatusUnprocessableEntity},
+		{fmt.Errorf("failed to send coin: %w", &wallet.RejectedError{Reason: "already have transaction"}), http.StatusUnprocessableEntity},
+		{fmt.Errorf("failed to get balance: %w", &wallet.MissingPrevTxError{}), http.StatusBadGateway},
+		{fmt.Errorf("failed to get balance: %w", wallet.ErrRPCUnavailable), http.StatusServiceUnavailable},
+		{fmt.Errorf("failed to get balance: %w", nodes.ErrNoHealthyNode), http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		got := httpStatus(test.err)
+		if got != test.status {
+			t.Errorf("wrong status of %q; got %d, want %d", test.err, got, test.status)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	handler := page(func(w http.ResponseWriter, r *http.Request) {
+		renderHTML(w, "<p>before the error</p>", nil)
+		renderHTMLErr(w, fmt.Errorf("failed to send coin: %w", wallet.ErrWrongPassphrase))
+		// Only the first status is sent
+		renderHTMLErr(w, errors.New("failed"))
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/sendcoin", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("wrong status; got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "before the error") || !strings.Contains(body, wallet.ErrWrongPassphrase.Error()) {
+		t.Errorf("page is missing its content: %s", body)
+	}
+
+	handler = page(func(w http.ResponseWriter, r *http.Request) {
+		renderHTML(w, "<p>ok</p>", nil)
+	})
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "<p>ok</p>" {
+		t.Errorf("wrong response; got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDisplayStyle(t *testing.T) {
+	display = amount.Style{Unit: soterutil.AmountMilliSOTER, Thousands: ",", Decimal: "."}
+	defer func() {
+		display = amount.DefaultStyle
+	}()
+
+	tests := []struct {
+		unit   string
+		locale string
+		want   string
+	}{
+		{"", "", "1,500 mSOTER"},
+		{"SOTER", "", "1.5 SOTER"},
+		{"µSOTER", "de", "1.500.000 µSOTER"},
+		{"", "none", "1500 mSOTER"},
+		// Settings that aren't valid are ignored
+		{"BTC", "xx", "1,500 mSOTER"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/balance", nil)
+		if len(test.unit) > 0 {
+			r.AddCookie(&http.Cookie{Name: unitCookie, Value: url.QueryEscape(test.unit)})
+		}
+		if len(test.locale) > 0 {
+			r.AddCookie(&http.Cookie{Name: localeCookie, Value: url.QueryEscape(test.locale)})
+		}
+
+		got := displayStyle(r).Format(1500000000)
+		if got != test.want {
+			t.Errorf("wrong amount with unit %q and locale %q; got %q, want %q", test.unit, test.locale, got, test.want)
+		}
+	}
+}
+
+func TestSaveSettings(t *testing.T) {
+	handler := page(handleSettings)
+
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/settings", strings.NewReader("unit=%C2%B5SOTER&locale=en"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, r)
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("wrong status; got %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+
+	// The saved settings are used by the next request
+	next := httptest.NewRequest("GET", "/balance", nil)
+	for _, c := range rec.Result().Cookies() {
+		next.AddCookie(c)
+	}
+	got := displayStyle(next).Format(1500000000)
+	if got != "1,500,000 µSOTER" {
+		t.Errorf("wrong amount with saved settings; got %q, want %q", got, "1,500,000 µSOTER")
+	}
+
+	rec = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/settings", strings.NewReader("unit=BTC"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("wrong status of invalid settings; got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSearchTarget(t *testing.T) {
+	activeNetParams = &chaincfg.SimNetParams
+	defer func() {
+		activeNetParams = nil
+	}()
+	addr, err := soterutil.NewAddressPubKeyHash(make([]byte, 20), &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+	mainAddr, err := soterutil.NewAddressPubKeyHash(make([]byte, 20), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("failed to create address: %s", err)
+	}
+
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{"0", "/height/0"},
+		{"1234", "/height/1234"},
+		{addr.EncodeAddress(), "/address/" + addr.EncodeAddress()},
+	}
+	for _, test := range tests {
+		got, err := searchTarget(test.q)
+		if err != nil {
+			t.Errorf("failed to search for %q: %s", test.q, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("wrong page of %q; got %s, want %s", test.q, got, test.want)
+		}
+	}
+
+	invalid := []string{"-1", "99999999999", "not a hash", "abcd", mainAddr.EncodeAddress()}
+	for _, q := range invalid {
+		_, err := searchTarget(q)
+		if httpStatus(err) != http.StatusBadRequest {
+			t.Errorf("wrong error of searching for %q; got %v, want a request error", q, err)
+		}
+	}
+}
@@ -0,0 +1,904 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package walletweb
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/walletweb/static"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg/chainhash"
+	"github.com/soteria-dag/soterd/rpcclient"
+	"github.com/soteria-dag/soterd/soterutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// beforeBody renders common HTML document sections including the opening <body> element
+func beforeBody(w http.ResponseWriter, title string) {
+	renderHTMLOpen(w)
+	renderHTMLHeader(w, title)
+	renderHTMLBodyOpen(w)
+	renderHTMLNavbar(w)
+}
+
+// afterBody renders common HTML document sections starting from the closing </body> element
+// to the end of the HTML document.
+func afterBody(w http.ResponseWriter) {
+	renderHTMLFooter(w)
+	renderHTMLScript(w)
+	renderHTMLBodyClose(w)
+	renderHTMLClose(w)
+}
+
+// handleRoot responds to requests for root url /
+func handleRoot(w http.ResponseWriter, r *http.Request) {
+	// By default we'll print out balance information
+	handleBalance(w, r)
+}
+
+// handleBalance responds to requests for /balance/<address>, /address/<address> or /balance?address=<address>
+// It renders known balance of the address in the dag
+func handleBalance(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - balance"
+	beforeBody(w, title)
+	defer afterBody(w)
+	// For r.URL.Path of /balance/Sh7EBro, parts will be: ["", "balance", "Sh7EBro"]
+	parts := strings.Split(r.URL.Path, "/")
+
+	var address string
+	address = r.URL.Query().Get("address")
+
+	if len(address) == 0 && len(parts) == 3 {
+		address = parts[2]
+	}
+
+	balanceForm := `<form action="/balance" method="get">
+  <div class="form-group">
+    <label for="address">Get balance of coin address</label>
+    <input type="text" class="form-control" id="address" name="address">
+  </div>
+  <button type="submit" class="btn btn-primary">Submit</button>
+</form>`
+
+	if len(address) == 0 {
+		// Render a search box
+		renderHTML(w, balanceForm, nil)
+		renderHTML(w, "<br>", nil)
+		return
+	}
+
+	var info balanceInfo
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		info, err = getBalance(client, address, displayStyle(r))
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get balance info for %s: %w", address, err))
+		return
+	}
+	info.RenderHTML(w)
+
+	var history historyInfos
+	err = rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		history, err = getHistory(client, address, displayStyle(r))
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get history of %s: %w", address, err))
+		return
+	}
+	renderHTML(w, "<h3>History</h3>", nil)
+	history.RenderHTML(w)
+}
+
+// handleSendCoin responds to requests for /sendcoin
+// It renders a page to send coin, or generates a transaction with the given inputs and submits it to the soter network.
+func handleSendCoin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		handleSendCoinGet(w, r)
+	case "POST":
+		handleSendCoinPost(w, r)
+	default:
+		handleSendCoinGet(w, r)
+	}
+}
+
+// handleSendCoinGet responds to GET requests for /sendcoin
+func handleSendCoinGet(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - sendcoin"
+	// Render the different HTML sections for the response
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	walletMtx.Lock()
+	addresses, err := wallet.WalletAddresses(myWallet)
+	walletMtx.Unlock()
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get wallet address info: %w", err))
+		return
+	}
+
+	renderHTML(w, "<h2>Wallet addresses</h2>", nil)
+	style := displayStyle(r)
+	infos := make([]balanceInfo, len(addresses))
+	for i, address := range addresses {
+		var info balanceInfo
+		err := rpcNodes.Do(func(client *rpcclient.Client) error {
+			var err error
+			info, err = getBalance(client, address.EncodeAddress(), style)
+			return err
+		})
+		if err != nil {
+			renderHTMLErr(w, fmt.Errorf("failed to get balance info for %s: %w", address, err))
+			return
+		}
+
+		infos[i] = info
+		info.RenderHTML(w)
+		renderHTML(w, "<br>", nil)
+	}
+
+	sendForm := `<form action="/sendcoin" method="post">
+  <div class="form-group">
+    <label for="source">Wallet address to send coin from</label>
+    <select class="form-control" id="source" name="source">
+      {{- range . }}
+      <option>{{ .Address }}</option>
+      {{- end}}
+    </select>
+  </div>
+  <div class="form-group">
+    <label for="dest">Send coin to address</label>
+    <input type="text" class="form-control" id="dest" name="dest">
+  </div>
+  <div class="form-group">
+    <label for="amount">Amount to send (in SOTER, or with a unit such as 1500 mSOTER)</label>
+    <input type="text" class="form-control" id="amount" name="amount" inputmode="decimal">
+  </div>
+  <div class="form-group">
+    <label for="fee">Fee for transfer (in SOTER, or with a unit such as 1500 mSOTER)</label>
+    <input type="text" class="form-control" id="fee" name="fee" inputmode="decimal">
+  </div>
+  <div class="form-group">
+    <label for="data">Hex-encoded data to embed in the transaction (optional, up to 80 bytes)</label>
+    <input type="text" class="form-control" id="data" name="data">
+  </div>
+  <button type="submit" class="btn btn-primary">Continue</button>
+</form>`
+
+	renderHTML(w, sendForm, infos)
+	renderHTML(w, "<br>", nil)
+}
+
+// handleSendCoinPost responds to POST requests for /sendcoin
+func handleSendCoinPost(w http.ResponseWriter, r *http.Request) {
+	var source, dest soterutil.Address
+	var amt, fee soterutil.Amount
+	style := displayStyle(r)
+
+	title := "walletweb - sendcoin"
+	// Render the different HTML sections for the response
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	err := r.ParseForm()
+	if err != nil {
+		renderHTMLErr(w, requestErrorf("failed to parse POST form: %s", err))
+		return
+	}
+
+	// Validate form
+	src := r.Form.Get("source")
+	source, err = soterutil.DecodeAddress(src, activeNetParams)
+	if err != nil {
+		renderHTMLErr(w, requestErrorf("failed to parse source address %s: %s", src, err))
+		return
+	}
+
+	dst := r.Form.Get("dest")
+	dest, err = soterutil.DecodeAddress(dst, activeNetParams)
+	if err != nil {
+		renderHTMLErr(w, requestErrorf("failed to parse destination address %s: %s", dst, err))
+		return
+	}
+
+	a := r.Form.Get("amount")
+	if len(a) == 0 {
+		renderHTMLErr(w, requestErrorf("no coin amount specified"))
+		return
+	}
+	amt, err = amount.Parse(a, soterutil.AmountSOTER)
+	if err != nil {
+		renderHTMLErr(w, requestErrorf("failed to parse coin amount: %s", err))
+		return
+	}
+
+	f := r.Form.Get("fee")
+	if len(f) == 0 {
+		renderHTMLErr(w, requestErrorf("no fee specified"))
+		return
+	}
+	fee, err = amount.Parse(f, soterutil.AmountSOTER)
+	if err != nil {
+		renderHTMLErr(w, requestErrorf("failed to parse transaction fee: %s", err))
+		return
+	}
+
+	var opts wallet.SendOptions
+	d := r.Form.Get("data")
+	if len(d) > 0 {
+		opts.Data, err = hex.DecodeString(d)
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("failed to decode data %s: %s", d, err))
+			return
+		}
+
+		_, err = wallet.NullDataOutput(opts.Data)
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("%s", err))
+			return
+		}
+	}
+
+	// Look for transactions with spendable outputs
+	var matches []wallet.TxMatch
+	err = rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		matches, err = wallet.SpendableTxOuts(client, []soterutil.Address{source}, activeNetParams)
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to find matching transactions in dag for address %s: %w", source, err))
+		return
+	}
+
+	// Leave out outputs that are being used by other in-flight or pending transactions
+	matches = reserver.Available(matches)
+	matches, err = wallet.ExcludePendingInputs(myWallet, matches)
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to read pending transactions from wallet: %w", err))
+		return
+	}
+
+	if len(matches) == 0 {
+		renderHTMLErr(w, fmt.Errorf("no matching transactions for source address %s found in dag: %w", source,
+			&wallet.InsufficientFundsError{Needed: amt + fee}))
+		return
+	}
+
+	spendable := soterutil.Amount(0)
+	for _, m := range matches {
+		spendable += m.Amount
+	}
+
+	if amt+fee > spendable {
+		renderHTMLErr(w, fmt.Errorf("not enough coin found to satisfy amount requested for transaction; %s requested + %s fee: %w",
+			style.Format(amt), style.Format(fee), &wallet.InsufficientFundsError{Needed: amt + fee, Available: spendable}))
+		return
+	}
+
+	// Ask for the private password before sending. It's only used for this send, and isn't kept.
+	privPass := r.PostForm.Get("priv")
+	if len(r.PostForm.Get("confirm")) == 0 {
+		confirmForm := `<h2>Confirm transaction</h2>
+<ul class="list-unstyled">
+  <li>From: {{ .Source }}</li>
+  <li>To: {{ .Dest }}</li>
+  <li>Amount: {{ .Amount }}</li>
+  <li>Fee: {{ .Fee }}</li>
+  {{- if .Data }}
+  <li>Data: {{ .Data }}</li>
+  {{- end }}
+</ul>
+<form action="/sendcoin" method="post">
+  <input type="hidden" name="source" value="{{ .Source }}">
+  <input type="hidden" name="dest" value="{{ .Dest }}">
+  <input type="hidden" name="amount" value="{{ .AmountValue }}">
+  <input type="hidden" name="fee" value="{{ .FeeValue }}">
+  <input type="hidden" name="data" value="{{ .Data }}">
+  <input type="hidden" name="confirm" value="yes">
+  <div class="form-group">
+    <label for="priv">Wallet private password</label>
+    <input type="password" class="form-control" id="priv" name="priv" autocomplete="off">
+  </div>
+  <button type="submit" class="btn btn-primary">Confirm and send</button>
+  <a href="/sendcoin" class="btn btn-secondary">Cancel</a>
+</form>`
+
+		renderHTML(w, confirmForm, map[string]string{
+			"Source":      source.EncodeAddress(),
+			"Dest":        dest.EncodeAddress(),
+			"Amount":      style.Format(amt),
+			"AmountValue": a,
+			"Fee":         style.Format(fee),
+			"FeeValue":    f,
+			"Data":        d,
+		})
+		renderHTML(w, "<br>", nil)
+		return
+	}
+
+	// Hold the outputs we'll use, until the transaction is accepted or fails
+	selected := wallet.SelectInputs(matches, amt, fee)
+	err = reserver.Reserve(selected)
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to reserve outputs for transaction: %w", err))
+		return
+	}
+
+	// Sends aren't repeated on another node, in case the transaction was broadcast before the node failed
+	client, err := rpcNodes.Client()
+	if err != nil {
+		reserver.Release(selected)
+		renderHTMLErr(w, fmt.Errorf("failed to send coin: %w", err))
+		return
+	}
+	walletMtx.Lock()
+	txHash, err := wallet.SendWithOptions(client, myWallet, privPass, selected, dest, amt, fee, &opts)
+	walletMtx.Unlock()
+	if err != nil {
+		// The outputs weren't spent, so other transactions can use them
+		reserver.Release(selected)
+		renderHTMLErr(w, fmt.Errorf("failed to send coin: %w", err))
+		return
+	}
+
+	renderHTML(w, `<p>Sent {{ .Amount }} to {{ .Dest }} in transaction <a href="/tx/{{ .Hash }}">{{ .Hash }}</a></p>`,
+		map[string]string{"Amount": style.Format(amt), "Dest": dest.String(), "Hash": txHash.String()})
+	renderHTML(w, "<br>", nil)
+}
+
+// handleTx responds to requests for /tx/<hash> or /tx?hash=<hash>
+// It renders the status of the transaction; whether it's in the mempool or a block, and how many confirmations it has,
+// and its inputs, outputs and fee.
+func handleTx(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - transaction"
+	beforeBody(w, title)
+	defer afterBody(w)
+	// For r.URL.Path of /tx/c8a606a7, parts will be: ["", "tx", "c8a606a7"]
+	parts := strings.Split(r.URL.Path, "/")
+
+	var hash string
+	hash = r.URL.Query().Get("hash")
+
+	if len(hash) == 0 && len(parts) == 3 {
+		hash = parts[2]
+	}
+
+	txForm := `<form action="/tx" method="get">
+  <div class="form-group">
+    <label for="hash">Get status of transaction</label>
+    <input type="text" class="form-control" id="hash" name="hash">
+  </div>
+  <button type="submit" class="btn btn-primary">Submit</button>
+</form>`
+
+	if len(hash) == 0 {
+		// Render a search box
+		renderHTML(w, txForm, nil)
+		renderHTML(w, "<br>", nil)
+		return
+	}
+
+	var info txStatusInfo
+	var status *wallet.TxStatus
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		info, status, err = getTxStatus(client, hash)
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get status of transaction %s: %w", hash, err))
+		return
+	}
+	info.RenderHTML(w)
+
+	var detail txDetailInfo
+	err = rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		detail, err = getTxDetail(client, status, displayStyle(r))
+		return err
+	})
+	if errors.Is(err, wallet.ErrNotFound) {
+		// The status already shows that the transaction isn't known
+		return
+	}
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get details of transaction %s: %w", hash, err))
+		return
+	}
+	detail.RenderHTML(w)
+}
+
+// handleTips responds to requests for /tips
+// It renders the tips of the dag, and links to the heights below them.
+func handleTips(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - explorer"
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	var info tipsInfo
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		info, err = getTips(client)
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get dag tips: %w", err))
+		return
+	}
+	renderHTML(w, "<h2>DAG tips</h2>", nil)
+	info.RenderHTML(w)
+}
+
+// handleHeight responds to requests for /height/<height> or /height?height=<height>
+// It renders the blocks at the height, which are parallel to each other in the dag.
+func handleHeight(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - height"
+	beforeBody(w, title)
+	defer afterBody(w)
+	// For r.URL.Path of /height/12, parts will be: ["", "height", "12"]
+	parts := strings.Split(r.URL.Path, "/")
+
+	var h string
+	h = r.URL.Query().Get("height")
+
+	if len(h) == 0 && len(parts) == 3 {
+		h = parts[2]
+	}
+
+	height, err := strconv.ParseInt(h, 10, 32)
+	if err != nil || height < 0 {
+		renderHTMLErr(w, requestErrorf("failed to parse height %s", h))
+		return
+	}
+
+	var info heightInfo
+	err = rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		info, err = getHeight(client, int32(height))
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get blocks at height %d: %w", height, err))
+		return
+	}
+	renderHTML(w, "<h2>Blocks at height {{ . }}</h2>", height)
+	info.RenderHTML(w)
+}
+
+// handleBlock responds to requests for /block/<hash> or /block?hash=<hash>
+// It renders the block's place in the dag, its parents, its coinbase and its transactions.
+func handleBlock(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - block"
+	beforeBody(w, title)
+	defer afterBody(w)
+	// For r.URL.Path of /block/2a1b9f3c, parts will be: ["", "block", "2a1b9f3c"]
+	parts := strings.Split(r.URL.Path, "/")
+
+	var hash string
+	hash = r.URL.Query().Get("hash")
+
+	if len(hash) == 0 && len(parts) == 3 {
+		hash = parts[2]
+	}
+
+	if len(hash) == 0 {
+		renderHTMLErr(w, requestErrorf("no block hash specified"))
+		return
+	}
+
+	var info blockInfo
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		info, err = getBlock(client, hash, displayStyle(r))
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get block %s: %w", hash, err))
+		return
+	}
+	info.RenderHTML(w)
+}
+
+// handleSearch responds to requests for /search?q=<query>
+// It redirects to the page of the block, transaction, height or address in the query.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var target string
+	var err error
+	if len(q) == 0 {
+		err = requestErrorf("nothing to search for; give a block or transaction hash, a height or an address")
+	} else {
+		target, err = searchTarget(q)
+	}
+	if err != nil {
+		beforeBody(w, "walletweb - search")
+		defer afterBody(w)
+		renderHTMLErr(w, err)
+		return
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// searchTarget returns the path of the page of the block or transaction hash, height or address in the query. Hashes
+// of blocks that the node doesn't have are taken to be transaction hashes.
+func searchTarget(q string) (string, error) {
+	height, err := strconv.ParseInt(q, 10, 32)
+	if err == nil && height >= 0 {
+		return fmt.Sprintf("/height/%d", height), nil
+	}
+
+	if len(q) == 2*chainhash.HashSize {
+		hash, err := chainhash.NewHashFromStr(q)
+		if err == nil {
+			isBlock := false
+			err = rpcNodes.Do(func(client *rpcclient.Client) error {
+				_, err := wallet.GetBlockDetail(client, hash)
+				if errors.Is(err, wallet.ErrNotFound) {
+					return nil
+				}
+				isBlock = err == nil
+				return err
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to look for block %s: %w", hash, err)
+			}
+			if isBlock {
+				return "/block/" + hash.String(), nil
+			}
+			return "/tx/" + hash.String(), nil
+		}
+	}
+
+	addr, err := soterutil.DecodeAddress(q, activeNetParams)
+	if err == nil && addr.IsForNet(activeNetParams) {
+		return "/address/" + addr.EncodeAddress(), nil
+	}
+
+	return "", requestErrorf("%s isn't a block or transaction hash, a height or an address", q)
+}
+
+// handlePending responds to requests for /pending
+// It renders the pending transactions sent from the wallet, or acts on one of them for POST requests.
+func handlePending(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - pending"
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	if r.Method == "POST" {
+		err := handlePendingAction(w, r)
+		if err != nil {
+			renderHTMLErr(w, err)
+			return
+		}
+	}
+
+	renderHTML(w, "<h2>Pending transactions</h2>", nil)
+	var infos []pendingTxInfo
+	err := rpcNodes.Do(func(client *rpcclient.Client) error {
+		var err error
+		infos, err = getPendingTxs(client, myWallet)
+		return err
+	})
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get pending transactions: %w", err))
+		return
+	}
+
+	if len(infos) == 0 {
+		renderHTML(w, "<p>There are no pending transactions</p>", nil)
+	}
+
+	for _, info := range infos {
+		info.RenderHTML(w)
+		renderHTML(w, "<br>", nil)
+	}
+}
+
+// handlePendingAction rebroadcasts, abandons or bumps the fee of the pending transaction in the POST form
+func handlePendingAction(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return requestErrorf("failed to parse POST form: %s", err)
+	}
+
+	h := r.Form.Get("hash")
+	hash, err := chainhash.NewHashFromStr(h)
+	if err != nil {
+		return requestErrorf("failed to parse transaction hash %s: %s", h, err)
+	}
+
+	walletMtx.Lock()
+	defer walletMtx.Unlock()
+
+	action := r.Form.Get("action")
+	switch action {
+	case "rebroadcast":
+		err = rpcNodes.Do(func(client *rpcclient.Client) error {
+			return wallet.Rebroadcast(client, myWallet, hash)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to rebroadcast transaction %s: %w", hash, err)
+		}
+
+		renderHTML(w, "<p>Rebroadcast transaction {{ . }}</p>", hash.String())
+	case "abandon":
+		abandoned, err := wallet.Abandon(myWallet, hash)
+		if err != nil {
+			return fmt.Errorf("failed to abandon transaction %s: %w", hash, err)
+		}
+
+		for _, s := range abandoned {
+			// The inputs of abandoned transactions can be used by new ones
+			reserver.ReleaseOutPoints(s.Inputs)
+			renderHTML(w, "<p>Abandoned transaction {{ . }}</p>", s.Hash.String())
+		}
+	case "bumpfee":
+		f := r.Form.Get("fee")
+		fee, err := amount.Parse(f, soterutil.AmountSOTER)
+		if err != nil {
+			return requestErrorf("failed to parse transaction fee: %s", err)
+		}
+
+		// The private password is only used for this transaction, and isn't kept
+		client, err := rpcNodes.Client()
+		if err != nil {
+			return fmt.Errorf("failed to bump fee of transaction %s: %w", hash, err)
+		}
+		childHash, err := wallet.BumpFee(client, myWallet, r.PostForm.Get("priv"), hash, fee)
+		if err != nil {
+			return fmt.Errorf("failed to bump fee of transaction %s: %w", hash, err)
+		}
+
+		renderHTML(w, `<p>Sent child transaction <a href="/tx/{{ . }}">{{ . }}</a></p>`, childHash.String())
+	default:
+		return requestErrorf("unknown action %s", action)
+	}
+
+	renderHTML(w, "<br>", nil)
+	return nil
+}
+
+// handleSignMessage responds to requests for /signmessage
+// It renders a form to sign a message with a wallet address, and the signature for POST requests.
+func handleSignMessage(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - sign message"
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	if r.Method == "POST" {
+		err := r.ParseForm()
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("failed to parse POST form: %s", err))
+			return
+		}
+
+		a := r.Form.Get("address")
+		addr, err := soterutil.DecodeAddress(a, activeNetParams)
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("failed to parse address %s: %s", a, err))
+			return
+		}
+		message := r.Form.Get("message")
+
+		walletMtx.Lock()
+		// The private password is only used for this message, and isn't kept
+		sig, err := wallet.SignMessage(myWallet, r.PostForm.Get("priv"), addr, message)
+		walletMtx.Unlock()
+		if err != nil {
+			renderHTMLErr(w, fmt.Errorf("failed to sign message: %w", err))
+			return
+		}
+
+		renderHTML(w, `<p>Signature of the message by {{ .Address }}:</p>
+<pre>{{ .Signature }}</pre>`, map[string]string{"Address": addr.EncodeAddress(), "Signature": sig})
+		renderHTML(w, "<br>", nil)
+		return
+	}
+
+	walletMtx.Lock()
+	addresses, err := wallet.WalletAddresses(myWallet)
+	walletMtx.Unlock()
+	if err != nil {
+		renderHTMLErr(w, fmt.Errorf("failed to get wallet address info: %w", err))
+		return
+	}
+
+	signForm := `<form action="/signmessage" method="post">
+  <div class="form-group">
+    <label for="address">Wallet address to sign the message with</label>
+    <select class="form-control" id="address" name="address">
+      {{- range . }}
+      <option>{{ .EncodeAddress }}</option>
+      {{- end}}
+    </select>
+  </div>
+  <div class="form-group">
+    <label for="message">Message</label>
+    <textarea class="form-control" id="message" name="message" rows="3"></textarea>
+  </div>
+  <div class="form-group">
+    <label for="priv">Wallet private password</label>
+    <input type="password" class="form-control" id="priv" name="priv" autocomplete="off">
+  </div>
+  <button type="submit" class="btn btn-primary">Sign</button>
+</form>`
+
+	renderHTML(w, signForm, addresses)
+	renderHTML(w, "<br>", nil)
+}
+
+// handleVerifyMessage responds to requests for /verifymessage
+// It renders a form to check the signature of a message, and whether the signature is valid for POST requests.
+func handleVerifyMessage(w http.ResponseWriter, r *http.Request) {
+	title := "walletweb - verify message"
+	beforeBody(w, title)
+	defer afterBody(w)
+
+	if r.Method == "POST" {
+		err := r.ParseForm()
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("failed to parse POST form: %s", err))
+			return
+		}
+
+		a := r.Form.Get("address")
+		addr, err := soterutil.DecodeAddress(a, activeNetParams)
+		if err != nil {
+			renderHTMLErr(w, requestErrorf("failed to parse address %s: %s", a, err))
+			return
+		}
+
+		valid, err := wallet.VerifyMessage(addr, r.Form.Get("signature"), r.Form.Get("message"), activeNetParams)
+		if err != nil {
+			renderHTMLErr(w, fmt.Errorf("failed to verify message: %w", err))
+			return
+		}
+
+		if valid {
+			renderHTML(w, `<div class="alert alert-success" role="alert">Signature is valid for address {{ . }}</div>`, a)
+		} else {
+			renderHTML(w, `<div class="alert alert-danger" role="alert">Signature is not valid for address {{ . }}</div>`, a)
+		}
+	}
+
+	verifyForm := `<form action="/verifymessage" method="post">
+  <div class="form-group">
+    <label for="address">Address that signed the message</label>
+    <input type="text" class="form-control" id="address" name="address">
+  </div>
+  <div class="form-group">
+    <label for="message">Message</label>
+    <textarea class="form-control" id="message" name="message" rows="3"></textarea>
+  </div>
+  <div class="form-group">
+    <label for="signature">Signature</label>
+    <input type="text" class="form-control" id="signature" name="signature">
+  </div>
+  <button type="submit" class="btn btn-primary">Verify</button>
+</form>`
+
+	renderHTML(w, verifyForm, nil)
+	renderHTML(w, "<br>", nil)
+}
+
+// handleSettings responds to requests for /settings
+// It renders a form to choose the unit and number format that amounts are shown in, and keeps the choice in cookies
+// for POST requests.
+func handleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		err := saveSettings(w, r)
+		if err == nil {
+			// Show the form again, with the settings that were saved
+			http.Redirect(w, r, "/settings", http.StatusSeeOther)
+			return
+		}
+
+		beforeBody(w, "walletweb - settings")
+		defer afterBody(w)
+		renderHTMLErr(w, err)
+		return
+	}
+
+	beforeBody(w, "walletweb - settings")
+	defer afterBody(w)
+
+	settingsForm := `<p>Amounts are shown like {{ .Example }}</p>
+<form action="/settings" method="post">
+  <div class="form-group">
+    <label for="unit">Unit to show amounts in</label>
+    <select class="form-control" id="unit" name="unit">
+      <option value="">Default ({{ .DefaultUnit }})</option>
+      {{- range .Units }}
+      <option{{ if eq . $.Unit }} selected{{ end }}>{{ . }}</option>
+      {{- end }}
+    </select>
+  </div>
+  <div class="form-group">
+    <label for="locale">Number format, by language</label>
+    <select class="form-control" id="locale" name="locale">
+      <option value="">Default ({{ .DefaultNumber }})</option>
+      {{- range .Locales }}
+      <option{{ if eq . $.Locale }} selected{{ end }}>{{ . }}</option>
+      {{- end }}
+    </select>
+  </div>
+  <button type="submit" class="btn btn-primary">Save</button>
+</form>`
+
+	example := soterutil.Amount(123456789000000)
+	renderHTML(w, settingsForm, map[string]interface{}{
+		"Example":       displayStyle(r).Format(example),
+		"Units":         amount.UnitNames(),
+		"Unit":          settingCookie(r, unitCookie),
+		"DefaultUnit":   amount.UnitName(display.Unit),
+		"Locales":       amount.LocaleNames(),
+		"Locale":        settingCookie(r, localeCookie),
+		"DefaultNumber": display.FormatNumber(example),
+	})
+	renderHTML(w, "<br>", nil)
+}
+
+// saveSettings keeps the display settings of the POST form in cookies. An empty setting is the server's default, so
+// its cookie is removed.
+func saveSettings(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return requestErrorf("failed to parse POST form: %s", err)
+	}
+
+	unit, locale := r.PostForm.Get("unit"), r.PostForm.Get("locale")
+	_, err = amount.NewStyle(unit, locale)
+	if err != nil {
+		return requestErrorf("failed to change settings: %s", err)
+	}
+
+	for name, value := range map[string]string{unitCookie: unit, localeCookie: locale} {
+		c := http.Cookie{
+			Name:     name,
+			Value:    url.QueryEscape(value),
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		}
+		if len(value) == 0 {
+			c.MaxAge = -1
+		}
+		http.SetCookie(w, &c)
+	}
+
+	return nil
+}
+
+// handleFavicon responds to requests for /favicon.ico
+func handleFavicon(w http.ResponseWriter, r *http.Request) {
+	setContentType(w, "image/vnd.microsoft.icon")
+	_, err := w.Write(static.Favicon)
+	if err != nil {
+		log.Printf("Failed to respond to /favicon.ico: %s", err)
+	}
+}
+
+// handleLogo responds to requests for /static/soteria_logo.jpg
+func handleLogo(w http.ResponseWriter, r *http.Request) {
+	setContentType(w, "image/jpeg")
+	_, err := w.Write(static.SoteriaLogo)
+	if err != nil {
+		log.Printf("Failed to respond to /static/soteria_logo.jpg: %s", err)
+	}
+}
@@ -0,0 +1,433 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+var (
+	// templateCache holds parsed templates
+	templateCache = make(map[string]*template.Template)
+	// Maps the template to its generating function
+	templates = map[string]func()(*template.Template, error){
+		"header": header,
+		"footer": footer,
+		"navbar": navbar,
+		"script": script,
+		"balance": balance,
+		"txstatus": txStatus,
+		"pendingtx": pendingTx,
+		"history": history,
+		"tips": tips,
+		"height": height,
+		"block": block,
+		"txdetail": txDetail,
+	}
+)
+
+// header generates a header template
+func header() (*template.Template, error) {
+	tpl := `<head>
+    <title>{{ . }}</title>
+    <!-- Bootstrap CSS -->
+    <link href="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/css/bootstrap.min.css" rel="stylesheet" integrity="sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T" crossorigin="anonymous">
+</head>`
+
+	t := template.New("header")
+	return t.Parse(tpl)
+}
+
+// footer generates a footer template
+func footer() (*template.Template, error) {
+	tpl := `<footer class="pt-2 border-top">
+    <div class="d-flex justify-content-center">
+        <h5><small class="text-muted">&copy; 2018-%d The Soteria DAG developers</small></h5>
+    </div>
+</footer>`
+
+	t := template.New("footer")
+	return t.Parse(fmt.Sprintf(tpl, time.Now().Year()))
+}
+
+func navbar() (*template.Template, error) {
+	tpl := `<nav class="navbar navbar-expand-lg navbar-light bg-light">
+    <a class="navbar-brand" href="/">
+        <img src="/static/soteria_logo.jpg" width="30" height="30" class="d-inline-block align-top" alt="soteria logo">
+        {{ .Brand }}</a>
+    <button class="navbar-toggler" type="button" data-toggle="collapse" data-target="#navbarSupportedContent" aria-controls="navbarSupportedContent" aria-expanded="false" aria-label="Toggle navigation">
+        <span class="navbar-toggler-icon"></span>
+    </button>
+
+    <div class="collapse navbar-collapse" id="navbarSupportedContent">
+        <ul class="navbar-nav mr-auto">
+            <li class="nav-item">
+                <a class="nav-link" href="/balance">balance</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/sendcoin">send coin</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/tx">transaction</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/pending">pending</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/signmessage">sign message</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/verifymessage">verify message</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/tips">explorer</a>
+            </li>
+            <li class="nav-item">
+                <a class="nav-link" href="/settings">settings</a>
+            </li>
+        </ul>
+        <form class="form-inline" action="/search" method="get">
+            <input class="form-control mr-2" type="search" name="q" placeholder="Hash, height or address" aria-label="Search">
+            <button class="btn btn-outline-secondary" type="submit">Search</button>
+        </form>
+    </div>
+</nav>`
+
+	t := template.New("navbar")
+	return t.Parse(tpl)
+}
+
+func script() (*template.Template, error) {
+	tpl := `<!-- Bootstrap JS -->
+<script src="https://code.jquery.com/jquery-3.3.1.slim.min.js" integrity="sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo" crossorigin="anonymous"></script>
+<script src="https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.14.7/umd/popper.min.js" integrity="sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1" crossorigin="anonymous"></script>
+<script src="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/js/bootstrap.min.js" integrity="sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM" crossorigin="anonymous"></script>`
+
+	t := template.New("script")
+	return t.Parse(tpl)
+}
+
+func balance() (*template.Template, error) {
+	tpl := `<div class="card-group">
+    <div class="card">
+        <div class="card-body">
+            <ul class="list-unstyled">
+                <li>Address: <a href="/address/{{ .Address }}">{{ .Address }}</a></li>
+				<li>Balance: {{ .Balance }}</li>
+				<li>Spendable: {{ .Spendable }}</li>
+            </ul>
+        </div>
+    </div>
+</div>`
+
+	t := template.New("balance")
+	return t.Parse(tpl)
+}
+
+func txStatus() (*template.Template, error) {
+	tpl := `<div class="card-group">
+    <div class="card">
+        <div class="card-body">
+            <ul class="list-unstyled">
+                <li>Transaction: {{ .Hash }}</li>
+                <li>Status: {{ .State }}</li>
+                {{- if .InBlock }}
+                <li>Block: <a href="/block/{{ .BlockHash }}">{{ .BlockHash }}</a></li>
+                <li>Block height: <a href="/height/{{ .BlockHeight }}">{{ .BlockHeight }}</a></li>
+                <li>Confirmations: {{ .Confirmations }}</li>
+                {{- end }}
+            </ul>
+        </div>
+    </div>
+</div>`
+
+	t := template.New("txstatus")
+	return t.Parse(tpl)
+}
+
+func pendingTx() (*template.Template, error) {
+	tpl := `<div class="card-group">
+    <div class="card">
+        <div class="card-body">
+            <ul class="list-unstyled">
+                <li>Transaction: <a href="/tx/{{ .Hash }}">{{ .Hash }}</a></li>
+                <li>Inputs: {{ .Inputs }}</li>
+                <li>Node status: {{ .State }}</li>
+                <li>Last broadcast: {{ .LastBroadcast }}</li>
+                {{- if .Parent }}
+                <li>Bumps fee of: <a href="/tx/{{ .Parent }}">{{ .Parent }}</a></li>
+                {{- end }}
+                {{- if .LockedUntil }}
+                <li>Locked until: {{ .LockedUntil }}</li>
+                {{- end }}
+            </ul>
+            <form class="form-inline" action="/pending" method="post">
+                <input type="hidden" name="hash" value="{{ .Hash }}">
+                <button type="submit" class="btn btn-primary mr-2" name="action" value="rebroadcast">Rebroadcast</button>
+                <button type="submit" class="btn btn-danger mr-2" name="action" value="abandon">Abandon</button>
+            </form>
+            <form class="form-inline mt-2" action="/pending" method="post">
+                <input type="hidden" name="hash" value="{{ .Hash }}">
+                <input type="hidden" name="action" value="bumpfee">
+                <input type="text" class="form-control mr-2" name="fee" placeholder="Fee (SOTER)" inputmode="decimal">
+                <input type="password" class="form-control mr-2" name="priv" placeholder="Private password" autocomplete="off">
+                <button type="submit" class="btn btn-secondary">Bump fee</button>
+            </form>
+        </div>
+    </div>
+</div>`
+
+	t := template.New("pendingtx")
+	return t.Parse(tpl)
+}
+
+func history() (*template.Template, error) {
+	tpl := `<table class="table table-sm">
+    <thead>
+        <tr>
+            <th>Height</th>
+            <th>Transaction</th>
+            <th>Received</th>
+            <th>Sent</th>
+            <th>Data</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{- range . }}
+        <tr>
+            <td><a href="/block/{{ .BlockHash }}">{{ .BlockHeight }}</a></td>
+            <td><a href="/tx/{{ .Hash }}">{{ .Hash }}</a></td>
+            <td>{{ .Received }}</td>
+            <td>{{ .Sent }}</td>
+            <td>
+                {{- range .Data }}
+                <div><code>{{ .Hex }}</code>{{ if .Text }} ({{ .Text }}){{ end }}</div>
+                {{- end }}
+            </td>
+        </tr>
+        {{- end }}
+    </tbody>
+</table>`
+
+	t := template.New("history")
+	return t.Parse(tpl)
+}
+
+func tips() (*template.Template, error) {
+	tpl := `<div class="card-group">
+    <div class="card">
+        <div class="card-body">
+            <ul class="list-unstyled">
+                <li>Height: <a href="/height/{{ .MaxHeight }}">{{ .MaxHeight }}</a></li>
+                <li>Tips:
+                    <ul>
+                        {{- range .Tips }}
+                        <li><a href="/block/{{ . }}">{{ . }}</a></li>
+                        {{- end }}
+                    </ul>
+                </li>
+                <li>Recent heights:
+                    {{- range .Heights }}
+                    <a href="/height/{{ . }}">{{ . }}</a>
+                    {{- end }}
+                </li>
+            </ul>
+        </div>
+    </div>
+</div>`
+
+	t := template.New("tips")
+	return t.Parse(tpl)
+}
+
+func height() (*template.Template, error) {
+	tpl := `<nav class="mb-2">
+    {{- if .HasPrev }}
+    <a href="/height/{{ .Prev }}">&larr; height {{ .Prev }}</a>
+    {{- end }}
+    {{- if .HasNext }}
+    <a class="ml-3" href="/height/{{ .Next }}">height {{ .Next }} &rarr;</a>
+    {{- end }}
+</nav>
+<table class="table table-sm">
+    <thead>
+        <tr>
+            <th>Block</th>
+            <th>Time</th>
+            <th>Parents</th>
+            <th>Transactions</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{- range .Blocks }}
+        <tr>
+            <td><a href="/block/{{ .Hash }}">{{ .Hash }}</a></td>
+            <td>{{ .Time }}</td>
+            <td>{{ .Parents }}</td>
+            <td>{{ .Txs }}</td>
+        </tr>
+        {{- end }}
+    </tbody>
+</table>`
+
+	t := template.New("height")
+	return t.Parse(tpl)
+}
+
+func block() (*template.Template, error) {
+	tpl := `<div class="card-group">
+    <div class="card">
+        <div class="card-body">
+            <ul class="list-unstyled">
+                <li>Block: {{ .Hash }}</li>
+                <li>Height: <a href="/height/{{ .Height }}">{{ .Height }}</a></li>
+                <li>Confirmations: {{ .Confirmations }}</li>
+                <li>Time: {{ .Time }}</li>
+                <li>Parents:
+                    <ul>
+                        {{- range .Parents }}
+                        <li><a href="/block/{{ . }}">{{ . }}</a></li>
+                        {{- end }}
+                    </ul>
+                </li>
+                {{- if .Coinbase }}
+                <li>Coinbase: <a href="/tx/{{ .Coinbase }}">{{ .Coinbase }}</a>
+                    <ul>
+                        {{- range .CoinbaseOutputs }}
+                        <li>{{ .Amount }}{{ range .Addresses }} to <a href="/address/{{ . }}">{{ . }}</a>{{ end }}</li>
+                        {{- end }}
+                    </ul>
+                </li>
+                {{- end }}
+            </ul>
+        </div>
+    </div>
+</div>
+<h3>Transactions</h3>
+<table class="table table-sm">
+    <thead>
+        <tr>
+            <th>Transaction</th>
+            <th>Inputs</th>
+            <th>Outputs</th>
+            <th>Sent</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{- range .Txs }}
+        <tr>
+            <td><a href="/tx/{{ .Hash }}">{{ .Hash }}</a></td>
+            <td>{{ .Inputs }}</td>
+            <td>{{ .Outputs }}</td>
+            <td>{{ .Total }}</td>
+        </tr>
+        {{- end }}
+    </tbody>
+</table>`
+
+	t := template.New("block")
+	return t.Parse(tpl)
+}
+
+func txDetail() (*template.Template, error) {
+	tpl := `<h3>Inputs</h3>
+<table class="table table-sm">
+    <thead>
+        <tr>
+            <th>Spends</th>
+            <th>From</th>
+            <th>Amount</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{- range .Inputs }}
+        <tr>
+            {{- if .Coinbase }}
+            <td colspan="3">Coinbase (new coin)</td>
+            {{- else }}
+            <td><a href="/tx/{{ .PrevTx }}">{{ .PrevTx }}</a>:{{ .PrevIndex }}</td>
+            <td>
+                {{- range .Addresses }}
+                <div><a href="/address/{{ . }}">{{ . }}</a></div>
+                {{- end }}
+            </td>
+            <td>{{ .Amount }}</td>
+            {{- end }}
+        </tr>
+        {{- end }}
+    </tbody>
+</table>
+<h3>Outputs</h3>
+<table class="table table-sm">
+    <thead>
+        <tr>
+            <th>Index</th>
+            <th>To</th>
+            <th>Type</th>
+            <th>Amount</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{- range .Outputs }}
+        <tr>
+            <td>{{ .Index }}</td>
+            <td>
+                {{- range .Addresses }}
+                <div><a href="/address/{{ . }}">{{ . }}</a></div>
+                {{- end }}
+                {{- with .Data }}
+                <div><code>{{ .Hex }}</code>{{ if .Text }} ({{ .Text }}){{ end }}</div>
+                {{- end }}
+            </td>
+            <td>{{ .Type }}</td>
+            <td>{{ .Amount }}</td>
+        </tr>
+        {{- end }}
+    </tbody>
+</table>
+{{- if not .Coinbase }}
+<p>Fee: {{ .Fee }}</p>
+{{- end }}`
+
+	t := template.New("txdetail")
+	return t.Parse(tpl)
+}
+
+func init() {
+	// Pre-parse templates
+	for name, tplGen := range templates {
+		t, err := tplGen()
+		if err != nil {
+			panic(err)
+		}
+
+		templateCache[name] = t
+	}
+}
+
+// ExecuteTemplate looks for the given template name, and if available executes it with the given data against the writer
+func ExecuteTemplate(w http.ResponseWriter, name string, data interface{}) error {
+	t, ok := templateCache[name]
+	if ok {
+		return t.Execute(w, data)
+	}
+
+	tplGen, ok := templates[name]
+	if !ok {
+		return fmt.Errorf("No template with name %s", name)
+	}
+
+	t, err := tplGen()
+	if err != nil {
+		return fmt.Errorf("Failed to generate template %s: %s", name, err)
+	}
+
+	templateCache[name] = t
+
+	return t.Execute(w, data)
+}
\ No newline at end of file
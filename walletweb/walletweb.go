@@ -0,0 +1,129 @@
+// Copyright (c) 2018-2019 The Soteria DAG developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package walletweb is a web ui for retrieving wallet address balance and sending coin to the soter network
+package walletweb
+
+import (
+	"fmt"
+	"github.com/soteria-dag/sotertools/amount"
+	"github.com/soteria-dag/sotertools/nodes"
+	"github.com/soteria-dag/sotertools/wallet"
+	"github.com/soteria-dag/soterd/chaincfg"
+	soterwallet "github.com/soteria-dag/soterwallet/wallet"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+var (
+	// Clients are held in a global variable, to make them available to the handlers of routes.
+	// rpcNodes fails over between the soterd nodes, and reconnects to them when they're back up.
+	rpcNodes        *nodes.Manager
+	activeNetParams *chaincfg.Params
+	myWallet        *soterwallet.Wallet
+
+	// walletMtx serializes use of myWallet between concurrently-served requests
+	walletMtx sync.Mutex
+	// reserver holds outputs selected for in-flight sends, so that concurrent sends don't double-spend them
+	reserver *wallet.Reserver
+	// display is how amounts are shown, unless a user chooses otherwise on the settings page
+	display = amount.DefaultStyle
+)
+
+// Options are the settings of the web ui server
+type Options struct {
+	// Addr is the [ip]:port to listen on
+	Addr string
+	// ReserveTimeout is how long outputs used by a sent transaction are held, before they can be used by another
+	ReserveTimeout time.Duration
+	// LockTimeout is the longest time the wallet can stay unlocked, before it's locked again
+	LockTimeout time.Duration
+	// CheckInterval is how often the health of the soterd nodes is checked, to fail over from nodes that are down
+	CheckInterval time.Duration
+	// Display is how amounts are shown, unless a user chooses otherwise on the settings page
+	Display amount.Style
+}
+
+// Serve serves the web ui of the wallet, using the soterd nodes, until it's interrupted or the server fails. The
+// private password isn't given to Serve; it's entered on the page of each action that needs it, and isn't kept.
+func Serve(w *soterwallet.Wallet, m *nodes.Manager, params *chaincfg.Params, opts Options) error {
+	myWallet = w
+	rpcNodes = m
+	activeNetParams = params
+	reserver = wallet.NewReserver(opts.ReserveTimeout)
+	if opts.Display != (amount.Style{}) {
+		display = opts.Display
+	}
+
+	// Lock the wallet again if it's left unlocked
+	quit := make(chan struct{})
+	defer close(quit)
+	go wallet.AutoLock(myWallet, opts.LockTimeout, &walletMtx, quit)
+
+	go rpcNodes.Monitor(opts.CheckInterval, quit)
+
+	mux := http.NewServeMux()
+	// Route requests for / (or anything that doesn't match another pattern) to handleRoot.
+	// https://golang.org/pkg/net/http/#ServeMux
+	mux.HandleFunc("/", page(handleRoot))
+	// Show coin balance details of an address
+	// The trailing / allows us to route requests for URLs
+	// like /balance/Sh7EBrov7iZqbMiYe6kPn3ebaBevB7DcH3 to handleBalance
+	mux.HandleFunc("/balance", page(handleBalance))
+	mux.HandleFunc("/balance/", page(handleBalance))
+	mux.HandleFunc("/address/", page(handleBalance))
+	// Send coin to an address
+	mux.HandleFunc("/sendcoin", page(handleSendCoin))
+	// Show the status of a transaction
+	mux.HandleFunc("/tx", page(handleTx))
+	mux.HandleFunc("/tx/", page(handleTx))
+	// Explore the dag: its tips, the blocks at a height, and the details of a block
+	mux.HandleFunc("/tips", page(handleTips))
+	mux.HandleFunc("/height", page(handleHeight))
+	mux.HandleFunc("/height/", page(handleHeight))
+	mux.HandleFunc("/block", page(handleBlock))
+	mux.HandleFunc("/block/", page(handleBlock))
+	// Go to the page of a block or transaction hash, height or address
+	mux.HandleFunc("/search", page(handleSearch))
+	// List, rebroadcast or abandon pending transactions sent from the wallet
+	mux.HandleFunc("/pending", page(handlePending))
+	// Sign a message with a wallet address, or check the signature of a message
+	mux.HandleFunc("/signmessage", page(handleSignMessage))
+	mux.HandleFunc("/verifymessage", page(handleVerifyMessage))
+	// Choose the unit and number format that amounts are shown in
+	mux.HandleFunc("/settings", page(handleSettings))
+	// Serve favicon from hard-coded bytes
+	mux.HandleFunc("/favicon.ico", handleFavicon)
+	// Serve the soteria logo from hard-coded bytes
+	mux.HandleFunc("/static/soteria_logo.jpg", handleLogo)
+
+	// Start http server in a goroutine, so that it doesn't block other background activities we may want to start.
+	// We'll use a channel to let us know if there was a problem encountered.
+	httpSrvResult := make(chan error)
+	startHttp := func() {
+		err := http.ListenAndServe(opts.Addr, mux)
+		httpSrvResult <- err
+	}
+
+	go startHttp()
+
+	// Listen for signals telling us to shut down, or for http server to stop
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	select {
+	case err := <-httpSrvResult:
+		if err != nil {
+			return fmt.Errorf("Failed to ListenAndServe for addr %s: %s", opts.Addr, err)
+		}
+	case s := <-c:
+		log.Println("Shutting down due to signal:", s)
+	}
+
+	return nil
+}